@@ -10,11 +10,22 @@
 // @BasePath /api/
 package api
 
+import "time"
+
 const (
 	BlockingStatusPath  = "/api/blocking/status"
 	BlockingEnablePath  = "/api/blocking/enable"
 	BlockingDisablePath = "/api/blocking/disable"
+	SchedulesPath       = "/api/blocking/schedules"
+	UnblockRequestsPath = "/api/blocking/unblock-requests"
+	TogglesPath         = "/api/blocking/toggles"
 	BlockingQueryPath   = "/api/query"
+	ConfigReloadPath    = "/api/config/reload"
+	ListsPath           = "/api/lists"
+	ListsRefreshPath    = "/api/lists/refresh"
+	ExplainPath         = "/api/explain"
+	ListEntriesPath     = "/api/lists/{type}/{group}/entries"
+	ListEntryPath       = "/api/lists/{type}/{group}/entries/{entry}"
 )
 
 type QueryRequest struct {
@@ -40,4 +51,184 @@ type BlockingStatus struct {
 	Enabled bool `json:"enabled"`
 	// If blocking is temporary disabled: amount of seconds until blocking will be enabled
 	AutoEnableInSec uint `json:"autoEnableInSec"`
+	// active pauses of single clients and groups
+	Pauses []BlockingPause `json:"pauses"`
+}
+
+type BlockingPause struct {
+	// client IP, name or MAC address, empty if the pause applies to all clients
+	Client string `json:"client,omitempty"`
+	// paused group, empty if all groups of the client are paused
+	Group string `json:"group,omitempty"`
+	// amount of seconds until blocking will be enabled, 0 if the pause lasts until blocking is enabled again
+	AutoEnableInSec uint `json:"autoEnableInSec"`
+}
+
+type ScheduleStatus struct {
+	// name of the schedule
+	Name string `json:"name"`
+	// time zone, in which the windows are evaluated
+	TimeZone string `json:"timeZone"`
+	// True if groups with this schedule are currently active
+	Active bool `json:"active"`
+	// client group entries which use the schedule, for example "kids-tablet: social"
+	UsedBy []string `json:"usedBy"`
+}
+
+type ToggleRequest struct {
+	// name of the toggleable group
+	Toggle string
+	// True to switch the group on
+	Enabled bool
+	// client IP, name or MAC address, empty to change the global state
+	Client string
+}
+
+type TogglesStatus struct {
+	// effective global state of each toggleable group
+	Global map[string]bool `json:"global"`
+	// states per client, which were changed at runtime
+	Clients map[string]map[string]bool `json:"clients"`
+}
+
+type UnblockRequest struct {
+	// blocked host name
+	Host string
+}
+
+type UnblockRequestEntry struct {
+	// IP address of the client, which requested the unblock
+	Client string `json:"client"`
+	// blocked host name
+	Host string `json:"host"`
+	// group, which blocked the host, empty if no recent block decision was found
+	Group string `json:"group,omitempty"`
+	// reason of the block decision
+	Reason string `json:"reason,omitempty"`
+	// time of the last request
+	RequestedAt time.Time `json:"requestedAt"`
+}
+
+type ListSource struct {
+	// link or file name of the list
+	Link string `json:"link"`
+	// number of entries from the last download
+	Entries int `json:"entries"`
+	// time of the last download attempt
+	LastAttempt *time.Time `json:"lastAttempt,omitempty"`
+	// time of the last successful download
+	LastSuccess *time.Time `json:"lastSuccess,omitempty"`
+	// error of the last download attempt, empty if the last attempt was successful
+	LastError string `json:"lastError,omitempty"`
+	// HTTP status code of the last download, empty for files
+	HTTPStatus int `json:"httpStatus,omitempty"`
+	// duration of the last download in milliseconds
+	DownloadDurationMs int64 `json:"downloadDurationMs"`
+	// size of the last download in bytes
+	Bytes int64 `json:"bytes"`
+	// If the download failed and a stored copy is used: age of the copy in seconds
+	FallbackAgeInSec uint `json:"fallbackAgeInSec,omitempty"`
+}
+
+type ListGroup struct {
+	// name of the group
+	Group string `json:"group"`
+	// number of entries in the group
+	Entries int `json:"entries"`
+	// status of each list of the group
+	Sources []ListSource `json:"sources"`
+}
+
+type ListsStatus struct {
+	BlackLists []ListGroup `json:"blackLists"`
+	WhiteLists []ListGroup `json:"whiteLists"`
+}
+
+type ListsRefreshStatus struct {
+	// True if the refresh is still running
+	Running bool `json:"running"`
+	// list type (blacklist or whitelist) of the refresh, empty for both
+	ListType string `json:"listType,omitempty"`
+	// group of the refresh, empty for all groups
+	Group string `json:"group,omitempty"`
+	// start time of the refresh
+	StartedAt *time.Time `json:"startedAt,omitempty"`
+	// end time of the refresh, empty while running
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	// number of groups to refresh
+	GroupsTotal int `json:"groupsTotal"`
+	// number of already refreshed groups
+	GroupsDone int `json:"groupsDone"`
+	// errors of list sources, which couldn't be refreshed
+	Errors []string `json:"errors,omitempty"`
+}
+
+type ListEntryRequest struct {
+	// domain name, wildcard (*.example.com) or IP address
+	Entry string
+}
+
+type ListEntries struct {
+	// list type (blacklist or whitelist)
+	ListType string `json:"listType"`
+	// name of the group
+	Group string `json:"group"`
+	// entries, which were added at runtime
+	Entries []string `json:"entries"`
+}
+
+type ExplainRequest struct {
+	// domain name to explain
+	Domain string
+	// request type (A, AAAA, ...), default A
+	Type string
+	// IP address of the simulated client
+	ClientIP string
+	// MAC address of the simulated client
+	ClientMAC string
+	// client ID of the simulated client (DoH path or TLS server name)
+	ClientID string
+	// names of the simulated client, resolved from the client IP if empty
+	ClientNames []string
+}
+
+type ExplainMatch struct {
+	// checked list: blacklist, whitelist or exception (exception rules of black lists)
+	List string `json:"list"`
+	// checked group
+	Group string `json:"group"`
+	// True if an entry of the group matches the domain
+	Matched bool `json:"matched"`
+	// matching entry
+	Entry string `json:"entry,omitempty"`
+	// link or file name of the list with the matching entry
+	Source string `json:"source,omitempty"`
+	// line number of the matching entry in the list
+	Line int `json:"line,omitempty"`
+}
+
+type ExplainStep struct {
+	// name of the resolver
+	Resolver string `json:"resolver"`
+	// result of the resolver
+	Result string `json:"result"`
+	// True if this resolver decided the outcome
+	Decisive bool `json:"decisive"`
+	// groups which were checked for the client
+	Groups []string `json:"groups,omitempty"`
+	// additional information about the decision
+	Details []string `json:"details,omitempty"`
+	// list matches per group
+	Matches []ExplainMatch `json:"matches,omitempty"`
+}
+
+type ExplainResult struct {
+	// explained domain name
+	Domain string `json:"domain"`
+	// outcome of the request (BLOCKED (group), WHITELISTED (group), CUSTOM DNS, RESOLVED, ...)
+	Outcome string `json:"outcome"`
+	// name of the resolver which decided the outcome
+	DecidedBy string `json:"decidedBy"`
+	// evaluated steps of the resolver chain
+	Steps []ExplainStep `json:"steps"`
 }
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 
 	"github.com/privacyherodev/ph-blocky/api"
 
@@ -16,13 +18,15 @@ import (
 func init() {
 	rootCmd.AddCommand(blockingCmd)
 
-	blockingCmd.AddCommand(&cobra.Command{
+	enableCommand := &cobra.Command{
 		Use:     "enable",
 		Args:    cobra.NoArgs,
 		Aliases: []string{"on"},
-		Short:   "Enable blocking",
+		Short:   "Enable blocking or end the pause of a client and/or groups",
 		Run:     enableBlocking,
-	})
+	}
+	addScopeFlags(enableCommand)
+	blockingCmd.AddCommand(enableCommand)
 
 	disableCommand := &cobra.Command{
 		Use:     "disable",
@@ -32,6 +36,7 @@ func init() {
 		Run:     disableBlocking,
 	}
 	disableCommand.Flags().DurationP("duration", "d", 0, "duration in min")
+	addScopeFlags(disableCommand)
 	blockingCmd.AddCommand(disableCommand)
 
 	blockingCmd.AddCommand(&cobra.Command{
@@ -49,8 +54,32 @@ var blockingCmd = &cobra.Command{
 	Short:   "Control status of blocking resolver",
 }
 
-func enableBlocking(_ *cobra.Command, _ []string) {
-	resp, err := http.Get(apiURL(api.BlockingEnablePath))
+// addScopeFlags adds the flags to pause the blocking only for a client and/or groups
+func addScopeFlags(cmd *cobra.Command) {
+	cmd.Flags().String("client", "", "client IP, name or MAC address (default: all clients)")
+	cmd.Flags().StringSliceP("groups", "g", nil, "groups (default: all groups)")
+}
+
+// scopeQuery returns the query parameters of the client and groups flags
+func scopeQuery(cmd *cobra.Command) url.Values {
+	client, _ := cmd.Flags().GetString("client")
+	groups, _ := cmd.Flags().GetStringSlice("groups")
+
+	query := url.Values{}
+
+	if len(client) > 0 {
+		query.Set("client", client)
+	}
+
+	if len(groups) > 0 {
+		query.Set("groups", strings.Join(groups, ","))
+	}
+
+	return query
+}
+
+func enableBlocking(cmd *cobra.Command, _ []string) {
+	resp, err := http.Get(fmt.Sprintf("%s?%s", apiURL(api.BlockingEnablePath), scopeQuery(cmd).Encode()))
 	if err != nil {
 		log.Logger.Fatal("can't execute", err)
 		return
@@ -67,7 +96,10 @@ func enableBlocking(_ *cobra.Command, _ []string) {
 func disableBlocking(cmd *cobra.Command, _ []string) {
 	duration, _ := cmd.Flags().GetDuration("duration")
 
-	resp, err := http.Get(fmt.Sprintf("%s?duration=%s", apiURL(api.BlockingDisablePath), duration))
+	query := scopeQuery(cmd)
+	query.Set("duration", duration.String())
+
+	resp, err := http.Get(fmt.Sprintf("%s?%s", apiURL(api.BlockingDisablePath), query.Encode()))
 	if err != nil {
 		log.Logger.Fatal("can't execute", err)
 		return
@@ -110,4 +142,21 @@ func statusBlocking(_ *cobra.Command, _ []string) {
 			log.Logger.Infof("blocking disabled for %d seconds", result.AutoEnableInSec)
 		}
 	}
+
+	for _, p := range result.Pauses {
+		scope := fmt.Sprintf("group '%s' of client '%s'", p.Group, p.Client)
+
+		switch {
+		case len(p.Group) == 0:
+			scope = fmt.Sprintf("client '%s'", p.Client)
+		case len(p.Client) == 0:
+			scope = fmt.Sprintf("group '%s'", p.Group)
+		}
+
+		if p.AutoEnableInSec == 0 {
+			log.Logger.Infof("blocking paused for %s", scope)
+		} else {
+			log.Logger.Infof("blocking paused for %s for %d seconds", scope, p.AutoEnableInSec)
+		}
+	}
 }
@@ -82,6 +82,38 @@ var _ = Describe("Blocking command", func() {
 			})
 		})
 	})
+	Describe("pause blocking for client and groups", func() {
+		var query url.Values
+		BeforeEach(func() {
+			mockFn = func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.Query()
+			}
+		})
+		It("should send client, groups and duration", func() {
+			cmd, _, err := blockingCmd.Find([]string{"disable"})
+			Expect(err).Should(Succeed())
+			Expect(cmd.ParseFlags([]string{"--duration", "10m", "--client", "laptop", "-g", "ads,adult"})).
+				Should(Succeed())
+
+			disableBlocking(cmd, []string{})
+
+			Expect(loggerHook.LastEntry().Message).Should(Equal("OK"))
+			Expect(query.Get("duration")).Should(Equal("10m0s"))
+			Expect(query.Get("client")).Should(Equal("laptop"))
+			Expect(query.Get("groups")).Should(Equal("ads,adult"))
+		})
+		It("should end the pause of the client", func() {
+			cmd, _, err := blockingCmd.Find([]string{"enable"})
+			Expect(err).Should(Succeed())
+			Expect(cmd.ParseFlags([]string{"--client", "192.168.178.10"})).Should(Succeed())
+
+			enableBlocking(cmd, []string{})
+
+			Expect(loggerHook.LastEntry().Message).Should(Equal("OK"))
+			Expect(query.Get("client")).Should(Equal("192.168.178.10"))
+			Expect(query.Get("groups")).Should(BeEmpty())
+		})
+	})
 	Describe("status blocking", func() {
 		When("status blocking is called via REST and blocking is enabled", func() {
 			BeforeEach(func() {
@@ -99,6 +131,36 @@ var _ = Describe("Blocking command", func() {
 				Expect(loggerHook.LastEntry().Message).Should(Equal("blocking enabled"))
 			})
 		})
+		When("status blocking is called via REST and blocking is paused for clients and groups", func() {
+			BeforeEach(func() {
+				mockFn = func(w http.ResponseWriter, _ *http.Request) {
+					response, _ := json.Marshal(api.BlockingStatus{
+						Enabled: true,
+						Pauses: []api.BlockingPause{
+							{Group: "ads", AutoEnableInSec: 300},
+							{Client: "laptop"},
+							{Client: "tablet", Group: "adult", AutoEnableInSec: 60},
+						},
+					})
+					_, err := w.Write(response)
+					Expect(err).Should(Succeed())
+				}
+			})
+			It("should print each pause", func() {
+				statusBlocking(blockingCmd, []string{})
+
+				messages := make([]string, 0, len(loggerHook.AllEntries()))
+				for _, e := range loggerHook.AllEntries() {
+					messages = append(messages, e.Message)
+				}
+
+				Expect(messages).Should(ContainElement("blocking enabled"))
+				Expect(messages).Should(ContainElement("blocking paused for group 'ads' for 300 seconds"))
+				Expect(messages).Should(ContainElement("blocking paused for client 'laptop'"))
+				Expect(loggerHook.LastEntry().Message).Should(
+					Equal("blocking paused for group 'adult' of client 'tablet' for 60 seconds"))
+			})
+		})
 		When("status blocking is called via REST and blocking is disabled", func() {
 			var autoEnable uint
 			BeforeEach(func() {
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/log"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoinits
+func init() {
+	rootCmd.AddCommand(explainCmd)
+	explainCmd.Flags().StringP("type", "t", "A", "query type (A, AAAA, ...)")
+	explainCmd.Flags().String("client-ip", "", "IP address of the simulated client")
+	explainCmd.Flags().String("client-mac", "", "MAC address of the simulated client")
+	explainCmd.Flags().String("client-id", "", "client ID of the simulated client (DoH path or TLS server name)")
+	explainCmd.Flags().StringSlice("client-name", nil, "name of the simulated client, can be repeated")
+}
+
+//nolint:gochecknoglobals
+var explainCmd = &cobra.Command{
+	Use:   "explain <domain>",
+	Args:  cobra.ExactArgs(1),
+	Short: "explains why a domain is blocked or allowed for a client",
+	Run:   explain,
+}
+
+func explain(cmd *cobra.Command, args []string) {
+	typeFlag, _ := cmd.Flags().GetString("type")
+	clientIP, _ := cmd.Flags().GetString("client-ip")
+	clientMAC, _ := cmd.Flags().GetString("client-mac")
+	clientID, _ := cmd.Flags().GetString("client-id")
+	clientNames, _ := cmd.Flags().GetStringSlice("client-name")
+
+	apiRequest := api.ExplainRequest{
+		Domain:      args[0],
+		Type:        typeFlag,
+		ClientIP:    clientIP,
+		ClientMAC:   clientMAC,
+		ClientID:    clientID,
+		ClientNames: clientNames,
+	}
+	jsonValue, _ := json.Marshal(apiRequest)
+
+	resp, err := http.Post(apiURL(api.ExplainPath), "application/json", bytes.NewBuffer(jsonValue))
+
+	if err != nil {
+		log.Logger.Fatal("can't execute", err)
+
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		log.Logger.Fatalf("NOK: %s %s", resp.Status, string(body))
+
+		return
+	}
+
+	var result api.ExplainResult
+	err = json.NewDecoder(resp.Body).Decode(&result)
+
+	if err != nil {
+		log.Logger.Fatal("can't read response: ", err)
+
+		return
+	}
+
+	for _, step := range result.Steps {
+		log.Logger.Infof("%s: %s", step.Resolver, step.Result)
+
+		if len(step.Groups) > 0 {
+			log.Logger.Infof("\tgroups: %s", strings.Join(step.Groups, ", "))
+		}
+
+		for _, d := range step.Details {
+			log.Logger.Infof("\t%s", d)
+		}
+
+		for _, m := range step.Matches {
+			if m.Matched {
+				log.Logger.Infof("\t%s %s: '%s' (%s:%d)", m.List, m.Group, m.Entry, m.Source, m.Line)
+			} else {
+				log.Logger.Infof("\t%s %s: no match", m.List, m.Group)
+			}
+		}
+	}
+
+	log.Logger.Infof("Explain result for '%s' (%s): %s, decided by %s", result.Domain, typeFlag, result.Outcome,
+		result.DecidedBy)
+}
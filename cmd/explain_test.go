@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Explain command", func() {
+	var (
+		ts      *httptest.Server
+		mockFn  func(w http.ResponseWriter, _ *http.Request)
+		request api.ExplainRequest
+	)
+	JustBeforeEach(func() {
+		ts = testHTTPAPIServer(func(w http.ResponseWriter, r *http.Request) {
+			Expect(json.NewDecoder(r.Body).Decode(&request)).Should(Succeed())
+			mockFn(w, r)
+		})
+	})
+	JustAfterEach(func() {
+		ts.Close()
+	})
+	BeforeEach(func() {
+		fatal = false
+		// logger could be recreated by the serve command
+		log.Logger.ExitFunc = func(int) { fatal = true }
+		log.Logger.AddHook(loggerHook)
+	})
+	When("explain command is called via REST", func() {
+		BeforeEach(func() {
+			mockFn = func(w http.ResponseWriter, _ *http.Request) {
+				response, _ := json.Marshal(api.ExplainResult{
+					Domain:    "ads.example.com",
+					Outcome:   "BLOCKED (ads)",
+					DecidedBy: "BlockingResolver",
+					Steps: []api.ExplainStep{{
+						Resolver: "BlockingResolver",
+						Result:   "BLOCKED (ads)",
+						Decisive: true,
+						Groups:   []string{"ads"},
+						Matches: []api.ExplainMatch{{
+							List: "blacklist", Group: "ads", Matched: true,
+							Entry: "*.example.com", Source: "http://list.com/ads.txt", Line: 12,
+						}},
+					}},
+				})
+				_, err := w.Write(response)
+				Expect(err).Should(Succeed())
+			}
+		})
+		It("should send the simulated client and print the result", func() {
+			Expect(explainCmd.Flags().Set("client-ip", "192.168.178.10")).Should(Succeed())
+			Expect(explainCmd.Flags().Set("client-mac", "aa:bb:cc:dd:ee:ff")).Should(Succeed())
+			Expect(explainCmd.Flags().Set("client-id", "kids-tablet")).Should(Succeed())
+			Expect(explainCmd.Flags().Set("client-name", "laptop")).Should(Succeed())
+
+			explain(explainCmd, []string{"ads.example.com"})
+
+			Expect(fatal).Should(BeFalse())
+			Expect(request).Should(Equal(api.ExplainRequest{
+				Domain:      "ads.example.com",
+				Type:        "A",
+				ClientIP:    "192.168.178.10",
+				ClientMAC:   "aa:bb:cc:dd:ee:ff",
+				ClientID:    "kids-tablet",
+				ClientNames: []string{"laptop"},
+			}))
+
+			messages := make([]string, 0, len(loggerHook.AllEntries()))
+			for _, e := range loggerHook.AllEntries() {
+				messages = append(messages, e.Message)
+			}
+
+			Expect(messages).Should(ContainElement("\tblacklist ads: '*.example.com' (http://list.com/ads.txt:12)"))
+			Expect(loggerHook.LastEntry().Message).Should(Equal(
+				"Explain result for 'ads.example.com' (A): BLOCKED (ads), decided by BlockingResolver"))
+		})
+	})
+	When("Server returns 400", func() {
+		BeforeEach(func() {
+			mockFn = func(w http.ResponseWriter, _ *http.Request) {
+				http.Error(w, "invalid client IP", http.StatusBadRequest)
+			}
+		})
+		It("should end with error", func() {
+			explain(explainCmd, []string{"ads.example.com"})
+			Expect(fatal).Should(BeTrue())
+			Expect(loggerHook.LastEntry().Message).Should(ContainSubstring("NOK: 400 Bad Request invalid client IP"))
+		})
+	})
+})
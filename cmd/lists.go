@@ -0,0 +1,199 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/api"
+
+	"github.com/privacyherodev/ph-blocky/log"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoglobals
+var refreshPollInterval = time.Second
+
+//nolint:gochecknoinits
+func init() {
+	rootCmd.AddCommand(listsCmd)
+
+	listsRefreshCmd.Flags().StringP("group", "g", "", "group to refresh (default: all groups)")
+	listsRefreshCmd.Flags().StringP("type", "t", "", "list type to refresh: blacklist or whitelist (default: both)")
+	listsRefreshCmd.Flags().Bool("wait", true, "wait until the refresh is finished and print the result")
+	listsCmd.AddCommand(listsRefreshCmd)
+	listsCmd.AddCommand(listsAddCmd)
+	listsCmd.AddCommand(listsRemoveCmd)
+}
+
+//nolint:gochecknoglobals
+var listsCmd = &cobra.Command{
+	Use:   "lists",
+	Short: "Control black and white lists",
+}
+
+//nolint:gochecknoglobals
+var listsRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Args:  cobra.NoArgs,
+	Short: "Download and parse black and white lists again",
+	Run:   refreshLists,
+}
+
+//nolint:gochecknoglobals
+var listsAddCmd = &cobra.Command{
+	Use:   "add <blacklist|whitelist> <group> <entry>",
+	Args:  cobra.ExactArgs(3),
+	Short: "Add a domain name, wildcard or IP address to the black or white list of a group",
+	Run:   addListEntry,
+}
+
+//nolint:gochecknoglobals
+var listsRemoveCmd = &cobra.Command{
+	Use:   "remove <blacklist|whitelist> <group> <entry>",
+	Args:  cobra.ExactArgs(3),
+	Short: "Remove an added entry from the black or white list of a group",
+	Run:   removeListEntry,
+}
+
+func addListEntry(_ *cobra.Command, args []string) {
+	jsonValue, _ := json.Marshal(api.ListEntryRequest{Entry: args[2]})
+
+	resp, err := http.Post(listEntriesURL(api.ListEntriesPath, args), "application/json", bytes.NewBuffer(jsonValue))
+	if err != nil {
+		log.Logger.Fatal("can't execute", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	printListEntries(resp)
+}
+
+func removeListEntry(_ *cobra.Command, args []string) {
+	req, _ := http.NewRequest(http.MethodDelete, listEntriesURL(api.ListEntryPath, args), nil)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		log.Logger.Fatal("can't execute", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	printListEntries(resp)
+}
+
+// returns the URL of the path with list type, group and entry from the arguments
+func listEntriesURL(path string, args []string) string {
+	return apiURL(strings.NewReplacer(
+		"{type}", url.PathEscape(args[0]),
+		"{group}", url.PathEscape(args[1]),
+		"{entry}", url.PathEscape(args[2])).Replace(path))
+}
+
+func printListEntries(resp *http.Response) {
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		log.Logger.Fatalf("NOK: %s %s", resp.Status, strings.TrimSpace(string(body)))
+
+		return
+	}
+
+	var entries api.ListEntries
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		log.Logger.Fatal("can't read response: ", err)
+		return
+	}
+
+	log.Logger.Infof("OK, entries of %s '%s' added at runtime: %s", entries.ListType, entries.Group,
+		strings.Join(entries.Entries, ", "))
+}
+
+func refreshLists(cmd *cobra.Command, _ []string) {
+	group, _ := cmd.Flags().GetString("group")
+	listType, _ := cmd.Flags().GetString("type")
+	wait, _ := cmd.Flags().GetBool("wait")
+
+	params := url.Values{}
+
+	if len(group) > 0 {
+		params.Set("group", group)
+	}
+
+	if len(listType) > 0 {
+		params.Set("type", listType)
+	}
+
+	refreshURL := apiURL(api.ListsRefreshPath)
+	if len(params) > 0 {
+		refreshURL = fmt.Sprintf("%s?%s", refreshURL, params.Encode())
+	}
+
+	resp, err := http.Post(refreshURL, "application/json", nil)
+	if err != nil {
+		log.Logger.Fatal("can't execute", err)
+		return
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted {
+		body, _ := ioutil.ReadAll(resp.Body)
+		log.Logger.Fatalf("NOK: %s %s", resp.Status, strings.TrimSpace(string(body)))
+
+		return
+	}
+
+	var status api.ListsRefreshStatus
+	if err = json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		log.Logger.Fatal("can't read response: ", err)
+		return
+	}
+
+	if !wait {
+		log.Logger.Infof("refresh of %d group(s) started", status.GroupsTotal)
+		return
+	}
+
+	for status.Running {
+		time.Sleep(refreshPollInterval)
+
+		if status, err = refreshStatus(); err != nil {
+			log.Logger.Fatal("can't read refresh status: ", err)
+			return
+		}
+
+		log.Logger.Infof("refreshed %d/%d group(s)", status.GroupsDone, status.GroupsTotal)
+	}
+
+	for _, e := range status.Errors {
+		log.Logger.Warn(e)
+	}
+
+	if len(status.Errors) > 0 {
+		log.Logger.Fatalf("NOK: %d error(s) while refreshing %d group(s)", len(status.Errors), status.GroupsTotal)
+		return
+	}
+
+	log.Logger.Info("OK")
+}
+
+func refreshStatus() (status api.ListsRefreshStatus, err error) {
+	resp, err := http.Get(apiURL(api.ListsRefreshPath))
+	if err != nil {
+		return status, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return status, fmt.Errorf("NOK: %s", resp.Status)
+	}
+
+	err = json.NewDecoder(resp.Body).Decode(&status)
+
+	return status, err
+}
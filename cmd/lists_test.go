@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Lists command", func() {
+	var (
+		ts         *httptest.Server
+		mockFn     func(w http.ResponseWriter, r *http.Request)
+		requestURI []string
+	)
+	JustBeforeEach(func() {
+		ts = testHTTPAPIServer(func(w http.ResponseWriter, r *http.Request) {
+			requestURI = append(requestURI, r.Method+" "+r.URL.RequestURI())
+			mockFn(w, r)
+		})
+	})
+	JustAfterEach(func() {
+		ts.Close()
+	})
+	BeforeEach(func() {
+		fatal = false
+		requestURI = nil
+		refreshPollInterval = time.Millisecond
+		// logger could be recreated by the serve command
+		log.Logger.ExitFunc = func(int) { fatal = true }
+		log.Logger.AddHook(loggerHook)
+
+		Expect(listsRefreshCmd.Flags().Set("group", "")).Should(Succeed())
+		Expect(listsRefreshCmd.Flags().Set("type", "")).Should(Succeed())
+		Expect(listsRefreshCmd.Flags().Set("wait", "true")).Should(Succeed())
+	})
+
+	writeStatus := func(w http.ResponseWriter, code int, status api.ListsRefreshStatus) {
+		w.WriteHeader(code)
+		response, _ := json.Marshal(status)
+		_, _ = w.Write(response)
+	}
+
+	Describe("refresh lists", func() {
+		When("refresh finishes without errors", func() {
+			BeforeEach(func() {
+				mockFn = func(w http.ResponseWriter, r *http.Request) {
+					if r.Method == http.MethodPost {
+						writeStatus(w, http.StatusAccepted, api.ListsRefreshStatus{Running: true, GroupsTotal: 2})
+					} else {
+						writeStatus(w, http.StatusOK, api.ListsRefreshStatus{GroupsTotal: 2, GroupsDone: 2})
+					}
+				}
+			})
+			It("should wait for the result and print OK", func() {
+				Expect(listsRefreshCmd.Flags().Set("group", "ads")).Should(Succeed())
+				Expect(listsRefreshCmd.Flags().Set("type", "blacklist")).Should(Succeed())
+
+				refreshLists(listsRefreshCmd, []string{})
+
+				Expect(fatal).Should(BeFalse())
+				Expect(loggerHook.LastEntry().Message).Should(Equal("OK"))
+				Expect(requestURI).Should(Equal([]string{
+					"POST /api/lists/refresh?group=ads&type=blacklist",
+					"GET /api/lists/refresh",
+				}))
+			})
+		})
+		When("refresh should not be awaited", func() {
+			BeforeEach(func() {
+				mockFn = func(w http.ResponseWriter, r *http.Request) {
+					writeStatus(w, http.StatusAccepted, api.ListsRefreshStatus{Running: true, GroupsTotal: 3})
+				}
+			})
+			It("should only start the refresh", func() {
+				Expect(listsRefreshCmd.Flags().Set("wait", "false")).Should(Succeed())
+
+				refreshLists(listsRefreshCmd, []string{})
+
+				Expect(fatal).Should(BeFalse())
+				Expect(loggerHook.LastEntry().Message).Should(Equal("refresh of 3 group(s) started"))
+				Expect(requestURI).Should(HaveLen(1))
+			})
+		})
+		When("refresh finishes with errors", func() {
+			BeforeEach(func() {
+				mockFn = func(w http.ResponseWriter, r *http.Request) {
+					writeStatus(w, http.StatusAccepted, api.ListsRefreshStatus{
+						GroupsTotal: 1, GroupsDone: 1, Errors: []string{"ads: http://list: 404"}})
+				}
+			})
+			It("should end with error", func() {
+				refreshLists(listsRefreshCmd, []string{})
+
+				Expect(fatal).Should(BeTrue())
+				Expect(loggerHook.LastEntry().Message).Should(Equal("NOK: 1 error(s) while refreshing 1 group(s)"))
+			})
+		})
+		When("another refresh is running", func() {
+			BeforeEach(func() {
+				mockFn = func(w http.ResponseWriter, r *http.Request) {
+					writeStatus(w, http.StatusConflict, api.ListsRefreshStatus{Running: true})
+				}
+			})
+			It("should end with error", func() {
+				refreshLists(listsRefreshCmd, []string{})
+
+				Expect(fatal).Should(BeTrue())
+				Expect(loggerHook.LastEntry().Message).Should(HavePrefix("NOK: 409 Conflict"))
+			})
+		})
+		When("Wrong url is used", func() {
+			It("Should end with error", func() {
+				apiPort = 0
+				refreshLists(listsRefreshCmd, []string{})
+				Expect(fatal).Should(BeTrue())
+				Expect(loggerHook.LastEntry().Message).Should(ContainSubstring("connection refused"))
+			})
+		})
+	})
+	Describe("add and remove list entries", func() {
+		BeforeEach(func() {
+			mockFn = func(w http.ResponseWriter, r *http.Request) {
+				response, _ := json.Marshal(api.ListEntries{ListType: "whitelist", Group: "ads",
+					Entries: []string{"a.com", "b.com"}})
+				_, _ = w.Write(response)
+			}
+		})
+		When("entry is added", func() {
+			It("should post the entry and print the entries of the group", func() {
+				addListEntry(listsAddCmd, []string{"whitelist", "ads", "b.com"})
+
+				Expect(fatal).Should(BeFalse())
+				Expect(requestURI).Should(Equal([]string{"POST /api/lists/whitelist/ads/entries"}))
+				Expect(loggerHook.LastEntry().Message).Should(Equal(
+					"OK, entries of whitelist 'ads' added at runtime: a.com, b.com"))
+			})
+		})
+		When("entry is removed", func() {
+			It("should send delete request", func() {
+				removeListEntry(listsRemoveCmd, []string{"whitelist", "ads", "c.com"})
+
+				Expect(fatal).Should(BeFalse())
+				Expect(requestURI).Should(Equal([]string{"DELETE /api/lists/whitelist/ads/entries/c.com"}))
+			})
+		})
+		When("server returns an error", func() {
+			BeforeEach(func() {
+				mockFn = func(w http.ResponseWriter, r *http.Request) {
+					http.Error(w, "group doesn't contain the entry", http.StatusNotFound)
+				}
+			})
+			It("should end with error", func() {
+				removeListEntry(listsRemoveCmd, []string{"whitelist", "ads", "c.com"})
+
+				Expect(fatal).Should(BeTrue())
+				Expect(loggerHook.LastEntry().Message).Should(Equal("NOK: 404 Not Found group doesn't contain the entry"))
+			})
+		})
+	})
+})
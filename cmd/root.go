@@ -27,6 +27,11 @@ var rootCmd = &cobra.Command{
 and ad-blocker for local network.
 		   
 Complete documentation is available at https://github.com/0xERR0R/blocky`,
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {
+		// only the server (default command) depends on the local settings like directories and external resolvers,
+		// other commands are clients of the API
+		initConfig(cmd == serveCmd || !cmd.HasParent())
+	},
 	Run: func(cmd *cobra.Command, args []string) {
 		serveCmd.Run(cmd, args)
 	},
@@ -38,15 +43,25 @@ func apiURL(path string) string {
 
 //nolint:gochecknoinits
 func init() {
-	cobra.OnInitialize(initConfig)
-
 	rootCmd.PersistentFlags().StringVarP(&configPath, "config", "c", "./config.yml", "path to config file")
 	rootCmd.PersistentFlags().StringVar(&apiHost, "apiHost", "localhost", "host of blocky (API)")
 	rootCmd.PersistentFlags().Uint16Var(&apiPort, "apiPort", 0, "port of blocky (API)")
 }
 
-func initConfig() {
-	cfg = config.NewConfig(configPath)
+// initConfig reads the configuration file, if validate is true the configuration is validated too
+func initConfig(validate bool) {
+	var err error
+
+	if validate {
+		cfg, err = config.LoadConfig(configPath)
+	} else {
+		cfg, err = config.ReadConfig(configPath)
+	}
+
+	if err != nil {
+		log.Logger.Fatal(err)
+	}
+
 	log.NewLogger(cfg.LogLevel, cfg.LogFormat)
 
 	if apiPort == 0 {
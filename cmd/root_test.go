@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/privacyherodev/ph-blocky/config"
+	. "github.com/privacyherodev/ph-blocky/helpertest"
+	"github.com/privacyherodev/ph-blocky/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Root command", func() {
+	var (
+		cfgFile        *os.File
+		previousCfg    config.Config
+		previousPort   uint16
+		previousLogger *logrus.Logger
+	)
+	BeforeEach(func() {
+		// reading the configuration creates a new logger
+		previousCfg, previousPort, previousLogger = cfg, apiPort, log.Logger
+
+		// query log directory and external resolvers are only needed by the server
+		cfgFile = TempFile("queryLog:\n  dir: /notExisting")
+		configPath = cfgFile.Name()
+
+		fatal = false
+		log.Logger.ExitFunc = func(int) { fatal = true }
+	})
+	AfterEach(func() {
+		cfg, apiPort, log.Logger = previousCfg, previousPort, previousLogger
+
+		_ = os.Remove(cfgFile.Name())
+	})
+	When("a command is a client of the API", func() {
+		It("should read the configuration without validation", func() {
+			cmd, _, err := blockingCmd.Find([]string{"status"})
+			Expect(err).Should(Succeed())
+
+			rootCmd.PersistentPreRun(cmd, []string{})
+
+			Expect(fatal).Should(BeFalse())
+			Expect(cfg.QueryLog.Dir).Should(Equal("/notExisting"))
+		})
+	})
+	When("the server is started", func() {
+		It("should end with error on an invalid configuration", func() {
+			rootCmd.PersistentPreRun(serveCmd, []string{})
+
+			Expect(fatal).Should(BeTrue())
+		})
+	})
+})
@@ -39,7 +39,7 @@ func startServer(_ *cobra.Command, _ []string) {
 
 	configureHTTPClient(&cfg)
 
-	signals := make(chan os.Signal)
+	signals := make(chan os.Signal, 1)
 	done = make(chan bool)
 
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
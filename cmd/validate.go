@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"github.com/privacyherodev/ph-blocky/config"
+	"github.com/privacyherodev/ph-blocky/log"
+
+	"github.com/spf13/cobra"
+)
+
+//nolint:gochecknoinits
+func init() {
+	rootCmd.AddCommand(validateCmd)
+}
+
+//nolint:gochecknoglobals
+var validateCmd = &cobra.Command{
+	Use:   "validate",
+	Args:  cobra.NoArgs,
+	Short: "Validates the configuration file and prints all errors and warnings",
+	// configuration will be read by the command itself, an invalid configuration must not end the program
+	PersistentPreRun: func(cmd *cobra.Command, args []string) {},
+	Run:              validateConfig,
+}
+
+func validateConfig(_ *cobra.Command, _ []string) {
+	c, err := config.ReadConfig(configPath)
+	if err != nil {
+		log.Logger.Fatal(err)
+		return
+	}
+
+	issues := c.Validate()
+
+	for _, issue := range issues {
+		entry := log.Logger.WithField("path", issue.Path)
+		if issue.Severity == config.SeverityError {
+			entry.Error(issue.Message)
+		} else {
+			entry.Warn(issue.Message)
+		}
+	}
+
+	if errors := len(issues.Errors()); errors > 0 {
+		log.Logger.Fatalf("configuration is invalid: %d error(s), %d warning(s)", errors, len(issues.Warnings()))
+		return
+	}
+
+	log.Logger.Infof("configuration is valid: %d warning(s)", len(issues.Warnings()))
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"os"
+
+	. "github.com/privacyherodev/ph-blocky/helpertest"
+	"github.com/privacyherodev/ph-blocky/log"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate command", func() {
+	var cfgFile *os.File
+	BeforeEach(func() {
+		fatal = false
+		// logger could be recreated by the serve command
+		log.Logger.ExitFunc = func(int) { fatal = true }
+		log.Logger.AddHook(loggerHook)
+	})
+	AfterEach(func() {
+		_ = os.Remove(cfgFile.Name())
+	})
+	When("configuration is valid", func() {
+		It("should print success", func() {
+			cfgFile = TempFile("upstream:\n  externalResolvers:\n    - udp:8.8.8.8")
+			configPath = cfgFile.Name()
+
+			validateConfig(validateCmd, []string{})
+			Expect(fatal).Should(BeFalse())
+			Expect(loggerHook.LastEntry().Message).Should(Equal("configuration is valid: 0 warning(s)"))
+		})
+	})
+	When("configuration contains errors", func() {
+		It("should print all issues and end with error", func() {
+			cfgFile = TempFile(`upstream:
+  externalResolvers:
+    - udp:8.8.8.8
+blocking:
+  blockType: wrong
+  clientGroupsBlock:
+    default:
+      - ads`)
+			configPath = cfgFile.Name()
+
+			validateConfig(validateCmd, []string{})
+			Expect(fatal).Should(BeTrue())
+			Expect(loggerHook.LastEntry().Message).Should(Equal("configuration is invalid: 2 error(s), 0 warning(s)"))
+		})
+	})
+	When("configuration file is malformed", func() {
+		It("should end with error", func() {
+			cfgFile = TempFile("malformed")
+			configPath = cfgFile.Name()
+
+			validateConfig(validateCmd, []string{})
+			Expect(fatal).Should(BeTrue())
+			Expect(loggerHook.LastEntry().Message).Should(ContainSubstring("wrong file structure"))
+		})
+	})
+})
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MACPrefixWildcard marks a MAC address prefix in clientGroupsBlock keys ("aa:bb:cc:*")
+const MACPrefixWildcard = "*"
+
+// IsCIDRKey returns true if the clientGroupsBlock key is an IP range in CIDR notation
+func IsCIDRKey(key string) bool {
+	return strings.Contains(key, "/")
+}
+
+// IsMACPrefixKey returns true if the clientGroupsBlock key is a MAC address prefix
+func IsMACPrefixKey(key string) bool {
+	return strings.HasSuffix(key, MACPrefixWildcard)
+}
+
+// ParseMACPrefix converts a MAC address prefix like "AA-BB-CC-*" to the lower case prefix "aa:bb:cc:" of
+// formatted MAC addresses and returns the count of bytes of the prefix
+func ParseMACPrefix(key string) (prefix string, length int, err error) {
+	parts := strings.FieldsFunc(strings.TrimSuffix(strings.ToLower(key), MACPrefixWildcard), func(r rune) bool {
+		return r == ':' || r == '-'
+	})
+
+	if len(parts) == 0 || len(parts) > 7 {
+		return "", 0, fmt.Errorf("invalid MAC address prefix '%s', please use format aa:bb:cc:*", key)
+	}
+
+	for _, part := range parts {
+		if len(part) != 2 || strings.Trim(part, "0123456789abcdef") != "" {
+			return "", 0, fmt.Errorf("invalid MAC address prefix '%s', please use format aa:bb:cc:*", key)
+		}
+	}
+
+	return strings.Join(parts, ":") + ":", len(parts), nil
+}
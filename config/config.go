@@ -110,6 +110,16 @@ const (
 	cfgDefaultPrometheusPath = "/metrics"
 )
 
+// EDNS0 option codes, which are used by dnsmasq for the identity of the client
+const (
+	// EDNS0OptionMACBinary is the MAC address in binary form (--add-mac)
+	EDNS0OptionMACBinary = 65001
+	// EDNS0OptionMACText is the MAC address as text or base64 (--add-mac=text, --add-mac=base64)
+	EDNS0OptionMACText = 65073
+	// EDNS0OptionClientID is an opaque client ID as text (--add-cpe-id)
+	EDNS0OptionClientID = 65074
+)
+
 // main configuration
 type Config struct {
 	Upstream     UpstreamConfig            `yaml:"upstream"`
@@ -129,6 +139,15 @@ type Config struct {
 	KeyFile      string                    `yaml:"httpsKeyFile"`
 	BootstrapDNS Upstream                  `yaml:"bootstrapDns"`
 	Cname        CnameConfig               `yaml:"cname"`
+	BlockPage    BlockPageConfig           `yaml:"blockPage"`
+
+	// path of the file this configuration was loaded from, used to reload it
+	path string
+}
+
+// Path returns the path of the file this configuration was loaded from
+func (c *Config) Path() string {
+	return c.path
 }
 
 type Groups struct {
@@ -148,6 +167,63 @@ type PrometheusConfig struct {
 
 type UpstreamConfig struct {
 	ExternalResolvers []Upstream `yaml:"externalResolvers"`
+	ECS               ECSConfig  `yaml:"ecs"`
+}
+
+// ECS policies define, how the EDNS client subnet option of a query is passed to the upstream servers
+const (
+	// ECSPolicyStrip removes the option
+	ECSPolicyStrip = "strip"
+	// ECSPolicyForward passes the option of the query unchanged
+	ECSPolicyForward = "forward"
+	// ECSPolicySynthesize replaces the option with the truncated IP address of the client
+	ECSPolicySynthesize = "synthesize"
+)
+
+const (
+	cfgDefaultECSIPv4Mask = 24
+	cfgDefaultECSIPv6Mask = 56
+)
+
+// ECSConfig is the EDNS client subnet (ECS) policy
+type ECSConfig struct {
+	// policy for all domains, default: strip
+	Policy string `yaml:"policy"`
+	// policy per domain suffix, the longest matching suffix wins
+	Domains map[string]string `yaml:"domains"`
+	// prefix lengths of synthesized client subnets, default: 24 and 56
+	IPv4Mask uint8 `yaml:"ipv4Mask"`
+	IPv6Mask uint8 `yaml:"ipv6Mask"`
+}
+
+// PolicyFor returns the policy of the longest domain suffix, which matches the domain, otherwise the default policy
+func (c *ECSConfig) PolicyFor(domain string) string {
+	domain = strings.Trim(strings.ToLower(domain), ".")
+
+	policy, length := c.Policy, -1
+
+	for suffix, p := range c.Domains {
+		suffix = strings.Trim(strings.ToLower(suffix), ".")
+		if (domain == suffix || strings.HasSuffix(domain, "."+suffix)) && len(suffix) > length {
+			policy, length = p, len(suffix)
+		}
+	}
+
+	if len(policy) == 0 {
+		return ECSPolicyStrip
+	}
+
+	return strings.ToLower(policy)
+}
+
+// IsValidECSPolicy returns true if the policy is empty (default), strip, forward or synthesize
+func IsValidECSPolicy(policy string) bool {
+	switch strings.ToLower(policy) {
+	case "", ECSPolicyStrip, ECSPolicyForward, ECSPolicySynthesize:
+		return true
+	}
+
+	return false
 }
 
 type CustomDNSConfig struct {
@@ -163,14 +239,80 @@ type BlockingConfig struct {
 	WhiteLists        map[string][]string `yaml:"whiteLists"`
 	ClientGroupsBlock map[string][]string `yaml:"clientGroupsBlock"`
 	Global            map[string]bool     `yaml:"global"`
+	Toggles           []string            `yaml:"toggles"`
 	BlockType         string              `yaml:"blockType"`
+	GroupBlockTypes   map[string]string   `yaml:"groupBlockTypes"`
 	RefreshPeriod     int                 `yaml:"refreshPeriod"`
+	MatchMode         string              `yaml:"matchMode"`
+	DownloadCacheDir  string              `yaml:"downloadCacheDir"`
+	OverlayFile       string              `yaml:"overlayFile"`
+	Schedules         map[string]Schedule `yaml:"schedules"`
+	StateFile         string              `yaml:"stateFile"`
+}
+
+// BlockPageConfig configures the listeners of the block page, which is shown for blocked domains if blockType
+// is the IP address of blocky
+type BlockPageConfig struct {
+	// HTTP listener port, 0 = no listener
+	HTTPPort uint16 `yaml:"httpPort"`
+	// HTTPS listener port, 0 = no listener
+	HTTPSPort uint16 `yaml:"httpsPort"`
+	// default certificate, used if no other certificate matches the requested host name (SNI).
+	// Default: httpsCertFile and httpsKeyFile
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	// additional certificates, selected by the requested host name (SNI)
+	Certificates []CertificateConfig `yaml:"certificates"`
+}
+
+type CertificateConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+}
+
+// BlockPageDefaultCertificate returns the certificate of the block page or the HTTPS certificate, if no own one is defined
+func (c *Config) BlockPageDefaultCertificate() CertificateConfig {
+	if c.BlockPage.CertFile != "" || c.BlockPage.KeyFile != "" {
+		return CertificateConfig{CertFile: c.BlockPage.CertFile, KeyFile: c.BlockPage.KeyFile}
+	}
+
+	return CertificateConfig{CertFile: c.CertFile, KeyFile: c.KeyFile}
+}
+
+// nolint:gochecknoglobals
+var defaultToggles = []string{"adblock", "malware", "adult", "whitelist"} // toggleable groups, if toggles is not configured
+
+// ToggleNames returns the groups, which can be toggled for clients identified by MAC address or client ID. If toggles
+// are not configured, these are the default toggles and the groups of global
+func (c *BlockingConfig) ToggleNames() []string {
+	if len(c.Toggles) > 0 {
+		return c.Toggles
+	}
+
+	names := append([]string{}, defaultToggles...)
+	for name := range c.Global {
+		names = append(names, name)
+	}
+
+	return names
 }
 
 type ClientLookupConfig struct {
 	ClientnameIPMapping map[string][]net.IP `yaml:"clients"`
 	Upstream            Upstream            `yaml:"upstream"`
 	SingleNameOrder     []uint              `yaml:"singleNameOrder"`
+	EDNS0               EDNS0Config         `yaml:"edns0"`
+	// client IDs are taken from the leftmost label of TLS server names (SNI) below this domain
+	ClientIDDomain string `yaml:"clientIDDomain"`
+}
+
+// EDNS0Config maps EDNS0 option codes to the identity of the client, code 0 disables the option
+type EDNS0Config struct {
+	MACBinary uint16 `yaml:"macBinary"`
+	MACText   uint16 `yaml:"macText"`
+	ClientID  uint16 `yaml:"clientID"`
+	// if true, the options are removed from the query before it is forwarded to the upstream servers
+	Strip bool `yaml:"strip"`
 }
 
 type CachingConfig struct {
@@ -184,25 +326,57 @@ type QueryLogConfig struct {
 	LogRetentionDays uint64 `yaml:"logRetentionDays"`
 }
 
-func NewConfig(path string) Config {
-	cfg := Config{}
+// LoadConfig reads and validates the configuration file. Warnings are logged, errors are returned as ValidationError
+func LoadConfig(path string) (Config, error) {
+	cfg, err := ReadConfig(path)
+	if err != nil {
+		return cfg, err
+	}
+
+	issues := cfg.Validate()
+	for _, issue := range issues.Warnings() {
+		log.Logger.WithField("path", issue.Path).Warn(issue.Message)
+	}
+
+	if len(issues.Errors()) > 0 {
+		return cfg, &ValidationError{Issues: issues}
+	}
+
+	return cfg, nil
+}
+
+// ReadConfig reads the configuration file without semantic validation
+func ReadConfig(path string) (Config, error) {
+	cfg := Config{path: path}
 	setDefaultValues(&cfg)
 
 	data, err := ioutil.ReadFile(path)
-
 	if err != nil {
-		log.Logger.Fatal("Can't read config file: ", err)
+		return cfg, fmt.Errorf("can't read config file: %v", err)
 	}
 
 	err = yaml.UnmarshalStrict(data, &cfg)
 	if err != nil {
-		log.Logger.Fatal("wrong file structure: ", err)
+		return cfg, fmt.Errorf("wrong file structure: %v", err)
 	}
-	if cfg.LogFormat != log.CfgLogFormatText && cfg.LogFormat != log.CfgLogFormatJSON {
-		log.Logger.Fatal("LogFormat should be 'text' or 'json'")
+
+	return cfg, nil
+}
+
+// isValidBlockType returns true if block type is empty, ZeroIP, NxDomain or contains at least one IP address
+func isValidBlockType(blockType string) bool {
+	t := strings.TrimSpace(strings.ToUpper(blockType))
+	if t == "" || t == "ZEROIP" || t == "NXDOMAIN" {
+		return true
 	}
 
-	return cfg
+	for _, part := range strings.Split(t, ",") {
+		if ip := net.ParseIP(strings.TrimSpace(part)); ip != nil {
+			return true
+		}
+	}
+
+	return false
 }
 
 func setDefaultValues(cfg *Config) {
@@ -210,4 +384,11 @@ func setDefaultValues(cfg *Config) {
 	cfg.LogLevel = "info"
 	cfg.LogFormat = log.CfgLogFormatText
 	cfg.Prometheus.Path = cfgDefaultPrometheusPath
+	cfg.Upstream.ECS.IPv4Mask = cfgDefaultECSIPv4Mask
+	cfg.Upstream.ECS.IPv6Mask = cfgDefaultECSIPv6Mask
+	cfg.ClientLookup.EDNS0 = EDNS0Config{
+		MACBinary: EDNS0OptionMACBinary,
+		MACText:   EDNS0OptionMACText,
+		ClientID:  EDNS0OptionClientID,
+	}
 }
@@ -8,8 +8,6 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
 	. "github.com/onsi/gomega"
-
-	"github.com/sirupsen/logrus"
 )
 
 var _ = Describe("Config", func() {
@@ -19,7 +17,8 @@ var _ = Describe("Config", func() {
 				err := os.Chdir("../testdata")
 				Expect(err).Should(Succeed())
 
-				cfg := NewConfig("config.yml")
+				cfg, err := ReadConfig("config.yml")
+				Expect(err).Should(Succeed())
 
 				Expect(cfg.Port).Should(Equal(uint16(55555)))
 				Expect(cfg.Upstream.ExternalResolvers).Should(HaveLen(3))
@@ -31,6 +30,8 @@ var _ = Describe("Config", func() {
 				Expect(cfg.Conditional.Mapping).Should(HaveLen(1))
 				Expect(cfg.ClientLookup.Upstream.Host).Should(Equal("192.168.178.1"))
 				Expect(cfg.ClientLookup.SingleNameOrder).Should(Equal([]uint{2, 1}))
+				Expect(cfg.ClientLookup.EDNS0).Should(Equal(EDNS0Config{MACBinary: 65001, MACText: 65073, ClientID: 65074}))
+				Expect(cfg.Upstream.ECS).Should(Equal(ECSConfig{IPv4Mask: 24, IPv6Mask: 56}))
 				Expect(cfg.Blocking.BlackLists).Should(HaveLen(2))
 				Expect(cfg.Blocking.WhiteLists).Should(HaveLen(1))
 				Expect(cfg.Blocking.ClientGroupsBlock).Should(HaveLen(2))
@@ -48,8 +49,7 @@ var _ = Describe("Config", func() {
 			})
 		})
 		When("config file is malformed", func() {
-			It("should log with fatal and exit", func() {
-
+			It("should return an error", func() {
 				dir, err := ioutil.TempDir("", "blocky")
 				defer os.Remove(dir)
 				Expect(err).Should(Succeed())
@@ -58,29 +58,50 @@ var _ = Describe("Config", func() {
 				err = ioutil.WriteFile("config.yml", []byte("malformed_config"), 0644)
 				Expect(err).Should(Succeed())
 
-				defer func() { logrus.StandardLogger().ExitFunc = nil }()
-
-				var fatal bool
-
-				logrus.StandardLogger().ExitFunc = func(int) { fatal = true }
-
-				_ = NewConfig("config.yml")
-				Expect(fatal).Should(BeTrue())
+				_, err = ReadConfig("config.yml")
+				Expect(err).Should(HaveOccurred())
+				Expect(err.Error()).Should(ContainSubstring("wrong file structure"))
 			})
 		})
 		When("config directory does not exist", func() {
-			It("should log with fatal and exit", func() {
+			It("should return an error", func() {
 				err := os.Chdir("../..")
 				Expect(err).Should(Succeed())
 
-				defer func() { logrus.StandardLogger().ExitFunc = nil }()
+				_, err = ReadConfig("config.yml")
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+	})
 
-				var fatal bool
+	Describe("Loading of Config", func() {
+		When("config contains an unknown block type", func() {
+			It("should return an error", func() {
+				file, err := ioutil.TempFile("", "blocky")
+				Expect(err).Should(Succeed())
+				defer os.Remove(file.Name())
 
-				logrus.StandardLogger().ExitFunc = func(int) { fatal = true }
-				_ = NewConfig("config.yml")
+				_, err = file.WriteString("upstream:\n  externalResolvers:\n    - udp:8.8.8.8\nblocking:\n  blockType: wrong")
+				Expect(err).Should(Succeed())
 
-				Expect(fatal).Should(BeTrue())
+				_, err = LoadConfig(file.Name())
+				Expect(err).Should(HaveOccurred())
+				Expect(err).Should(BeAssignableToTypeOf(&ValidationError{}))
+				Expect(err.Error()).Should(ContainSubstring("unknown blockType"))
+			})
+		})
+		When("config file is valid", func() {
+			It("should remember the file path", func() {
+				file, err := ioutil.TempFile("", "blocky")
+				Expect(err).Should(Succeed())
+				defer os.Remove(file.Name())
+
+				_, err = file.WriteString("upstream:\n  externalResolvers:\n    - udp:8.8.8.8")
+				Expect(err).Should(Succeed())
+
+				cfg, err := LoadConfig(file.Name())
+				Expect(err).Should(Succeed())
+				Expect(cfg.Path()).Should(Equal(file.Name()))
 			})
 		})
 	})
@@ -172,4 +193,26 @@ var _ = Describe("Config", func() {
 			nil,
 			true),
 	)
+
+	DescribeTable("ECS policy of a domain",
+		func(domain, expected string) {
+			cfg := ECSConfig{
+				Policy: "Synthesize",
+				Domains: map[string]string{
+					"example.com":      ECSPolicyStrip,
+					"cdn.example.com.": ECSPolicyForward,
+				},
+			}
+			Expect(cfg.PolicyFor(domain)).Should(Equal(expected))
+		},
+		Entry("default policy", "other.com", ECSPolicySynthesize),
+		Entry("domain itself", "example.com.", ECSPolicyStrip),
+		Entry("subdomain", "www.example.com", ECSPolicyStrip),
+		Entry("longest suffix wins", "img.CDN.example.com", ECSPolicyForward),
+		Entry("not a subdomain", "myexample.com", ECSPolicySynthesize),
+	)
+
+	It("should strip ECS by default", func() {
+		Expect((&ECSConfig{}).PolicyFor("example.com")).Should(Equal(ECSPolicyStrip))
+	})
 })
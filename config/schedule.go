@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ScheduleSeparator separates the group from the schedule name in clientGroupsBlock entries ("social@school-nights")
+const ScheduleSeparator = "@"
+
+// Schedule defines the time windows, in which groups with this schedule are active
+type Schedule struct {
+	// IANA time zone name (for example "Europe/Berlin"), default: local time zone of the server
+	TimeZone string           `yaml:"timeZone"`
+	Windows  []ScheduleWindow `yaml:"windows"`
+}
+
+// ScheduleWindow is a time range on the given week days. If "to" is not after "from", the window ends on the next day
+type ScheduleWindow struct {
+	// week days on which the window starts (mon, tue, ...), empty means every day
+	Days []string `yaml:"days"`
+	// start time (inclusive) in format "15:04"
+	From string `yaml:"from"`
+	// end time (exclusive) in format "15:04", "24:00" is the end of the day
+	To string `yaml:"to"`
+}
+
+// SplitGroupSchedule splits a clientGroupsBlock entry into the group and the schedule name.
+// The schedule is empty if the group is always active
+func SplitGroupSchedule(entry string) (group string, schedule string) {
+	if idx := strings.Index(entry, ScheduleSeparator); idx >= 0 {
+		return entry[:idx], entry[idx+1:]
+	}
+
+	return entry, ""
+}
+
+// ParseTimeOfDay converts "15:04" to minutes since midnight, "24:00" is allowed as end of the day
+func ParseTimeOfDay(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if value == "24:00" {
+		return 24 * 60, nil
+	}
+
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time '%s', please use format HH:MM", value)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseWeekday converts the abbreviated or full english day name (case insensitive) to time.Weekday
+func ParseWeekday(value string) (time.Weekday, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		name := strings.ToLower(d.String())
+		if value == name || value == name[:3] {
+			return d, nil
+		}
+	}
+
+	return time.Sunday, fmt.Errorf("invalid day '%s', please use mon, tue, wed, thu, fri, sat or sun", value)
+}
@@ -0,0 +1,479 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/log"
+	"github.com/privacyherodev/ph-blocky/util"
+
+	"github.com/miekg/dns"
+	"github.com/sirupsen/logrus"
+)
+
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	names := [...]string{
+		"error",
+		"warning"}
+
+	return names[s]
+}
+
+// Issue is a single problem found during the validation of the configuration
+type Issue struct {
+	Severity Severity
+	// path of the affected configuration key, for example "blocking.blockType"
+	Path    string
+	Message string
+}
+
+func (i Issue) String() string {
+	return fmt.Sprintf("%s: %s: %s", i.Severity, i.Path, i.Message)
+}
+
+// Issues is the result of the configuration validation
+type Issues []Issue
+
+// Errors returns only issues with severity error
+func (i Issues) Errors() Issues {
+	return i.filter(SeverityError)
+}
+
+// Warnings returns only issues with severity warning
+func (i Issues) Warnings() Issues {
+	return i.filter(SeverityWarning)
+}
+
+func (i Issues) filter(severity Severity) (result Issues) {
+	for _, issue := range i {
+		if issue.Severity == severity {
+			result = append(result, issue)
+		}
+	}
+
+	return
+}
+
+// ValidationError is returned, if the configuration contains at least one issue with severity error
+type ValidationError struct {
+	Issues Issues
+}
+
+func (e *ValidationError) Error() string {
+	errs := e.Issues.Errors()
+	messages := make([]string, len(errs))
+
+	for i, issue := range errs {
+		messages[i] = fmt.Sprintf("%s: %s", issue.Path, issue.Message)
+	}
+
+	return fmt.Sprintf("invalid configuration: %s", strings.Join(messages, "; "))
+}
+
+type validator struct {
+	issues Issues
+}
+
+func (v *validator) errorf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Severity: SeverityError, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) warnf(path, format string, args ...interface{}) {
+	v.issues = append(v.issues, Issue{Severity: SeverityWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// Validate checks the configuration values and references between the sections
+func (c *Config) Validate() Issues {
+	v := &validator{}
+
+	v.validateLogging(c)
+	v.validateUpstream(c)
+	v.validateBlocking(&c.Blocking)
+	v.validateCname(&c.Cname)
+	v.validateClientLookup(&c.ClientLookup)
+	v.validateQueryLog(&c.QueryLog)
+	v.validateListeners(c)
+
+	return v.issues
+}
+
+func (v *validator) validateLogging(c *Config) {
+	if c.LogFormat != log.CfgLogFormatText && c.LogFormat != log.CfgLogFormatJSON {
+		v.errorf("logFormat", "should be '%s' or '%s'", log.CfgLogFormatText, log.CfgLogFormatJSON)
+	}
+
+	if _, err := logrus.ParseLevel(c.LogLevel); err != nil {
+		v.errorf("logLevel", "invalid log level '%s'", c.LogLevel)
+	}
+}
+
+func (v *validator) validateUpstream(c *Config) {
+	if len(c.Upstream.ExternalResolvers) == 0 {
+		v.errorf("upstream.externalResolvers", "at least one external resolver is required")
+	}
+
+	v.validateECS(&c.Upstream.ECS)
+
+	if c.BootstrapDNS != (Upstream{}) && c.BootstrapDNS.Net != "tcp" && c.BootstrapDNS.Net != "udp" {
+		v.errorf("bootstrapDns", "net should be tcp or udp, but was '%s'", c.BootstrapDNS.Net)
+	}
+}
+
+// validateECS checks the policies and the prefix lengths of synthesized client subnets
+func (v *validator) validateECS(cfg *ECSConfig) {
+	const policies = "please use one of: strip, forward, synthesize"
+
+	if !IsValidECSPolicy(cfg.Policy) {
+		v.errorf("upstream.ecs.policy", "unknown policy '%s', %s", cfg.Policy, policies)
+	}
+
+	for _, domain := range util.SortedKeys(cfg.Domains) {
+		if !IsValidECSPolicy(cfg.Domains[domain]) {
+			v.errorf(fmt.Sprintf("upstream.ecs.domains.%s", domain), "unknown policy '%s', %s", cfg.Domains[domain],
+				policies)
+		}
+	}
+
+	if cfg.IPv4Mask > 32 {
+		v.errorf("upstream.ecs.ipv4Mask", "prefix length %d is greater than 32", cfg.IPv4Mask)
+	}
+
+	if cfg.IPv6Mask > 128 {
+		v.errorf("upstream.ecs.ipv6Mask", "prefix length %d is greater than 128", cfg.IPv6Mask)
+	}
+}
+
+func (v *validator) validateBlocking(cfg *BlockingConfig) {
+	if !isValidBlockType(cfg.BlockType) {
+		v.errorf("blocking.blockType",
+			"unknown blockType '%s', please use one of: ZeroIP, NxDomain or specify destination IP address(es)",
+			cfg.BlockType)
+	}
+
+	for _, group := range util.SortedKeys(cfg.GroupBlockTypes) {
+		path := fmt.Sprintf("blocking.groupBlockTypes.%s", group)
+
+		if !isValidBlockType(cfg.GroupBlockTypes[group]) {
+			v.errorf(path,
+				"unknown blockType '%s', please use one of: ZeroIP, NxDomain or specify destination IP address(es)",
+				cfg.GroupBlockTypes[group])
+		}
+
+		if _, found := cfg.BlackLists[group]; !found {
+			v.warnf(path, "group '%s' is not defined in blackLists", group)
+		}
+	}
+
+	mode := strings.ToLower(strings.TrimSpace(cfg.MatchMode))
+	if mode != "" && mode != "exact" && mode != "subdomains" {
+		v.errorf("blocking.matchMode", "unknown match mode '%s', please use 'exact' or 'subdomains'", cfg.MatchMode)
+	}
+
+	if cfg.DownloadCacheDir != "" {
+		if fi, err := os.Stat(cfg.DownloadCacheDir); err == nil && !fi.IsDir() {
+			v.errorf("blocking.downloadCacheDir", "'%s' is not a directory", cfg.DownloadCacheDir)
+		}
+	}
+
+	v.validateWritableFile("blocking.overlayFile", cfg.OverlayFile)
+	v.validateWritableFile("blocking.stateFile", cfg.StateFile)
+
+	for _, group := range util.SortedKeys(cfg.BlackLists) {
+		v.validateLinks(fmt.Sprintf("blocking.blackLists.%s", group), cfg.BlackLists[group])
+	}
+
+	for _, group := range util.SortedKeys(cfg.WhiteLists) {
+		v.validateLinks(fmt.Sprintf("blocking.whiteLists.%s", group), cfg.WhiteLists[group])
+	}
+
+	for _, client := range util.SortedKeys(cfg.ClientGroupsBlock) {
+		v.validateClientKey(fmt.Sprintf("blocking.clientGroupsBlock.%s", client), client)
+
+		for _, entry := range cfg.ClientGroupsBlock[client] {
+			group, schedule := SplitGroupSchedule(entry)
+			_, inBlackLists := cfg.BlackLists[group]
+			_, inWhiteLists := cfg.WhiteLists[group]
+
+			if !inBlackLists && !inWhiteLists {
+				v.errorf(fmt.Sprintf("blocking.clientGroupsBlock.%s", client),
+					"group '%s' is defined neither in blackLists nor in whiteLists", group)
+			}
+
+			if _, found := cfg.Schedules[schedule]; schedule != "" && !found {
+				v.errorf(fmt.Sprintf("blocking.clientGroupsBlock.%s", client), "schedule '%s' is not defined", schedule)
+			}
+		}
+	}
+
+	v.validateSchedules(cfg.Schedules)
+
+	v.validateToggles(cfg)
+
+	for group := range cfg.Global {
+		_, inBlackLists := cfg.BlackLists[group]
+		_, inWhiteLists := cfg.WhiteLists[group]
+
+		if !inBlackLists && !inWhiteLists {
+			v.warnf(fmt.Sprintf("blocking.global.%s", group),
+				"group '%s' is defined neither in blackLists nor in whiteLists", group)
+		}
+	}
+}
+
+func (v *validator) validateToggles(cfg *BlockingConfig) {
+	for _, name := range cfg.Toggles {
+		_, inBlackLists := cfg.BlackLists[name]
+		_, inWhiteLists := cfg.WhiteLists[name]
+
+		if !inBlackLists && !inWhiteLists {
+			v.warnf("blocking.toggles", "group '%s' is defined neither in blackLists nor in whiteLists", name)
+		}
+	}
+
+	toggles := make(map[string]bool)
+	for _, name := range cfg.ToggleNames() {
+		toggles[name] = true
+	}
+
+	for _, client := range util.SortedKeys(cfg.ClientGroupsBlock) {
+		// groups of clients identified by MAC address are only checked, if they are toggled on
+		if _, err := net.ParseMAC(client); err != nil && !IsMACPrefixKey(client) {
+			continue
+		}
+
+		for _, entry := range cfg.ClientGroupsBlock[client] {
+			if group, _ := SplitGroupSchedule(entry); !toggles[group] {
+				v.warnf(fmt.Sprintf("blocking.clientGroupsBlock.%s", client),
+					"group '%s' is not in toggles and will be ignored for this MAC address", group)
+			}
+		}
+	}
+}
+
+func (v *validator) validateSchedules(schedules map[string]Schedule) {
+	for _, name := range util.SortedKeys(schedules) {
+		schedule := schedules[name]
+		path := fmt.Sprintf("blocking.schedules.%s", name)
+
+		if _, err := time.LoadLocation(schedule.TimeZone); err != nil {
+			v.errorf(path, "unknown time zone '%s'", schedule.TimeZone)
+		}
+
+		if len(schedule.Windows) == 0 {
+			v.warnf(path, "schedule has no windows, groups with this schedule are never active")
+		}
+
+		for i, window := range schedule.Windows {
+			windowPath := fmt.Sprintf("%s.windows[%d]", path, i)
+
+			for _, day := range window.Days {
+				if _, err := ParseWeekday(day); err != nil {
+					v.errorf(windowPath, "%v", err)
+				}
+			}
+
+			for _, value := range []string{window.From, window.To} {
+				if _, err := ParseTimeOfDay(value); err != nil {
+					v.errorf(windowPath, "%v", err)
+				}
+			}
+		}
+	}
+}
+
+// validateClientKey checks CIDR ranges and MAC address prefixes in clientGroupsBlock keys
+func (v *validator) validateClientKey(path, key string) {
+	switch {
+	case IsCIDRKey(key):
+		if _, _, err := net.ParseCIDR(key); err != nil {
+			v.errorf(path, "invalid IP range '%s', please use CIDR notation like 192.168.10.0/24", key)
+		}
+	case IsMACPrefixKey(key):
+		if _, _, err := ParseMACPrefix(key); err != nil {
+			v.errorf(path, "%v", err)
+		}
+	}
+}
+
+// validateWritableFile checks that the optional file is not a directory and its directory exists
+func (v *validator) validateWritableFile(path, file string) {
+	if file == "" {
+		return
+	}
+
+	if fi, err := os.Stat(file); err == nil && fi.IsDir() {
+		v.errorf(path, "'%s' is a directory", file)
+	} else if _, err := os.Stat(filepath.Dir(file)); err != nil {
+		v.errorf(path, "directory of '%s' does not exist", file)
+	}
+}
+
+func (v *validator) validateLinks(path string, links []string) {
+	if len(links) == 0 {
+		v.warnf(path, "group has no lists")
+	}
+
+	for _, link := range links {
+		if strings.HasPrefix(link, "http") {
+			continue
+		}
+
+		if _, err := os.Stat(strings.TrimPrefix(link, "file://")); err != nil {
+			v.warnf(path, "can't read list file '%s': %v", link, err)
+		}
+	}
+}
+
+func (v *validator) validateCname(cfg *CnameConfig) {
+	for _, name := range util.SortedKeys(cfg.Groups) {
+		group := cfg.Groups[name]
+		path := fmt.Sprintf("cname.groups.%s", name)
+
+		if strings.TrimSpace(group.Cname) == "" {
+			v.errorf(path, "cname target is empty")
+		}
+
+		if len(group.Domains) == 0 {
+			v.warnf(path, "group has no domains")
+		}
+	}
+
+	for _, client := range util.SortedKeys(cfg.ClientGroupsBlock) {
+		v.validateClientKey(fmt.Sprintf("cname.clientGroupsBlock.%s", client), client)
+
+		for _, group := range cfg.ClientGroupsBlock[client] {
+			if name, schedule := SplitGroupSchedule(group); len(schedule) > 0 {
+				v.errorf(fmt.Sprintf("cname.clientGroupsBlock.%s", client),
+					"schedule '%s' of cname group '%s' is not supported, schedules apply to blocking groups only",
+					schedule, name)
+			} else if _, found := cfg.Groups[group]; !found {
+				v.errorf(fmt.Sprintf("cname.clientGroupsBlock.%s", client), "cname group '%s' is not defined", group)
+			}
+		}
+	}
+}
+
+func (v *validator) validateClientLookup(cfg *ClientLookupConfig) {
+	for _, i := range cfg.SingleNameOrder {
+		if i == 0 {
+			v.warnf("clientLookup.singleNameOrder", "order starts with 1, value 0 will be ignored")
+		}
+	}
+
+	v.validateEDNS0(&cfg.EDNS0)
+}
+
+// validateEDNS0 checks that the option codes are distinct and in the range of local options
+func (v *validator) validateEDNS0(cfg *EDNS0Config) {
+	used := make(map[uint16]string)
+
+	for _, option := range []struct {
+		name string
+		code uint16
+	}{{"macBinary", cfg.MACBinary}, {"macText", cfg.MACText}, {"clientID", cfg.ClientID}} {
+		if option.code == 0 {
+			continue
+		}
+
+		path := "clientLookup.edns0." + option.name
+
+		if other, found := used[option.code]; found {
+			v.errorf(path, "option code %d is already used by %s", option.code, other)
+		}
+
+		used[option.code] = option.name
+
+		if option.code < dns.EDNS0LOCALSTART || option.code > dns.EDNS0LOCALEND {
+			v.warnf(path, "option code %d is not in the range of local options (%d-%d) and will be ignored",
+				option.code, dns.EDNS0LOCALSTART, dns.EDNS0LOCALEND)
+		}
+	}
+}
+
+func (v *validator) validateQueryLog(cfg *QueryLogConfig) {
+	if cfg.Dir == "" {
+		return
+	}
+
+	fi, err := os.Stat(cfg.Dir)
+
+	switch {
+	case err != nil:
+		v.errorf("queryLog.dir", "query log directory '%s' does not exist", cfg.Dir)
+	case !fi.IsDir():
+		v.errorf("queryLog.dir", "'%s' is not a directory", cfg.Dir)
+	}
+}
+
+func (v *validator) validateListeners(c *Config) {
+	if c.HTTPSPort > 0 && (c.CertFile == "" || c.KeyFile == "") {
+		v.errorf("httpsPort", "httpsCertFile and httpsKeyFile parameters are mandatory for HTTPS")
+	}
+
+	if c.Prometheus.Enable && c.HTTPPort == 0 && c.HTTPSPort == 0 {
+		v.warnf("prometheus.enable", "prometheus endpoint requires httpPort or httpsPort")
+	}
+
+	v.validateBlockPage(c)
+}
+
+func (v *validator) validateBlockPage(c *Config) {
+	cfg := &c.BlockPage
+
+	usedPort := func(port uint16) bool {
+		return port > 0 && (port == c.Port || port == c.HTTPPort || port == c.HTTPSPort)
+	}
+
+	if usedPort(cfg.HTTPPort) {
+		v.errorf("blockPage.httpPort", "port %d is already used by another listener", cfg.HTTPPort)
+	}
+
+	if usedPort(cfg.HTTPSPort) || (cfg.HTTPSPort > 0 && cfg.HTTPSPort == cfg.HTTPPort) {
+		v.errorf("blockPage.httpsPort", "port %d is already used by another listener", cfg.HTTPSPort)
+	}
+
+	if cfg.HTTPSPort > 0 {
+		if cert := c.BlockPageDefaultCertificate(); cert.CertFile == "" || cert.KeyFile == "" {
+			v.errorf("blockPage.httpsPort", "certFile and keyFile (or httpsCertFile and httpsKeyFile) are mandatory for HTTPS")
+		}
+	}
+
+	for i, cert := range cfg.Certificates {
+		if cert.CertFile == "" || cert.KeyFile == "" {
+			v.errorf(fmt.Sprintf("blockPage.certificates[%d]", i), "certFile and keyFile are mandatory")
+		}
+	}
+
+	if (cfg.HTTPPort > 0 || cfg.HTTPSPort > 0) && !hasIPBlockType(&c.Blocking) {
+		v.warnf("blockPage", "block page is only reachable, if blockType contains the IP address of blocky")
+	}
+}
+
+// hasIPBlockType returns true if the block type or one of the group block types contains an IP address
+func hasIPBlockType(cfg *BlockingConfig) bool {
+	blockTypes := []string{cfg.BlockType}
+	for _, blockType := range cfg.GroupBlockTypes {
+		blockTypes = append(blockTypes, blockType)
+	}
+
+	for _, blockType := range blockTypes {
+		for _, part := range strings.Split(blockType, ",") {
+			if net.ParseIP(strings.TrimSpace(part)) != nil {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,277 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validation", func() {
+	var (
+		cfg      Config
+		listFile *os.File
+	)
+
+	BeforeEach(func() {
+		var err error
+		listFile, err = ioutil.TempFile("", "blocky")
+		Expect(err).Should(Succeed())
+
+		cfg = Config{
+			Upstream: UpstreamConfig{ExternalResolvers: []Upstream{{Net: "udp", Host: "8.8.8.8", Port: 53}}},
+			Blocking: BlockingConfig{
+				BlackLists:        map[string][]string{"ads": {listFile.Name()}},
+				ClientGroupsBlock: map[string][]string{"default": {"ads"}},
+			},
+		}
+		setDefaultValues(&cfg)
+	})
+
+	AfterEach(func() {
+		_ = os.Remove(listFile.Name())
+	})
+
+	When("configuration is valid", func() {
+		It("should not return any issues", func() {
+			Expect(cfg.Validate()).Should(BeEmpty())
+		})
+	})
+
+	When("configuration contains errors", func() {
+		It("should report unknown block type", func() {
+			cfg.Blocking.BlockType = "wrong"
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(1))
+			Expect(issues[0].Path).Should(Equal("blocking.blockType"))
+		})
+		It("should report unknown block types of groups", func() {
+			cfg.Blocking.GroupBlockTypes = map[string]string{"ads": "wrong", "unknown": "NxDomain"}
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(1))
+			Expect(issues.Errors()[0].Path).Should(Equal("blocking.groupBlockTypes.ads"))
+			Expect(issues.Warnings()).Should(ContainElement(
+				Issue{Path: "blocking.groupBlockTypes.unknown", Message: "group 'unknown' is not defined in blackLists",
+					Severity: SeverityWarning}))
+		})
+		It("should report groups, which are not defined in black or white lists", func() {
+			cfg.Blocking.ClientGroupsBlock["laptop"] = []string{"ads", "unknown"}
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(1))
+			Expect(issues[0].Path).Should(Equal("blocking.clientGroupsBlock.laptop"))
+			Expect(issues[0].Message).Should(ContainSubstring("'unknown'"))
+		})
+		It("should report invalid IP ranges and MAC address prefixes", func() {
+			cfg.Blocking.ClientGroupsBlock["192.168.10.0/33"] = []string{"ads"}
+			cfg.Blocking.ClientGroupsBlock["48:52:4x:*"] = []string{"ads"}
+			cfg.Blocking.ClientGroupsBlock["192.168.0.0/16"] = []string{"ads"}
+			cfg.Blocking.ClientGroupsBlock["48-52-4A-*"] = []string{"ads"}
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(2))
+			Expect(issues[0].Path).Should(Equal("blocking.clientGroupsBlock.192.168.10.0/33"))
+			Expect(issues[1].Path).Should(Equal("blocking.clientGroupsBlock.48:52:4x:*"))
+		})
+		It("should report EDNS0 option codes, which are used twice", func() {
+			cfg.ClientLookup.EDNS0 = EDNS0Config{MACBinary: 65001, MACText: 65001, ClientID: 10}
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(1))
+			Expect(issues.Errors()[0].Path).Should(Equal("clientLookup.edns0.macText"))
+			Expect(issues.Warnings()).Should(HaveLen(1))
+			Expect(issues.Warnings()[0].Path).Should(Equal("clientLookup.edns0.clientID"))
+		})
+		It("should report unknown ECS policies and invalid prefix lengths", func() {
+			cfg.Upstream.ECS = ECSConfig{
+				Policy:   "send",
+				Domains:  map[string]string{"example.com": "Forward", "cdn.example.com": "synthesise"},
+				IPv4Mask: 33,
+				IPv6Mask: 56,
+			}
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(3))
+			Expect(issues.Errors()[0].Path).Should(Equal("upstream.ecs.policy"))
+			Expect(issues.Errors()[1].Path).Should(Equal("upstream.ecs.domains.cdn.example.com"))
+			Expect(issues.Errors()[2].Path).Should(Equal("upstream.ecs.ipv4Mask"))
+		})
+		It("should report unknown schedules and invalid schedule definitions", func() {
+			cfg.Blocking.ClientGroupsBlock["kids-tablet"] = []string{"ads@school-nights", "ads@unknown"}
+			cfg.Blocking.Schedules = map[string]Schedule{
+				"school-nights": {
+					TimeZone: "Europe/Nowhere",
+					Windows: []ScheduleWindow{
+						{Days: []string{"sun", "mon", "funday"}, From: "21:00", To: "7 o'clock"},
+					},
+				},
+			}
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(4))
+			Expect(issues[0].Path).Should(Equal("blocking.clientGroupsBlock.kids-tablet"))
+			Expect(issues[0].Message).Should(ContainSubstring("'unknown'"))
+			Expect(issues[1].Path).Should(Equal("blocking.schedules.school-nights"))
+			Expect(issues[1].Message).Should(ContainSubstring("'Europe/Nowhere'"))
+			Expect(issues[2].Path).Should(Equal("blocking.schedules.school-nights.windows[0]"))
+			Expect(issues[2].Message).Should(ContainSubstring("'funday'"))
+			Expect(issues[3].Message).Should(ContainSubstring("'7 o'clock'"))
+		})
+		It("should accept groups with valid schedules", func() {
+			cfg.Blocking.ClientGroupsBlock["kids-tablet"] = []string{"ads@school-nights"}
+			cfg.Blocking.Schedules = map[string]Schedule{
+				"school-nights": {
+					TimeZone: "Europe/Berlin",
+					Windows: []ScheduleWindow{
+						{Days: []string{"Sun", "monday"}, From: "21:00", To: "07:00"},
+						{From: "12:00", To: "24:00"},
+					},
+				},
+			}
+
+			Expect(cfg.Validate()).Should(BeEmpty())
+		})
+		It("should report cname groups with empty target and unknown references", func() {
+			cfg.Cname = CnameConfig{
+				Groups: map[string]Groups{
+					"youtube": {Domains: []string{"youtube.com"}},
+				},
+				ClientGroupsBlock: map[string][]string{
+					"default": {"youtube", "unknown"},
+				},
+			}
+
+			issues := cfg.Validate().Errors()
+			Expect(issues).Should(HaveLen(2))
+			Expect(issues[0].Path).Should(Equal("cname.groups.youtube"))
+			Expect(issues[1].Path).Should(Equal("cname.clientGroupsBlock.default"))
+		})
+		It("should reject schedules in cname groups", func() {
+			cfg.Cname = CnameConfig{
+				Groups: map[string]Groups{
+					"youtube": {Cname: "restrict.youtube.com", Domains: []string{"youtube.com"}},
+				},
+				ClientGroupsBlock: map[string][]string{
+					"default": {"youtube@school-nights"},
+				},
+			}
+
+			issues := cfg.Validate().Errors()
+			Expect(issues).Should(HaveLen(1))
+			Expect(issues[0].Path).Should(Equal("cname.clientGroupsBlock.default"))
+			Expect(issues[0].Message).Should(ContainSubstring("not supported"))
+		})
+		It("should report missing query log directory", func() {
+			cfg.QueryLog.Dir = "/notExisting"
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(1))
+			Expect(issues[0].Path).Should(Equal("queryLog.dir"))
+			Expect(issues[0].Message).Should(ContainSubstring("does not exist"))
+		})
+		It("should accept writable query log directory", func() {
+			dir, err := ioutil.TempDir("", "querylog")
+			Expect(err).Should(Succeed())
+			defer os.RemoveAll(dir)
+
+			cfg.QueryLog.Dir = dir
+
+			Expect(cfg.Validate().Errors()).Should(BeEmpty())
+
+			files, _ := ioutil.ReadDir(dir)
+			Expect(files).Should(BeEmpty())
+		})
+		It("should report download cache dir, which is not a directory", func() {
+			file, err := ioutil.TempFile("", "blocky")
+			Expect(err).Should(Succeed())
+			defer os.Remove(file.Name())
+
+			cfg.Blocking.DownloadCacheDir = file.Name()
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(1))
+			Expect(issues[0].Path).Should(Equal("blocking.downloadCacheDir"))
+		})
+		It("should report overlay file in not existing directory", func() {
+			cfg.Blocking.OverlayFile = "/notExisting/overlay.json"
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(1))
+			Expect(issues[0].Path).Should(Equal("blocking.overlayFile"))
+		})
+		It("should report state file, which is a directory", func() {
+			cfg.Blocking.StateFile = os.TempDir()
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(HaveLen(1))
+			Expect(issues[0].Path).Should(Equal("blocking.stateFile"))
+			Expect(issues[0].Message).Should(ContainSubstring("is a directory"))
+		})
+		It("should report block page ports in use and missing certificates", func() {
+			cfg.HTTPPort = 4000
+			cfg.Blocking.BlockType = "192.168.178.3"
+			cfg.BlockPage = BlockPageConfig{
+				HTTPPort:     4000,
+				HTTPSPort:    443,
+				Certificates: []CertificateConfig{{CertFile: "example.crt"}},
+			}
+
+			issues := cfg.Validate()
+			Expect(issues).Should(HaveLen(3))
+			Expect(issues[0].Path).Should(Equal("blockPage.httpPort"))
+			Expect(issues[1].Path).Should(Equal("blockPage.httpsPort"))
+			Expect(issues[1].Message).Should(ContainSubstring("mandatory"))
+			Expect(issues[2].Path).Should(Equal("blockPage.certificates[0]"))
+		})
+		It("should warn if the block page is not reachable with the block type", func() {
+			cfg.BlockPage = BlockPageConfig{HTTPPort: 80}
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(BeEmpty())
+			Expect(issues.Warnings()).Should(HaveLen(1))
+			Expect(issues[0].Path).Should(Equal("blockPage"))
+
+			cfg.Blocking.GroupBlockTypes = map[string]string{"ads": "192.168.178.3"}
+			Expect(cfg.Validate()).Should(BeEmpty())
+		})
+		It("should warn about undefined toggles and groups of MAC addresses, which are not toggleable", func() {
+			cfg.Blocking.Toggles = []string{"ads", "gambling"}
+			cfg.Blocking.BlackLists["special"] = []string{listFile.Name()}
+			cfg.Blocking.ClientGroupsBlock["48:52:4a:00:00:01"] = []string{"ads", "special"}
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(BeEmpty())
+			Expect(issues).Should(HaveLen(2))
+			Expect(issues[0].Path).Should(Equal("blocking.toggles"))
+			Expect(issues[0].Message).Should(ContainSubstring("'gambling'"))
+			Expect(issues[1].Path).Should(Equal("blocking.clientGroupsBlock.48:52:4a:00:00:01"))
+			Expect(issues[1].Message).Should(ContainSubstring("'special'"))
+		})
+		It("should report missing upstream resolvers and wrong log level", func() {
+			cfg.Upstream.ExternalResolvers = nil
+			cfg.LogLevel = "verbose"
+
+			Expect(cfg.Validate().Errors()).Should(HaveLen(2))
+		})
+		It("should be returned as ValidationError", func() {
+			cfg.Blocking.BlockType = "wrong"
+
+			err := &ValidationError{Issues: cfg.Validate()}
+			Expect(err.Error()).Should(ContainSubstring("blocking.blockType"))
+		})
+	})
+
+	When("configuration contains warnings", func() {
+		It("should report not existing list files", func() {
+			cfg.Blocking.BlackLists["ads"] = append(cfg.Blocking.BlackLists["ads"], "/notExisting.txt")
+
+			issues := cfg.Validate()
+			Expect(issues.Errors()).Should(BeEmpty())
+			Expect(issues.Warnings()).Should(HaveLen(1))
+			Expect(issues[0].String()).Should(HavePrefix("warning: blocking.blackLists.ads"))
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package lists
+
+import (
+	"net"
+)
+
+// cidrTree is a binary prefix tree of IP ranges ("10.0.0.0/8", "2001:db8::/32"). A lookup walks the bits of the
+// address and returns the longest prefix, which contains the address, so the costs depend only on the address length
+// and not on the number of ranges. IPv4 and IPv6 ranges are stored in separate trees
+type cidrTree struct {
+	v4    *cidrNode
+	v6    *cidrNode
+	count int
+}
+
+type cidrNode struct {
+	children [2]*cidrNode
+	// range in CIDR notation, empty if no range ends at this node
+	prefix string
+	line   int32
+}
+
+// add inserts the range, for duplicates the line number of the first occurrence is kept
+func (t *cidrTree) add(ipNet *net.IPNet, line int) {
+	ip, root := t.root(ipNet.IP, len(ipNet.Mask) == net.IPv4len)
+	ones, _ := ipNet.Mask.Size()
+
+	if *root == nil {
+		*root = &cidrNode{}
+	}
+
+	node := *root
+	for i := 0; i < ones; i++ {
+		bit := bitAt(ip, i)
+		if node.children[bit] == nil {
+			node.children[bit] = &cidrNode{}
+		}
+
+		node = node.children[bit]
+	}
+
+	if len(node.prefix) == 0 {
+		node.prefix = ipNet.String()
+		node.line = int32(line)
+		t.count++
+	}
+}
+
+// lookup returns the longest range which contains the IP address with its prefix length and line number
+// or empty string
+func (t *cidrTree) lookup(address net.IP) (string, int, int) {
+	ip, root := t.root(address, address.To4() != nil)
+	if *root == nil {
+		return "", 0, 0
+	}
+
+	node := *root
+	prefix, ones, line := node.prefix, 0, node.line
+
+	for i := 0; i < len(ip)*8; i++ {
+		node = node.children[bitAt(ip, i)]
+		if node == nil {
+			break
+		}
+
+		if len(node.prefix) > 0 {
+			prefix, ones, line = node.prefix, i+1, node.line
+		}
+	}
+
+	return prefix, ones, int(line)
+}
+
+// prefixes returns all ranges of the tree
+func (t *cidrTree) prefixes() []string {
+	result := make([]string, 0, t.count)
+
+	var walk func(n *cidrNode)
+	walk = func(n *cidrNode) {
+		if n == nil {
+			return
+		}
+
+		if len(n.prefix) > 0 {
+			result = append(result, n.prefix)
+		}
+
+		walk(n.children[0])
+		walk(n.children[1])
+	}
+
+	walk(t.v4)
+	walk(t.v6)
+
+	return result
+}
+
+// root returns the address in its tree representation (4 or 16 bytes) and the root of the matching tree
+func (t *cidrTree) root(ip net.IP, v4 bool) (net.IP, **cidrNode) {
+	if v4 {
+		return ip.To4(), &t.v4
+	}
+
+	return ip.To16(), &t.v6
+}
+
+func bitAt(ip net.IP, i int) int {
+	return int(ip[i/8]>>(7-uint(i%8))) & 1
+}
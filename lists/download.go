@@ -0,0 +1,218 @@
+package lists
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// sourceState contains the validators of the last successful download of a link.
+// It is used to send conditional requests and to skip parsing, if the list was not modified
+type sourceState struct {
+	etag         string
+	lastModified string
+}
+
+// downloadResult is the response of a (conditional) download
+type downloadResult struct {
+	// content of the list, nil if the list was not modified
+	body         io.ReadCloser
+	etag         string
+	lastModified string
+	statusCode   int
+	// error of the failed download, if the body is the stored copy from the download cache
+	downloadErr error
+}
+
+func (d *downloadResult) notModified() bool {
+	return d.body == nil
+}
+
+// downloadFile downloads the link. If etag or lastModified is set, a conditional request will be sent
+func downloadFile(link string, etag, lastModified string) (*downloadResult, error) {
+	client := http.Client{
+		Timeout: timeout,
+	}
+
+	req, err := http.NewRequest(http.MethodGet, link, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(etag) > 0 {
+		req.Header.Set("If-None-Match", etag)
+	}
+
+	if len(lastModified) > 0 {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+
+	var resp *http.Response
+
+	logger().WithField("link", link).Info("starting download")
+
+	attempt := 1
+
+	for attempt <= 3 {
+		//nolint:bodyclose
+		if resp, err = client.Do(req); err == nil {
+			switch resp.StatusCode {
+			case http.StatusOK:
+				return &downloadResult{
+					body:         resp.Body,
+					etag:         resp.Header.Get("ETag"),
+					lastModified: resp.Header.Get("Last-Modified"),
+					statusCode:   resp.StatusCode,
+				}, nil
+			case http.StatusNotModified:
+				resp.Body.Close()
+
+				return &downloadResult{etag: etag, lastModified: lastModified, statusCode: resp.StatusCode}, nil
+			}
+
+			resp.Body.Close()
+
+			return &downloadResult{statusCode: resp.StatusCode},
+				fmt.Errorf("couldn't download url, got status code %d", resp.StatusCode)
+		}
+
+		if errNet, ok := err.(net.Error); ok && (errNet.Timeout() || errNet.Temporary()) {
+			logger().WithField("link", link).WithField("attempt",
+				attempt).Warnf("Temporary network error / Timeout occurred, retrying... %s", errNet)
+			time.Sleep(time.Second)
+			attempt++
+		} else {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+// downloads the link and stores a copy in the download cache. If the download fails, the stored copy is used.
+// A conditional request is sent, if the previous download of the link returned validators
+func (b *ListCache) download(link string) (*downloadResult, error) {
+	var etag, lastModified string
+
+	if state := b.sourceState(link); state != nil {
+		etag, lastModified = state.etag, state.lastModified
+	}
+
+	res, err := downloadFile(link, etag, lastModified)
+	if b.downloadCache == nil {
+		return res, err
+	}
+
+	if err == nil {
+		if res.notModified() {
+			b.setFallback(link, time.Time{})
+
+			return res, nil
+		}
+
+		tmp, createErr := b.downloadCache.createTemp()
+		if createErr != nil {
+			logger().WithField("link", link).Warn("can't store copy of downloaded list: ", createErr)
+
+			b.setFallback(link, time.Time{})
+
+			return res, nil
+		}
+
+		// the download is streamed into the copy, which is read afterwards. If the download breaks off,
+		// the previous copy is kept and used instead
+		stored, storeErr := b.downloadCache.store(tmp, link, res.body)
+		res.body.Close()
+
+		if storeErr == nil {
+			b.setFallback(link, time.Time{})
+
+			res.body = stored
+
+			return res, nil
+		}
+
+		err = storeErr
+	}
+
+	f, downloaded, loadErr := b.downloadCache.load(link)
+	if loadErr != nil {
+		return nil, err
+	}
+
+	logger().WithFields(logrus.Fields{
+		"link": link,
+		"age":  time.Since(downloaded).Round(time.Second),
+	}).Warnf("download failed, using stored copy: %v", err)
+
+	b.setFallback(link, downloaded)
+
+	fallback := &downloadResult{body: f, downloadErr: err}
+	if res != nil {
+		fallback.statusCode = res.statusCode
+	}
+
+	return fallback, nil
+}
+
+// sourceState returns the state of the last successful download of the link or nil
+func (b *ListCache) sourceState(link string) *sourceState {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return b.sources[link]
+}
+
+// setSourceState stores the state, if the server returned validators for conditional requests
+func (b *ListCache) setSourceState(link string, state *sourceState) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if len(state.etag) > 0 || len(state.lastModified) > 0 {
+		b.sources[link] = state
+	} else {
+		delete(b.sources, link)
+	}
+}
+
+type gzipReadCloser struct {
+	*gzip.Reader
+	source io.Closer
+}
+
+func (g *gzipReadCloser) Close() error {
+	_ = g.Reader.Close()
+
+	return g.source.Close()
+}
+
+type bufferedReadCloser struct {
+	*bufio.Reader
+	io.Closer
+}
+
+// decompress returns a reader for the uncompressed content of gzip compressed sources (".gz" files or
+// responses with "Content-Encoding: gzip"), other sources are returned unchanged
+func decompress(r io.ReadCloser) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+
+	magic, err := br.Peek(2)
+	if err != nil || magic[0] != 0x1f || magic[1] != 0x8b {
+		return &bufferedReadCloser{Reader: br, Closer: r}, nil
+	}
+
+	gz, err := gzip.NewReader(br)
+	if err != nil {
+		r.Close()
+
+		return nil, err
+	}
+
+	return &gzipReadCloser{Reader: gz, source: r}, nil
+}
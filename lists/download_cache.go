@@ -0,0 +1,117 @@
+package lists
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// downloadCache stores copies of successfully downloaded lists in a directory. The copies are used
+// as fallback, if a download fails (for example if the list server is down while blocky starts)
+type downloadCache struct {
+	dir string
+}
+
+// fileName returns the path of the copy for the link
+func (d *downloadCache) fileName(link string) string {
+	return filepath.Join(d.dir, fmt.Sprintf("%x.txt", sha256.Sum256([]byte(link))))
+}
+
+// createTemp creates the file for the next copy in the directory of the cache
+func (d *downloadCache) createTemp() (*os.File, error) {
+	return ioutil.TempFile(d.dir, ".download-*")
+}
+
+// store writes the content to the temporary file, which atomically replaces the copy of the link afterwards.
+// Returns the copy for reading from the start, the temporary file is removed on error
+func (d *downloadCache) store(tmp *os.File, link string, content io.Reader) (*os.File, error) {
+	_, err := io.Copy(tmp, content)
+
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), d.fileName(link))
+	}
+
+	if err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+
+		return nil, err
+	}
+
+	return tmp, nil
+}
+
+// load opens the copy of the link and returns the time of the download
+func (d *downloadCache) load(link string) (io.ReadCloser, time.Time, error) {
+	f, err := os.Open(d.fileName(link))
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, time.Time{}, err
+	}
+
+	return f, fi.ModTime(), nil
+}
+
+// fallbackAgeCollector exports the age of fallback copies, which are currently in use
+type fallbackAgeCollector struct {
+	cache *ListCache
+	desc  *prometheus.Desc
+}
+
+func newFallbackAgeCollector(t ListCacheType, cache *ListCache) *fallbackAgeCollector {
+	return &fallbackAgeCollector{
+		cache: cache,
+		desc: prometheus.NewDesc(fmt.Sprintf("blocky_%s_fallback_age_seconds", t),
+			"Age of the stored list copy, which is used because the download failed", []string{"link"}, nil),
+	}
+}
+
+func (c *fallbackAgeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *fallbackAgeCollector) Collect(ch chan<- prometheus.Metric) {
+	for link, downloaded := range c.cache.fallbacks() {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, time.Since(downloaded).Seconds(), link)
+	}
+}
+
+// setFallback remembers the download time of the used stored copy, zero time means download was successful
+func (b *ListCache) setFallback(link string, downloaded time.Time) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	if downloaded.IsZero() {
+		delete(b.fallbackTimes, link)
+	} else {
+		b.fallbackTimes[link] = downloaded
+	}
+}
+
+// fallbacks returns the download time of stored copies, which are currently used instead of the link
+func (b *ListCache) fallbacks() map[string]time.Time {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	result := make(map[string]time.Time, len(b.fallbackTimes))
+	for link, downloaded := range b.fallbackTimes {
+		result[link] = downloaded
+	}
+
+	return result
+}
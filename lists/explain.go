@@ -0,0 +1,51 @@
+package lists
+
+// MatchResult is the result of matching a domain against the entries of one group
+type MatchResult struct {
+	Group string
+	Found bool
+	// matching entry, for example "*.example.com" or "/^ad[0-9]+\./"
+	Entry string
+	// link of the list source and line number of the entry
+	Source string
+	Line   int
+}
+
+// Explain matches the domain against each group and returns the result of every group, also of groups without match
+func (b *ListCache) Explain(domain string, groupsToCheck []string) []MatchResult {
+	return b.explain(domain, groupsToCheck, false)
+}
+
+// ExplainExceptions matches the domain against the exception rules of each group, see Explain
+func (b *ListCache) ExplainExceptions(domain string, groupsToCheck []string) []MatchResult {
+	return b.explain(domain, groupsToCheck, true)
+}
+
+func (b *ListCache) explain(domain string, groupsToCheck []string, exceptions bool) []MatchResult {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	result := make([]MatchResult, 0, len(groupsToCheck))
+
+	for _, g := range groupsToCheck {
+		res := MatchResult{Group: g}
+
+		c := b.groupCaches[g]
+		if c != nil && exceptions {
+			c = c.exceptions
+		}
+
+		if c != nil {
+			res.Entry, res.Source, res.Line = c.lookup(domain, b.matchMode)
+			res.Found = len(res.Entry) > 0
+
+			if res.Found && exceptions {
+				res.Entry = "@@" + res.Entry
+			}
+		}
+
+		result = append(result, res)
+	}
+
+	return result
+}
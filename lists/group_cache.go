@@ -0,0 +1,272 @@
+package lists
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const wildcardPrefix = "*."
+
+type MatchMode int
+
+const (
+	// MatchExact matches only entries which are equal to the domain (default)
+	MatchExact MatchMode = iota
+	// MatchSubdomains matches entries which are equal to the domain or to one of its parent domains
+	MatchSubdomains
+)
+
+func (m MatchMode) String() string {
+	names := [...]string{
+		"exact",
+		"subdomains"}
+
+	return names[m]
+}
+
+// ParseMatchMode converts the configuration value to MatchMode, empty value means MatchExact
+func ParseMatchMode(mode string) (MatchMode, error) {
+	switch strings.ToLower(strings.TrimSpace(mode)) {
+	case "", "exact":
+		return MatchExact, nil
+	case "subdomains":
+		return MatchSubdomains, nil
+	}
+
+	return MatchExact, fmt.Errorf("unknown match mode '%s', please use 'exact' or 'subdomains'", mode)
+}
+
+// entrySet contains all entries of one list source. Plain entries and wildcard entries ("*.example.com") are
+// stored as sorted blobs. Sub domain matching walks up the labels of the domain and performs a binary search for each
+// parent domain, so the lookup costs O(labels * log n) without additional memory. IP ranges ("10.0.0.0/8") are
+// stored in a prefix tree. Groups with the same list source share the entry set
+type entrySet struct {
+	// link of the list source
+	source string
+	// sorted plain entries (domain names and IP addresses)
+	domains sortedBlob
+	// sorted wildcard entries without the "*." prefix, they match only sub domains
+	wildcards sortedBlob
+	// compiled regular expressions
+	regexes []*regexp.Regexp
+	// IP ranges in CIDR notation, they match IP addresses inside the range
+	cidrs cidrTree
+	// line numbers of the entries with the same index
+	domainLines   []int32
+	wildcardLines []int32
+	regexLines    []int32
+}
+
+// count returns the number of entries
+func (s *entrySet) count() int {
+	return s.domains.len() + s.wildcards.len() + len(s.regexes) + s.cidrs.count
+}
+
+// regexLine is a regular expression without the enclosing slashes and its line number
+type regexLine struct {
+	pattern string
+	line    int
+}
+
+// cidrLine is an IP range and its line number
+type cidrLine struct {
+	ipNet *net.IPNet
+	line  int
+}
+
+// entriesBuilder collects the entries of one list source while parsing
+type entriesBuilder struct {
+	domains   blobBuilder
+	wildcards blobBuilder
+	regexes   []regexLine
+	cidrs     []cidrLine
+}
+
+func (b *entriesBuilder) add(entry string, line int) {
+	if pattern, ok := regexPattern(entry); ok {
+		b.regexes = append(b.regexes, regexLine{pattern: pattern, line: line})
+
+		return
+	}
+
+	entry = processLine(entry)
+
+	if strings.Contains(entry, "/") {
+		if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+			b.cidrs = append(b.cidrs, cidrLine{ipNet: ipNet, line: line})
+
+			return
+		}
+	}
+
+	if strings.HasPrefix(entry, wildcardPrefix) {
+		b.wildcards.add(strings.TrimPrefix(entry, wildcardPrefix), line)
+	} else if len(entry) > 0 {
+		b.domains.add(entry, line)
+	}
+}
+
+func (b *entriesBuilder) append(other *entriesBuilder) {
+	b.domains.append(&other.domains)
+	b.wildcards.append(&other.wildcards)
+	b.regexes = append(b.regexes, other.regexes...)
+	b.cidrs = append(b.cidrs, other.cidrs...)
+}
+
+func (b *entriesBuilder) empty() bool {
+	return len(b.domains.offsets) == 0 && len(b.wildcards.offsets) == 0 && len(b.regexes) == 0 && len(b.cidrs) == 0
+}
+
+// build creates the entry set with distinct entries, the line number of the first occurrence is kept.
+// Invalid regular expressions are logged and skipped. The builder must not be used afterwards
+func (b *entriesBuilder) build(source string) *entrySet {
+	s := &entrySet{source: source}
+
+	s.domains, s.domainLines = b.domains.build()
+	s.wildcards, s.wildcardLines = b.wildcards.build()
+
+	compiled := make(map[string]bool, len(b.regexes))
+
+	for _, entry := range b.regexes {
+		if compiled[entry.pattern] {
+			continue
+		}
+
+		compiled[entry.pattern] = true
+
+		re, err := regexp.Compile(entry.pattern)
+		if err != nil {
+			logger().WithFields(logrus.Fields{
+				"source": source,
+				"line":   entry.line,
+			}).Errorf("invalid regular expression '/%s/', entry will be ignored: %v", entry.pattern, err)
+
+			continue
+		}
+
+		s.regexes = append(s.regexes, re)
+		s.regexLines = append(s.regexLines, int32(entry.line))
+	}
+
+	for _, entry := range b.cidrs {
+		s.cidrs.add(entry.ipNet, entry.line)
+	}
+
+	b.regexes = nil
+	b.cidrs = nil
+
+	return s
+}
+
+// groupCache contains the entry sets of all list sources of one group.
+// Regular expressions are evaluated only if the fast lookup found nothing
+type groupCache struct {
+	sets []*entrySet
+	// number of distinct entries of all sets
+	entries int
+	// exception rules of AdBlock formatted black lists, nil if the group has no exceptions
+	exceptions *groupCache
+}
+
+func newGroupCache(sets []*entrySet) *groupCache {
+	domains := make([]*sortedBlob, 0, len(sets))
+	wildcards := make([]*sortedBlob, 0, len(sets))
+	regexes := make(map[string]bool)
+	cidrs := make(map[string]bool)
+
+	for _, s := range sets {
+		domains = append(domains, &s.domains)
+		wildcards = append(wildcards, &s.wildcards)
+
+		for _, re := range s.regexes {
+			regexes[re.String()] = true
+		}
+
+		for _, prefix := range s.cidrs.prefixes() {
+			cidrs[prefix] = true
+		}
+	}
+
+	return &groupCache{
+		sets:    sets,
+		entries: countDistinct(domains) + countDistinct(wildcards) + len(regexes) + len(cidrs),
+	}
+}
+
+// count returns the number of distinct entries
+func (c *groupCache) count() int {
+	return c.entries
+}
+
+// match returns the entry which matches the domain or empty string
+func (c *groupCache) match(domain string, mode MatchMode) string {
+	entry, _, _ := c.lookup(domain, mode)
+
+	return entry
+}
+
+// lookup returns the entry which matches the domain with the link and line number of its list source
+func (c *groupCache) lookup(domain string, mode MatchMode) (entry string, source string, line int) {
+	domain = strings.ToLower(domain)
+
+	for _, s := range c.sets {
+		if idx, found := s.domains.search(domain); found {
+			return domain, s.source, int(s.domainLines[idx])
+		}
+	}
+
+	// IP addresses have no parent domains, but can be inside of IP ranges. The longest range of all lists wins,
+	// for ranges of the same length the first list
+	if ip := net.ParseIP(domain); ip != nil {
+		longest := -1
+
+		for _, s := range c.sets {
+			if prefix, ones, rangeLine := s.cidrs.lookup(ip); len(prefix) > 0 && ones > longest {
+				entry, source, line, longest = prefix, s.source, rangeLine, ones
+			}
+		}
+
+		if longest >= 0 {
+			return entry, source, line
+		}
+	} else {
+		for parent := parentDomain(domain); len(parent) > 0; parent = parentDomain(parent) {
+			if mode == MatchSubdomains {
+				for _, s := range c.sets {
+					if idx, found := s.domains.search(parent); found {
+						return parent, s.source, int(s.domainLines[idx])
+					}
+				}
+			}
+
+			for _, s := range c.sets {
+				if idx, found := s.wildcards.search(parent); found {
+					return wildcardPrefix + parent, s.source, int(s.wildcardLines[idx])
+				}
+			}
+		}
+	}
+
+	for _, s := range c.sets {
+		for i, re := range s.regexes {
+			if re.MatchString(domain) {
+				return "/" + re.String() + "/", s.source, int(s.regexLines[i])
+			}
+		}
+	}
+
+	return "", "", 0
+}
+
+// parentDomain returns the domain without the first label or empty string for top level domains
+func parentDomain(domain string) string {
+	if i := strings.Index(domain, "."); i >= 0 {
+		return domain[i+1:]
+	}
+
+	return ""
+}
@@ -0,0 +1,105 @@
+package lists
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("GroupCache", func() {
+	var sut *groupCache
+
+	BeforeEach(func() {
+		list1 := &entriesBuilder{}
+		list1.add("doubleclick.net", 1)
+		list1.add("*.example.com", 2)
+		list1.add("ads.sub.domain.org", 3)
+		list1.add("172.16.0.0/12", 4)
+
+		list2 := &entriesBuilder{}
+		list2.add("192.168.178.55", 1)
+		list2.add("", 2)
+		list2.add("doubleclick.net", 3)
+		list2.add(`/^ad[0-9]+\./`, 4)
+		list2.add("10.0.0.0/8", 5)
+		list2.add("0.0.0.0 10.1.0.0/16", 6)
+		list2.add("2001:db8::/32", 7)
+		list2.add("10.0.0.0/8", 8)
+		list2.add("172.16.5.0/24", 9)
+
+		sut = newGroupCache([]*entrySet{list1.build("list1"), list2.build("list2")})
+	})
+
+	It("should count distinct entries of all lists", func() {
+		Expect(sut.count()).Should(Equal(10))
+	})
+
+	DescribeTable("matching in exact mode",
+		func(domain, expectedEntry string) {
+			Expect(sut.match(domain, MatchExact)).Should(Equal(expectedEntry))
+		},
+		Entry("exact entry", "doubleclick.net", "doubleclick.net"),
+		Entry("exact entry with upper case", "DoubleClick.net", "doubleclick.net"),
+		Entry("sub domain of exact entry", "ad.doubleclick.net", ""),
+		Entry("sub domain of wildcard entry", "www.example.com", "*.example.com"),
+		Entry("deep sub domain of wildcard entry", "a.b.example.com", "*.example.com"),
+		Entry("wildcard entry itself", "example.com", ""),
+		Entry("IP address", "192.168.178.55", "192.168.178.55"),
+		Entry("unknown domain", "google.com", ""),
+		Entry("regular expression", "ad123.google.com", `/^ad[0-9]+\./`),
+		Entry("regular expression without match", "adx.google.com", ""),
+		Entry("IP address inside of a range", "10.2.3.4", "10.0.0.0/8"),
+		Entry("IP address inside of a nested range", "10.1.3.4", "10.1.0.0/16"),
+		Entry("IP address outside of a range", "11.0.0.1", ""),
+		Entry("IPv6 address inside of a range", "2001:db8:1::1", "2001:db8::/32"),
+		Entry("IPv6 address outside of a range", "2001:db9::1", ""),
+	)
+
+	DescribeTable("matching in sub domain mode",
+		func(domain, expectedEntry string) {
+			Expect(sut.match(domain, MatchSubdomains)).Should(Equal(expectedEntry))
+		},
+		Entry("exact entry", "doubleclick.net", "doubleclick.net"),
+		Entry("sub domain of entry", "ad.doubleclick.net", "doubleclick.net"),
+		Entry("deep sub domain of entry", "x.y.ads.sub.domain.org", "ads.sub.domain.org"),
+		Entry("parent domain of entry", "sub.domain.org", ""),
+		Entry("sub domain of wildcard entry", "www.example.com", "*.example.com"),
+		Entry("wildcard entry itself", "example.com", ""),
+		Entry("IP address in a different range", "1.168.178.55", ""),
+		Entry("domain with same suffix but different label", "notdoubleclick.net", ""),
+	)
+
+	DescribeTable("origin of the matching entry",
+		func(domain, expectedEntry, expectedSource string, expectedLine int) {
+			entry, source, line := sut.lookup(domain, MatchSubdomains)
+			Expect(entry).Should(Equal(expectedEntry))
+			Expect(source).Should(Equal(expectedSource))
+			Expect(line).Should(Equal(expectedLine))
+		},
+		Entry("first occurrence of duplicate entry", "doubleclick.net", "doubleclick.net", "list1", 1),
+		Entry("parent domain entry", "ad.doubleclick.net", "doubleclick.net", "list1", 1),
+		Entry("wildcard entry", "www.example.com", "*.example.com", "list1", 2),
+		Entry("IP address", "192.168.178.55", "192.168.178.55", "list2", 1),
+		Entry("regular expression", "ad1.google.com", `/^ad[0-9]+\./`, "list2", 4),
+		Entry("first occurrence of duplicate range", "10.2.3.4", "10.0.0.0/8", "list2", 5),
+		Entry("range of the first list", "172.16.1.1", "172.16.0.0/12", "list1", 4),
+		Entry("longest range of all lists", "172.16.5.1", "172.16.5.0/24", "list2", 9),
+		Entry("no match", "google.com", "", "", 0),
+	)
+
+	DescribeTable("parsing of match mode",
+		func(in string, expected MatchMode, wantErr bool) {
+			mode, err := ParseMatchMode(in)
+			if wantErr {
+				Expect(err).Should(HaveOccurred())
+			} else {
+				Expect(err).Should(Succeed())
+				Expect(mode).Should(Equal(expected))
+			}
+		},
+		Entry("empty", "", MatchExact, false),
+		Entry("exact", "exact", MatchExact, false),
+		Entry("subdomains", "Subdomains", MatchSubdomains, false),
+		Entry("unknown", "prefix", MatchExact, true),
+	)
+})
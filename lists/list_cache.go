@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"net"
-	"net/http"
 	"os"
 	"sort"
 	"strings"
@@ -42,22 +41,53 @@ func (l ListCacheType) String() string {
 	return names[l]
 }
 
+// ParseListCacheType converts "blacklist" or "whitelist" to ListCacheType
+func ParseListCacheType(t string) (ListCacheType, error) {
+	switch strings.ToLower(t) {
+	case BLACKLIST.String():
+		return BLACKLIST, nil
+	case WHITELIST.String():
+		return WHITELIST, nil
+	}
+
+	return BLACKLIST, fmt.Errorf("unknown list type '%s', please use '%s' or '%s'", t, BLACKLIST, WHITELIST)
+}
+
 type Matcher interface {
-	// matches passed domain name against cached list entries
-	Match(domain string, groupsToCheck []string) (found bool, group string)
+	// matches passed domain name against cached list entries, returns the first matching group and entry
+	Match(domain string, groupsToCheck []string) (found bool, group string, entry string)
 
 	// returns current configuration and stats
 	Configuration() []string
 }
 
 type ListCache struct {
-	groupCaches map[string][]string
+	groupCaches map[string]*groupCache
 	lock        sync.RWMutex
+	// serializes periodical and on-demand refreshes
+	refreshLock sync.Mutex
 
+	listType      ListCacheType
 	groupToLinks  map[string][]string
 	refreshPeriod time.Duration
+	matchMode     MatchMode
+
+	// nil, if downloaded lists should not be stored
+	downloadCache *downloadCache
+	// download time of stored copies, which are used because the download failed
+	fallbackTimes map[string]time.Time
+	// validators of the last successful download per link
+	sources map[string]*sourceState
+	// entries per link, groups with the same link share the entries
+	sourceCaches map[string]*sourceCache
+	// entries per group, which were added at runtime
+	overlaySets map[string]*entrySet
+	// result of the last processing per group and link
+	sourceStatuses map[sourceKey]SourceStatus
 
 	counter *prometheus.GaugeVec
+
+	stop chan struct{}
 }
 
 func (b *ListCache) Configuration() (result []string) {
@@ -67,6 +97,24 @@ func (b *ListCache) Configuration() (result []string) {
 		result = append(result, "refresh: disabled")
 	}
 
+	if b.downloadCache != nil {
+		result = append(result, fmt.Sprintf("download cache dir: %s", b.downloadCache.dir))
+
+		fallbacks := b.fallbacks()
+		links := make([]string, 0, len(fallbacks))
+
+		for link := range fallbacks {
+			links = append(links, link)
+		}
+
+		sort.Strings(links)
+
+		for _, link := range links {
+			result = append(result, fmt.Sprintf("fallback copy in use: %s (age %s)", link,
+				time.Since(fallbacks[link]).Round(time.Second)))
+		}
+	}
+
 	result = append(result, "group links:")
 	for group, links := range b.groupToLinks {
 		result = append(result, fmt.Sprintf("  %s:", group))
@@ -80,8 +128,12 @@ func (b *ListCache) Configuration() (result []string) {
 	var total int
 
 	for group, cache := range b.groupCaches {
-		result = append(result, fmt.Sprintf("  %s: %d entries", group, len(cache)))
-		total += len(cache)
+		result = append(result, fmt.Sprintf("  %s: %d entries", group, cache.count()))
+		total += cache.count()
+
+		if cache.exceptions != nil {
+			result = append(result, fmt.Sprintf("  %s: %d exceptions", group, cache.exceptions.count()))
+		}
 	}
 
 	result = append(result, fmt.Sprintf("  TOTAL: %d entries", total))
@@ -89,8 +141,9 @@ func (b *ListCache) Configuration() (result []string) {
 	return
 }
 
-func NewListCache(t ListCacheType, groupToLinks map[string][]string, refreshPeriod int) *ListCache {
-	groupCaches := make(map[string][]string)
+func NewListCache(t ListCacheType, groupToLinks map[string][]string, refreshPeriod int,
+	matchMode MatchMode, downloadCacheDir string) *ListCache {
+	groupCaches := make(map[string]*groupCache)
 
 	p := time.Duration(refreshPeriod) * time.Minute
 	if refreshPeriod == 0 {
@@ -111,11 +164,37 @@ func NewListCache(t ListCacheType, groupToLinks map[string][]string, refreshPeri
 	}
 
 	b := &ListCache{
-		groupToLinks:  groupToLinks,
-		groupCaches:   groupCaches,
-		refreshPeriod: p,
-		counter:       counter,
+		listType:       t,
+		groupToLinks:   groupToLinks,
+		groupCaches:    groupCaches,
+		refreshPeriod:  p,
+		matchMode:      matchMode,
+		counter:        counter,
+		fallbackTimes:  make(map[string]time.Time),
+		sources:        make(map[string]*sourceState),
+		sourceCaches:   make(map[string]*sourceCache),
+		overlaySets:    make(map[string]*entrySet),
+		sourceStatuses: make(map[sourceKey]SourceStatus),
+		stop:           make(chan struct{}),
+	}
+
+	if len(downloadCacheDir) > 0 {
+		if err := os.MkdirAll(downloadCacheDir, 0750); err != nil {
+			logger().Errorf("can't create download cache dir '%s', downloaded lists will not be stored: %v",
+				downloadCacheDir, err)
+		} else {
+			b.downloadCache = &downloadCache{dir: downloadCacheDir}
+
+			if metrics.IsEnabled() {
+				metrics.RegisterMetric(newFallbackAgeCollector(t, b))
+			}
+		}
 	}
+
+	if metrics.IsEnabled() {
+		metrics.RegisterMetric(newSourceStatusCollector(t, b))
+	}
+
 	b.refresh()
 
 	go periodicUpdate(b)
@@ -130,139 +209,269 @@ func periodicUpdate(cache *ListCache) {
 		defer ticker.Stop()
 
 		for {
-			<-ticker.C
-			cache.refresh()
+			select {
+			case <-ticker.C:
+				cache.refresh()
+			case <-cache.stop:
+				return
+			}
 		}
 	}
 }
 
+// MatchMode returns the configured match mode
+func (b *ListCache) MatchMode() MatchMode {
+	return b.matchMode
+}
+
+// Stop terminates the periodical refresh, already cached entries can still be matched
+func (b *ListCache) Stop() {
+	close(b.stop)
+}
+
 func logger() *logrus.Entry {
 	return log.Logger.WithField("prefix", "list_cache")
 }
 
-// downloads and reads files with domain names and creates cache for them
-func createCacheForGroup(links []string) []string {
-	cache := make([]string, 0)
-
-	keys := make(map[string]bool)
+// fileContent contains all entries of one list source
+type fileContent struct {
+	rules entriesBuilder
+	// exception rules ("@@||example.com^") of AdBlock formatted sources
+	exceptions entriesBuilder
+	// number of processed lines
+	count int
+}
 
-	var wg sync.WaitGroup
+// sourceCache contains the entries of one list source. Exception rules are stored separately for black lists,
+// for white lists they are regular entries
+type sourceCache struct {
+	rules *entrySet
+	// nil, if the source has no exception rules
+	exceptions *entrySet
+}
 
-	c := make(chan []string, len(links))
+// sourceResult is the result of the processing of one list source
+type sourceResult struct {
+	// true, if the server returned 304 and the entries of the previous download are used
+	notModified bool
+	// true, if a temporary error occurred and the entries of the previous download are used
+	failed bool
+	// result of the processing, which is stored for each group of the link
+	status SourceStatus
+}
 
-	for _, link := range links {
-		wg.Add(1)
+func (b *ListCache) Match(domain string, groupsToCheck []string) (found bool, group string, entry string) {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
 
-		go processFile(link, c, &wg)
+	for _, g := range groupsToCheck {
+		if c, ok := b.groupCaches[g]; ok {
+			if entry := c.match(domain, b.matchMode); len(entry) > 0 {
+				return true, g, entry
+			}
+		}
 	}
 
-	wg.Wait()
+	return false, "", ""
+}
+
+// Exceptions returns a matcher for the exception rules ("@@||example.com^") of AdBlock formatted black lists.
+// Matching entries are returned with the "@@" prefix
+func (b *ListCache) Exceptions() Matcher {
+	return &exceptionMatcher{cache: b}
+}
+
+type exceptionMatcher struct {
+	cache *ListCache
+}
+
+func (m *exceptionMatcher) Match(domain string, groupsToCheck []string) (found bool, group string, entry string) {
+	m.cache.lock.RLock()
+	defer m.cache.lock.RUnlock()
 
-Loop:
-	for {
-		select {
-		case res := <-c:
-			if res == nil {
-				return nil
+	for _, g := range groupsToCheck {
+		if c, ok := m.cache.groupCaches[g]; ok && c.exceptions != nil {
+			if entry := c.exceptions.match(domain, m.cache.matchMode); len(entry) > 0 {
+				return true, g, "@@" + entry
 			}
-			for _, entry := range res {
-				if _, value := keys[entry]; !value {
-					keys[entry] = true
-					cache = append(cache, entry)
-				}
+		}
+	}
+
+	return false, "", ""
+}
+
+func (m *exceptionMatcher) Configuration() []string {
+	return m.cache.Configuration()
+}
+
+// refresh downloads (or reads) each distinct list once and replaces the caches of all groups
+func (b *ListCache) refresh() {
+	b.refreshLock.Lock()
+	defer b.refreshLock.Unlock()
+
+	distinct := make(map[string]bool)
+
+	var links []string
+
+	for _, group := range b.Groups() {
+		for _, link := range b.groupToLinks[group] {
+			if !distinct[link] {
+				distinct[link] = true
+				links = append(links, link)
 			}
-		default:
-			close(c)
-			break Loop
 		}
 	}
 
-	sort.Strings(cache)
+	results := b.refreshSources(links)
 
-	return cache
+	for _, group := range b.Groups() {
+		b.updateGroup(group, results)
+	}
 }
 
-func (b *ListCache) Match(domain string, groupsToCheck []string) (found bool, group string) {
-	b.lock.RLock()
-	defer b.lock.RUnlock()
+// createGroupCache creates the cache with the overlay entries and the current entries of all lists of the group.
+// The caller must hold the lock
+func (b *ListCache) createGroupCache(group string) *groupCache {
+	var rules, exceptions []*entrySet
 
-	for _, g := range groupsToCheck {
-		if contains(domain, b.groupCaches[g]) {
-			return true, g
+	if s, ok := b.overlaySets[group]; ok {
+		rules = append(rules, s)
+	}
+
+	for _, link := range b.groupToLinks[group] {
+		if c, ok := b.sourceCaches[link]; ok {
+			rules = append(rules, c.rules)
+
+			if c.exceptions != nil {
+				exceptions = append(exceptions, c.exceptions)
+			}
 		}
 	}
 
-	return false, ""
+	cache := newGroupCache(rules)
+	if len(exceptions) > 0 {
+		cache.exceptions = newGroupCache(exceptions)
+	}
+
+	return cache
 }
 
-func contains(domain string, cache []string) bool {
-	idx := sort.SearchStrings(cache, domain)
-	if idx < len(cache) {
-		return cache[idx] == strings.ToLower(domain)
+// Groups returns the sorted names of all configured groups
+func (b *ListCache) Groups() []string {
+	groups := make([]string, 0, len(b.groupToLinks))
+	for group := range b.groupToLinks {
+		groups = append(groups, group)
 	}
 
-	return false
+	sort.Strings(groups)
+
+	return groups
 }
 
-func (b *ListCache) refresh() {
-	for group, links := range b.groupToLinks {
-		cacheForGroup := createCacheForGroup(links)
+// RefreshGroup downloads (or reads) all lists of the group and replaces the group cache. Caches of other groups,
+// which use one of the lists, are replaced too. Refreshes of the same list cache are executed sequentially
+func (b *ListCache) RefreshGroup(group string) error {
+	links, found := b.groupToLinks[group]
+	if !found {
+		return fmt.Errorf("group '%s' is not defined in %s", group, b.listType)
+	}
 
-		if cacheForGroup != nil {
-			b.lock.Lock()
-			b.groupCaches[group] = cacheForGroup
-			b.lock.Unlock()
-		} else {
-			logger().Warn("Populating of group cache failed, leaving items from last successful download in cache")
-		}
+	b.refreshLock.Lock()
+	defer b.refreshLock.Unlock()
 
-		if metrics.IsEnabled() {
-			b.counter.WithLabelValues(group).Set(float64(len(b.groupCaches[group])))
-		}
+	results := b.refreshSources(links)
+
+	for _, g := range b.Groups() {
+		for _, link := range b.groupToLinks[g] {
+			if _, refreshed := results[link]; refreshed {
+				b.updateGroup(g, results)
 
-		logger().WithFields(logrus.Fields{
-			"group":       group,
-			"total_count": len(b.groupCaches[group]),
-		}).Info("group import finished")
+				break
+			}
+		}
 	}
+
+	return nil
 }
 
-func downloadFile(link string) (io.ReadCloser, error) {
-	client := http.Client{
-		Timeout: timeout,
+// refreshSources processes the links in parallel and returns the result per link
+func (b *ListCache) refreshSources(links []string) map[string]sourceResult {
+	type linkResult struct {
+		link   string
+		result sourceResult
 	}
 
-	var resp *http.Response
+	var wg sync.WaitGroup
+
+	ch := make(chan linkResult, len(links))
 
-	var err error
+	for _, link := range links {
+		wg.Add(1)
 
-	logger().WithField("link", link).Info("starting download")
+		go func(link string) {
+			defer wg.Done()
 
-	attempt := 1
+			ch <- linkResult{link: link, result: b.processFile(link)}
+		}(link)
+	}
 
-	for attempt <= 3 {
-		//nolint:bodyclose
-		if resp, err = client.Get(link); err == nil {
-			if resp.StatusCode == http.StatusOK {
-				return resp.Body, nil
-			}
+	wg.Wait()
+	close(ch)
 
-			resp.Body.Close()
+	results := make(map[string]sourceResult, len(links))
+	for r := range ch {
+		results[r.link] = r.result
+	}
 
-			return nil, fmt.Errorf("couldn't download url, got status code %d", resp.StatusCode)
+	return results
+}
+
+// updateGroup replaces the group cache with the current entries of its lists. The group cache is kept,
+// if no list was modified or if a list couldn't be refreshed due to a temporary error
+func (b *ListCache) updateGroup(group string, results map[string]sourceResult) {
+	links := b.groupToLinks[group]
+	modified := false
+	failed := false
+
+	for _, link := range links {
+		if r, refreshed := results[link]; refreshed {
+			modified = modified || !r.notModified
+			failed = failed || r.failed
 		}
+	}
 
-		if errNet, ok := err.(net.Error); ok && (errNet.Timeout() || errNet.Temporary()) {
-			logger().WithField("link", link).WithField("attempt",
-				attempt).Warnf("Temporary network error / Timeout occurred, retrying... %s", errNet)
-			time.Sleep(time.Second)
-			attempt++
-		} else {
-			return nil, err
+	b.lock.Lock()
+	_, exists := b.groupCaches[group]
+
+	switch {
+	case failed:
+		logger().Warn("Populating of group cache failed, leaving items from last successful download in cache")
+	case !modified && exists:
+		logger().WithField("group", group).Info("lists of group not modified, keeping group cache")
+	default:
+		b.groupCaches[group] = b.createGroupCache(group)
+	}
+
+	for _, link := range links {
+		if r, refreshed := results[link]; refreshed {
+			b.updateSourceStatus(group, r)
 		}
 	}
 
-	return nil, err
+	count := 0
+	if c, ok := b.groupCaches[group]; ok {
+		count = c.count()
+	}
+	b.lock.Unlock()
+
+	if metrics.IsEnabled() {
+		b.counter.WithLabelValues(group).Set(float64(count))
+	}
+
+	logger().WithFields(logrus.Fields{
+		"group":       group,
+		"total_count": count,
+	}).Info("group import finished")
 }
 
 func readFile(file string) (io.ReadCloser, error) {
@@ -272,59 +481,198 @@ func readFile(file string) (io.ReadCloser, error) {
 	return os.Open(file)
 }
 
-// downloads file (or reads local file) and writes file content as string array in the channel
-func processFile(link string, ch chan<- []string, wg *sync.WaitGroup) {
-	defer wg.Done()
+// downloads file (or reads local file) and stores the entries in the source cache
+func (b *ListCache) processFile(link string) (result sourceResult) {
+	var r io.ReadCloser
 
-	result := make([]string, 0)
+	var err, downloadErr error
 
-	var r io.ReadCloser
+	state := &sourceState{}
+	status := SourceStatus{Link: link, LastAttempt: time.Now()}
 
-	var err error
+	defer func() {
+		status.Duration = time.Since(status.LastAttempt)
+		result.status = status
+	}()
 
 	if strings.HasPrefix(link, "http") {
-		r, err = downloadFile(link)
+		var res *downloadResult
+
+		res, err = b.download(link)
+		if res != nil {
+			status.HTTPStatus = res.statusCode
+		}
+
+		if err == nil {
+			if res.notModified() {
+				if b.sourceCache(link) != nil {
+					logger().WithField("source", link).Info("list not modified, skip processing")
+
+					status.LastSuccess = status.LastAttempt
+					status.LastError = ""
+
+					return sourceResult{notModified: true}
+				}
+
+				err = fmt.Errorf("got 'not modified' response without previous download")
+			}
+
+			r, state.etag, state.lastModified, downloadErr = res.body, res.etag, res.lastModified, res.downloadErr
+		}
 	} else {
 		r, err = readFile(link)
 	}
 
+	counter := &countingReader{ReadCloser: r}
+
+	if err == nil {
+		r, err = decompress(counter)
+	}
+
 	if err != nil {
 		logger().Warn("error reading "+link+": ", err)
 
+		status.LastError = err.Error()
+
 		if errNet, ok := err.(net.Error); ok && (errNet.Timeout() || errNet.Temporary()) {
-			// put nil to indicate the temporary error
-			ch <- nil
-			return
+			return sourceResult{failed: true}
 		}
-		ch <- []string{}
 
-		return
+		status.Entries = 0
+		b.setSourceCache(link, &sourceCache{rules: &entrySet{source: link}})
+
+		return sourceResult{}
 	}
 	defer r.Close()
 
-	var count int
+	content, err := parseContent(link, r)
+
+	status.Bytes = counter.count
+	status.Entries = content.count
+
+	switch {
+	case err != nil:
+		logger().Warn("can't parse file: ", err)
+
+		status.LastError = err.Error()
+	case downloadErr != nil:
+		status.LastError = downloadErr.Error()
+	default:
+		status.LastSuccess = time.Now()
+		status.LastError = ""
+	}
+
+	if strings.HasPrefix(link, "http") {
+		if len(status.LastError) > 0 {
+			// the next download must not be conditional, since the content is incomplete or outdated
+			state = &sourceState{}
+		}
+
+		b.setSourceState(link, state)
+	}
+
+	b.setSourceCache(link, b.createSourceCache(link, content))
+
+	return sourceResult{}
+}
+
+// createSourceCache builds the entry sets of the source, for white lists exception rules are regular entries
+func (b *ListCache) createSourceCache(link string, content *fileContent) *sourceCache {
+	c := &sourceCache{}
+
+	if b.listType == WHITELIST {
+		content.rules.append(&content.exceptions)
+	} else if !content.exceptions.empty() {
+		c.exceptions = content.exceptions.build(link)
+	}
+
+	c.rules = content.rules.build(link)
+
+	return c
+}
+
+// sourceCache returns the entries of the last processing of the link or nil
+func (b *ListCache) sourceCache(link string) *sourceCache {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	return b.sourceCaches[link]
+}
+
+func (b *ListCache) setSourceCache(link string, c *sourceCache) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.sourceCaches[link] = c
+}
+
+// reads the lines of the source and converts them to entries, the format of the source is detected automatically
+func parseContent(link string, r io.Reader) (*fileContent, error) {
+	result := &fileContent{}
+
+	var skipped, lineNumber int
+
+	format := formatUnknown
 
 	scanner := bufio.NewScanner(r)
 
 	for scanner.Scan() {
-		line := scanner.Text()
+		line := strings.TrimSpace(scanner.Text())
+		lineNumber++
+
+		if format == formatUnknown {
+			format = detectFormat(line)
+		}
+
 		// skip comments
-		if !strings.HasPrefix(line, "#") {
-			result = append(result, processLine(line))
+		if isComment(line, format) {
+			continue
+		}
+
+		if format == formatAdblock {
+			entries, exception, ok := parseAdblockRule(line)
+			if !ok {
+				skipped++
+				continue
+			}
+
+			target := &result.rules
+			if exception {
+				target = &result.exceptions
+			}
 
-			count++
+			for _, entry := range entries {
+				target.add(entry, lineNumber)
+			}
+		} else {
+			result.rules.add(line, lineNumber)
 		}
+
+		result.count++
 	}
 
 	if err := scanner.Err(); err != nil {
-		logger().Warn("can't parse file: ", err)
-	} else {
-		logger().WithFields(logrus.Fields{
-			"source": link,
-			"count":  count,
-		}).Info("file imported")
+		return result, err
 	}
-	ch <- result
+
+	logger().WithFields(logrus.Fields{
+		"source":  link,
+		"format":  format,
+		"count":   result.count,
+		"skipped": skipped,
+	}).Info("file imported")
+
+	return result, nil
+}
+
+// returns the pattern of a regular expression line ("/pattern/")
+func regexPattern(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if len(line) > 2 && strings.HasPrefix(line, "/") && strings.HasSuffix(line, "/") {
+		return line[1 : len(line)-1], true
+	}
+
+	return "", false
 }
 
 // return only first column (see hosts format)
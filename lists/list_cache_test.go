@@ -1,9 +1,14 @@
 package lists
 
 import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
 	"github.com/privacyherodev/ph-blocky/config"
 	. "github.com/privacyherodev/ph-blocky/helpertest"
+	"github.com/privacyherodev/ph-blocky/log"
 	"github.com/privacyherodev/ph-blocky/metrics"
+	"io/ioutil"
 	"net/http"
 	"sync/atomic"
 	"time"
@@ -16,6 +21,8 @@ import (
 
 	"github.com/go-chi/chi"
 	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 )
 
 var _ = Describe("ListCache", func() {
@@ -52,9 +59,9 @@ var _ = Describe("ListCache", func() {
 				lists := map[string][]string{
 					"gr1": {emptyFile.Name()},
 				}
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 
-				found, group := sut.Match("google.com", []string{"gr1"})
+				found, group, _ := sut.Match("google.com", []string{"gr1"})
 				Expect(found).Should(BeFalse())
 				Expect(group).Should(BeEmpty())
 			})
@@ -79,9 +86,9 @@ var _ = Describe("ListCache", func() {
 				}
 
 				timeout = 100 * time.Millisecond
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 				time.Sleep(time.Second)
-				found, group := sut.Match("blocked1.com", []string{"gr1"})
+				found, group, _ := sut.Match("blocked1.com", []string{"gr1"})
 				Expect(found).Should(BeTrue())
 				Expect(group).Should(Equal("gr1"))
 			})
@@ -106,10 +113,10 @@ var _ = Describe("ListCache", func() {
 				}
 
 				timeout = 100 * time.Millisecond
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 				time.Sleep(time.Second)
 				By("Lists loaded without timeout", func() {
-					found, group := sut.Match("blocked1.com", []string{"gr1"})
+					found, group, _ := sut.Match("blocked1.com", []string{"gr1"})
 					Expect(found).Should(BeTrue())
 					Expect(group).Should(Equal("gr1"))
 				})
@@ -117,7 +124,7 @@ var _ = Describe("ListCache", func() {
 				sut.refresh()
 
 				By("List couldn't be loaded due to timeout", func() {
-					found, group := sut.Match("blocked1.com", []string{"gr1"})
+					found, group, _ := sut.Match("blocked1.com", []string{"gr1"})
 					Expect(found).Should(BeTrue())
 					Expect(group).Should(Equal("gr1"))
 				})
@@ -142,10 +149,10 @@ var _ = Describe("ListCache", func() {
 					"gr1": {s.URL},
 				}
 
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 				time.Sleep(time.Second)
 				By("Lists loaded without error", func() {
-					found, group := sut.Match("blocked1.com", []string{"gr1"})
+					found, group, _ := sut.Match("blocked1.com", []string{"gr1"})
 					Expect(found).Should(BeTrue())
 					Expect(group).Should(Equal("gr1"))
 				})
@@ -154,7 +161,7 @@ var _ = Describe("ListCache", func() {
 				time.Sleep(time.Second)
 
 				By("List couldn't be loaded due to 404 error", func() {
-					found, _ := sut.Match("blocked1.com", []string{"gr1"})
+					found, _, _ := sut.Match("blocked1.com", []string{"gr1"})
 					Expect(found).Should(BeFalse())
 				})
 			})
@@ -166,17 +173,17 @@ var _ = Describe("ListCache", func() {
 					"gr2": {server3.URL},
 				}
 
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 
-				found, group := sut.Match("blocked1.com", []string{"gr1", "gr2"})
+				found, group, _ := sut.Match("blocked1.com", []string{"gr1", "gr2"})
 				Expect(found).Should(BeTrue())
 				Expect(group).Should(Equal("gr1"))
 
-				found, group = sut.Match("blocked1a.com", []string{"gr1", "gr2"})
+				found, group, _ = sut.Match("blocked1a.com", []string{"gr1", "gr2"})
 				Expect(found).Should(BeTrue())
 				Expect(group).Should(Equal("gr1"))
 
-				found, group = sut.Match("blocked1a.com", []string{"gr2"})
+				found, group, _ = sut.Match("blocked1a.com", []string{"gr2"})
 				Expect(found).Should(BeTrue())
 				Expect(group).Should(Equal("gr2"))
 			})
@@ -187,9 +194,9 @@ var _ = Describe("ListCache", func() {
 					"withDeadLink": {"http://wrong.host.name"},
 				}
 
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 
-				found, group := sut.Match("blocked1.com", []string{})
+				found, group, _ := sut.Match("blocked1.com", []string{})
 				Expect(found).Should(BeFalse())
 				Expect(group).Should(BeEmpty())
 			})
@@ -201,9 +208,9 @@ var _ = Describe("ListCache", func() {
 					"gr1": {server1.URL},
 				}
 
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 
-				found, group := sut.Match("blocked1.com", []string{})
+				found, group, _ := sut.Match("blocked1.com", []string{})
 				Expect(found).Should(BeFalse())
 				Expect(group).Should(BeEmpty())
 				Expect(testutil.ToFloat64(sut.counter)).Should(Equal(float64(3)))
@@ -216,22 +223,481 @@ var _ = Describe("ListCache", func() {
 					"gr2": {"file://" + file3.Name()},
 				}
 
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 
-				found, group := sut.Match("blocked1.com", []string{"gr1", "gr2"})
+				found, group, _ := sut.Match("blocked1.com", []string{"gr1", "gr2"})
 				Expect(found).Should(BeTrue())
 				Expect(group).Should(Equal("gr1"))
 
-				found, group = sut.Match("blocked1a.com", []string{"gr1", "gr2"})
+				found, group, _ = sut.Match("blocked1a.com", []string{"gr1", "gr2"})
 				Expect(found).Should(BeTrue())
 				Expect(group).Should(Equal("gr1"))
 
-				found, group = sut.Match("blocked1a.com", []string{"gr2"})
+				found, group, _ = sut.Match("blocked1a.com", []string{"gr2"})
 				Expect(found).Should(BeTrue())
 				Expect(group).Should(Equal("gr2"))
 			})
 		})
 	})
+	Describe("Lists used by several groups", func() {
+		It("should download the list once and share the entries", func() {
+			var downloads int32
+
+			sharedServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				atomic.AddInt32(&downloads, 1)
+				_, _ = rw.Write([]byte("shared.com"))
+			}))
+			defer sharedServer.Close()
+
+			lists := map[string][]string{
+				"gr1": {sharedServer.URL, file1.Name()},
+				"gr2": {sharedServer.URL},
+			}
+
+			sut := NewListCache(BLACKLIST, lists, -1, MatchExact, "")
+
+			Expect(atomic.LoadInt32(&downloads)).Should(Equal(int32(1)))
+			Expect(sut.groupCaches["gr1"].sets[0]).Should(BeIdenticalTo(sut.groupCaches["gr2"].sets[0]))
+			Expect(sut.groupCaches["gr1"].count()).Should(Equal(3))
+
+			found, group, _ := sut.Match("shared.com", []string{"gr2"})
+			Expect(found).Should(BeTrue())
+			Expect(group).Should(Equal("gr2"))
+
+			By("refreshing one group, all groups with the list get the new entries", func() {
+				Expect(sut.RefreshGroup("gr2")).Should(Succeed())
+
+				Expect(atomic.LoadInt32(&downloads)).Should(Equal(int32(2)))
+				Expect(sut.groupCaches["gr1"].sets[0]).Should(BeIdenticalTo(sut.groupCaches["gr2"].sets[0]))
+			})
+		})
+	})
+	Describe("Regular expressions", func() {
+		var (
+			regexFile *os.File
+			hook      *test.Hook
+		)
+		BeforeEach(func() {
+			regexFile = TempFile("# regex entries\n/^ad[0-9]+\\./\nblocked1.com\n/^(bad[/\n/^ad[0-9]+\\./")
+			hook = test.NewLocal(log.Logger)
+		})
+		AfterEach(func() {
+			_ = os.Remove(regexFile.Name())
+			hook.Reset()
+		})
+		It("should match domains against valid regular expressions and report invalid ones", func() {
+			lists := map[string][]string{
+				"gr1": {regexFile.Name()},
+			}
+
+			sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
+
+			found, group, entry := sut.Match("ad12.example.com", []string{"gr1"})
+			Expect(found).Should(BeTrue())
+			Expect(group).Should(Equal("gr1"))
+			Expect(entry).Should(Equal(`/^ad[0-9]+\./`))
+
+			found, _, _ = sut.Match("adx.example.com", []string{"gr1"})
+			Expect(found).Should(BeFalse())
+
+			found, _, entry = sut.Match("blocked1.com", []string{"gr1"})
+			Expect(found).Should(BeTrue())
+			Expect(entry).Should(Equal("blocked1.com"))
+
+			// duplicate expression is compiled only once
+			Expect(sut.groupCaches["gr1"].sets[0].regexes).Should(HaveLen(1))
+
+			var invalid *logrus.Entry
+			for _, e := range hook.AllEntries() {
+				if e.Level == logrus.ErrorLevel {
+					invalid = e
+				}
+			}
+			Expect(invalid).ShouldNot(BeNil())
+			Expect(invalid.Message).Should(ContainSubstring("invalid regular expression '/^(bad[/'"))
+			Expect(invalid.Data).Should(HaveKeyWithValue("source", regexFile.Name()))
+			Expect(invalid.Data).Should(HaveKeyWithValue("line", 4))
+		})
+	})
+	Describe("AdBlock format", func() {
+		var adblockFile *os.File
+		BeforeEach(func() {
+			adblockFile = TempFile(`[Adblock Plus 2.0]
+! Title: test list
+||ads.example.com^
+||tracker.org^$important
+@@||good.ads.example.com^
+example.com##.banner
+||example.com/ads/*
+`)
+		})
+		AfterEach(func() {
+			_ = os.Remove(adblockFile.Name())
+		})
+		When("source is used as black list", func() {
+			It("should block domains with sub domains and use exception rules separately", func() {
+				lists := map[string][]string{
+					"gr1": {adblockFile.Name()},
+				}
+
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
+
+				found, group, entry := sut.Match("ads.example.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+				Expect(group).Should(Equal("gr1"))
+				Expect(entry).Should(Equal("ads.example.com"))
+
+				found, _, entry = sut.Match("x.tracker.org", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+				Expect(entry).Should(Equal("*.tracker.org"))
+
+				found, _, _ = sut.Match("example.com", []string{"gr1"})
+				Expect(found).Should(BeFalse())
+
+				found, group, entry = sut.Exceptions().Match("good.ads.example.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+				Expect(group).Should(Equal("gr1"))
+				Expect(entry).Should(Equal("@@good.ads.example.com"))
+
+				found, _, _ = sut.Exceptions().Match("ads.example.com", []string{"gr1"})
+				Expect(found).Should(BeFalse())
+
+				Expect(sut.groupCaches["gr1"].count()).Should(Equal(4))
+				Expect(sut.Configuration()).Should(ContainElement("  gr1: 2 exceptions"))
+			})
+		})
+		When("match is explained", func() {
+			It("should return result of each group with source and line of the entry", func() {
+				lists := map[string][]string{
+					"gr1": {adblockFile.Name()},
+					"gr2": {file1.Name()},
+				}
+
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
+
+				Expect(sut.Explain("x.tracker.org", []string{"gr2", "gr1", "unknown"})).Should(Equal([]MatchResult{
+					{Group: "gr2"},
+					{Group: "gr1", Found: true, Entry: "*.tracker.org", Source: adblockFile.Name(), Line: 4},
+					{Group: "unknown"},
+				}))
+
+				Expect(sut.ExplainExceptions("good.ads.example.com", []string{"gr1", "gr2"})).Should(Equal([]MatchResult{
+					{Group: "gr1", Found: true, Entry: "@@good.ads.example.com", Source: adblockFile.Name(), Line: 5},
+					{Group: "gr2"},
+				}))
+			})
+		})
+		When("source is used as white list", func() {
+			It("should use rules and exception rules as entries", func() {
+				lists := map[string][]string{
+					"gr1": {adblockFile.Name()},
+				}
+
+				sut := NewListCache(WHITELIST, lists, 0, MatchExact, "")
+
+				found, _, _ := sut.Match("good.ads.example.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+
+				found, _, _ = sut.Match("ads.example.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+
+				found, _, _ = sut.Exceptions().Match("good.ads.example.com", []string{"gr1"})
+				Expect(found).Should(BeFalse())
+			})
+		})
+	})
+	Describe("Sub domain matching", func() {
+		When("match mode is 'subdomains'", func() {
+			It("should match sub domains of list entries and return the matched entry", func() {
+				lists := map[string][]string{
+					"gr1": {file1.Name()},
+				}
+
+				sut := NewListCache(BLACKLIST, lists, 0, MatchSubdomains, "")
+
+				found, group, entry := sut.Match("ads.blocked1.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+				Expect(group).Should(Equal("gr1"))
+				Expect(entry).Should(Equal("blocked1.com"))
+			})
+		})
+		When("match mode is 'exact'", func() {
+			It("should not match sub domains of list entries", func() {
+				lists := map[string][]string{
+					"gr1": {file1.Name()},
+				}
+
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
+
+				found, _, _ := sut.Match("ads.blocked1.com", []string{"gr1"})
+				Expect(found).Should(BeFalse())
+			})
+		})
+	})
+	Describe("Download cache", func() {
+		var cacheDir string
+		BeforeEach(func() {
+			var err error
+			cacheDir, err = ioutil.TempDir("", "download_cache")
+			Expect(err).Should(Succeed())
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(cacheDir)
+		})
+		When("download of a list fails", func() {
+			It("should use the stored copy of the last successful download", func() {
+				lists := map[string][]string{
+					"gr1": {server1.URL},
+				}
+
+				By("downloading the list successfully", func() {
+					sut := NewListCache(BLACKLIST, lists, -1, MatchExact, cacheDir)
+
+					found, _, _ := sut.Match("blocked1.com", []string{"gr1"})
+					Expect(found).Should(BeTrue())
+					Expect(sut.fallbacks()).Should(BeEmpty())
+
+					files, _ := ioutil.ReadDir(cacheDir)
+					Expect(files).Should(HaveLen(1))
+				})
+
+				By("using the stored copy, if the server is down", func() {
+					server1.Close()
+
+					sut := NewListCache(BLACKLIST, lists, -1, MatchExact, cacheDir)
+
+					found, group, _ := sut.Match("blocked1.com", []string{"gr1"})
+					Expect(found).Should(BeTrue())
+					Expect(group).Should(Equal("gr1"))
+					Expect(sut.fallbacks()).Should(HaveKey(server1.URL))
+					Expect(sut.Configuration()).Should(ContainElement(
+						HavePrefix(fmt.Sprintf("fallback copy in use: %s (age ", server1.URL))))
+					Expect(testutil.CollectAndCount(newFallbackAgeCollector(BLACKLIST, sut))).Should(Equal(1))
+				})
+			})
+		})
+		When("download breaks off", func() {
+			It("should keep and use the stored copy of the last complete download", func() {
+				var broken int32
+
+				listServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					if atomic.LoadInt32(&broken) == 1 {
+						// announce more content than sent, the connection is closed after the handler returns
+						rw.Header().Set("Content-Length", "1000")
+						_, _ = rw.Write([]byte("partial.com\n"))
+
+						return
+					}
+
+					_, _ = rw.Write([]byte("complete.com"))
+				}))
+				defer listServer.Close()
+
+				lists := map[string][]string{
+					"gr1": {listServer.URL},
+				}
+
+				NewListCache(BLACKLIST, lists, -1, MatchExact, cacheDir)
+
+				atomic.StoreInt32(&broken, 1)
+
+				sut := NewListCache(BLACKLIST, lists, -1, MatchExact, cacheDir)
+
+				found, _, _ := sut.Match("complete.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+				found, _, _ = sut.Match("partial.com", []string{"gr1"})
+				Expect(found).Should(BeFalse())
+				Expect(sut.fallbacks()).Should(HaveKey(listServer.URL))
+
+				files, _ := ioutil.ReadDir(cacheDir)
+				Expect(files).Should(HaveLen(1))
+			})
+		})
+		When("download fails and no stored copy exists", func() {
+			It("should not match anything", func() {
+				server1.Close()
+				lists := map[string][]string{
+					"gr1": {server1.URL},
+				}
+
+				sut := NewListCache(BLACKLIST, lists, -1, MatchExact, cacheDir)
+
+				found, _, _ := sut.Match("blocked1.com", []string{"gr1"})
+				Expect(found).Should(BeFalse())
+				Expect(sut.fallbacks()).Should(BeEmpty())
+			})
+		})
+	})
+	Describe("Conditional download", func() {
+		var (
+			conditionalServer  *httptest.Server
+			conditionalHeaders []http.Header
+		)
+		BeforeEach(func() {
+			conditionalHeaders = nil
+			conditionalServer = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+				conditionalHeaders = append(conditionalHeaders, req.Header.Clone())
+
+				if req.Header.Get("If-None-Match") == `"v1"` {
+					rw.WriteHeader(http.StatusNotModified)
+					return
+				}
+
+				rw.Header().Set("ETag", `"v1"`)
+				rw.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+				_, _ = rw.Write([]byte("conditional.com"))
+			}))
+		})
+		AfterEach(func() {
+			conditionalServer.Close()
+		})
+		When("list was not modified", func() {
+			It("should send conditional request and keep the group cache", func() {
+				lists := map[string][]string{
+					"gr1": {conditionalServer.URL},
+				}
+
+				sut := NewListCache(BLACKLIST, lists, -1, MatchExact, "")
+				groupCache := sut.groupCaches["gr1"]
+
+				sut.refresh()
+
+				Expect(conditionalHeaders).Should(HaveLen(2))
+				Expect(conditionalHeaders[0].Get("If-None-Match")).Should(BeEmpty())
+				Expect(conditionalHeaders[1].Get("If-None-Match")).Should(Equal(`"v1"`))
+				Expect(conditionalHeaders[1].Get("If-Modified-Since")).Should(Equal("Wed, 21 Oct 2015 07:28:00 GMT"))
+
+				Expect(sut.groupCaches["gr1"]).Should(BeIdenticalTo(groupCache))
+
+				found, _, _ := sut.Match("conditional.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+			})
+		})
+		When("only one list of the group was modified", func() {
+			It("should use the content of the previous download for the not modified list", func() {
+				lists := map[string][]string{
+					"gr1": {conditionalServer.URL, server1.URL},
+				}
+
+				sut := NewListCache(BLACKLIST, lists, -1, MatchExact, "")
+				groupCache := sut.groupCaches["gr1"]
+
+				sut.refresh()
+
+				Expect(sut.groupCaches["gr1"]).ShouldNot(BeIdenticalTo(groupCache))
+
+				found, _, _ := sut.Match("conditional.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+				found, _, _ = sut.Match("blocked1.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+			})
+		})
+	})
+
+	Describe("Compressed lists", func() {
+		gzipData := func(data string) []byte {
+			var buf bytes.Buffer
+			w := gzip.NewWriter(&buf)
+			_, _ = w.Write([]byte(data))
+			_ = w.Close()
+
+			return buf.Bytes()
+		}
+		When("server responds with 'Content-Encoding: gzip'", func() {
+			It("should decompress the content", func() {
+				gzipServer := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+					rw.Header().Set("Content-Encoding", "gzip")
+					_, _ = rw.Write(gzipData("gzip.com"))
+				}))
+				defer gzipServer.Close()
+
+				sut := NewListCache(BLACKLIST, map[string][]string{"gr1": {gzipServer.URL}}, -1, MatchExact, "")
+
+				found, _, _ := sut.Match("gzip.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+			})
+		})
+		When("list is a .gz file", func() {
+			It("should decompress the content", func() {
+				gzFile, err := ioutil.TempFile("", "list*.gz")
+				Expect(err).Should(Succeed())
+				defer os.Remove(gzFile.Name())
+
+				_, err = gzFile.Write(gzipData("blocked.com\ngzip.com"))
+				Expect(err).Should(Succeed())
+				Expect(gzFile.Close()).Should(Succeed())
+
+				sut := NewListCache(BLACKLIST, map[string][]string{"gr1": {gzFile.Name()}}, -1, MatchExact, "")
+
+				found, _, _ := sut.Match("gzip.com", []string{"gr1"})
+				Expect(found).Should(BeTrue())
+				Expect(sut.groupCaches["gr1"].count()).Should(Equal(2))
+			})
+		})
+	})
+	Describe("Source status", func() {
+		It("should return the status of each source per group", func() {
+			notFoundServer := httptest.NewServer(http.NotFoundHandler())
+			defer notFoundServer.Close()
+
+			lists := map[string][]string{
+				"gr1": {server1.URL, notFoundServer.URL},
+				"gr2": {file2.Name()},
+			}
+
+			sut := NewListCache(BLACKLIST, lists, -1, MatchExact, "")
+
+			status := sut.Status()
+			Expect(status).Should(HaveLen(2))
+
+			Expect(status[0].Group).Should(Equal("gr1"))
+			Expect(status[0].Entries).Should(Equal(3))
+			Expect(status[0].Sources).Should(HaveLen(2))
+
+			ok := status[0].Sources[0]
+			Expect(ok.Link).Should(Equal(server1.URL))
+			Expect(ok.Entries).Should(Equal(3))
+			Expect(ok.HTTPStatus).Should(Equal(http.StatusOK))
+			Expect(ok.LastError).Should(BeEmpty())
+			Expect(ok.LastSuccess).ShouldNot(BeZero())
+			Expect(ok.LastAttempt).ShouldNot(BeZero())
+			Expect(ok.Bytes).Should(Equal(int64(len("blocked1.com\nblocked1a.com\n192.168.178.55"))))
+
+			failed := status[0].Sources[1]
+			Expect(failed.Link).Should(Equal(notFoundServer.URL))
+			Expect(failed.Entries).Should(BeZero())
+			Expect(failed.HTTPStatus).Should(Equal(http.StatusNotFound))
+			Expect(failed.LastError).Should(ContainSubstring("404"))
+			Expect(failed.LastSuccess).Should(BeZero())
+			Expect(failed.LastAttempt).ShouldNot(BeZero())
+
+			Expect(status[1].Group).Should(Equal("gr2"))
+			Expect(status[1].Sources[0].Link).Should(Equal(file2.Name()))
+			Expect(status[1].Sources[0].HTTPStatus).Should(BeZero())
+			Expect(status[1].Sources[0].Entries).Should(Equal(1))
+
+			// 6 metrics for each source
+			Expect(testutil.CollectAndCount(newSourceStatusCollector(BLACKLIST, sut))).Should(Equal(18))
+		})
+		It("should keep the status of a link for each group", func() {
+			lists := map[string][]string{
+				"gr1": {server1.URL},
+				"gr2": {server1.URL, file2.Name()},
+			}
+
+			sut := NewListCache(BLACKLIST, lists, -1, MatchExact, "")
+
+			Expect(sut.sourceStatuses).Should(HaveLen(3))
+			Expect(sut.sourceStatuses).Should(HaveKey(sourceKey{group: "gr1", link: server1.URL}))
+			Expect(sut.sourceStatuses).Should(HaveKey(sourceKey{group: "gr2", link: server1.URL}))
+
+			status := sut.Status()
+			Expect(status[0].Sources[0].Entries).Should(Equal(3))
+			Expect(status[1].Sources[0].Entries).Should(Equal(3))
+			Expect(status[1].Sources[1].Entries).Should(Equal(1))
+		})
+	})
+
 	Describe("Configuration", func() {
 		When("refresh is enabled", func() {
 			It("should print list configuration", func() {
@@ -239,7 +705,7 @@ var _ = Describe("ListCache", func() {
 					"gr1": {server1.URL, server2.URL},
 				}
 
-				sut := NewListCache(BLACKLIST, lists, 0)
+				sut := NewListCache(BLACKLIST, lists, 0, MatchExact, "")
 
 				c := sut.Configuration()
 				Expect(c).Should(HaveLen(8))
@@ -251,7 +717,7 @@ var _ = Describe("ListCache", func() {
 					"gr1": {"file1", "file2"},
 				}
 
-				sut := NewListCache(BLACKLIST, lists, -1)
+				sut := NewListCache(BLACKLIST, lists, -1, MatchExact, "")
 
 				c := sut.Configuration()
 				Expect(c).Should(ContainElement("refresh: disabled"))
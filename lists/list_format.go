@@ -0,0 +1,89 @@
+package lists
+
+import (
+	"regexp"
+	"strings"
+)
+
+type listFormat int
+
+const (
+	// formatUnknown is used until the first meaningful line of the source was read
+	formatUnknown listFormat = iota
+	// formatHosts contains plain domain names or hosts file lines ("0.0.0.0 example.com")
+	formatHosts
+	// formatAdblock contains rules in AdBlock Plus / uBlock syntax ("||example.com^")
+	formatAdblock
+)
+
+func (f listFormat) String() string {
+	names := [...]string{
+		"unknown",
+		"hosts",
+		"adblock"}
+
+	return names[f]
+}
+
+// nolint:gochecknoglobals
+var adblockDomainRegex = regexp.MustCompile(`^[a-z0-9_]([a-z0-9_.-]*[a-z0-9_])?$`)
+
+// detectFormat determines the format of the source by its first meaningful line
+func detectFormat(line string) listFormat {
+	switch {
+	case len(line) == 0 || strings.HasPrefix(line, "#"):
+		return formatUnknown
+	case strings.HasPrefix(line, "[Adblock"), strings.HasPrefix(line, "!"),
+		strings.HasPrefix(line, "||"), strings.HasPrefix(line, "@@"):
+		return formatAdblock
+	}
+
+	return formatHosts
+}
+
+// isComment returns true for empty lines and comments of the format
+func isComment(line string, format listFormat) bool {
+	if len(line) == 0 || strings.HasPrefix(line, "#") {
+		return true
+	}
+
+	return format == formatAdblock && (strings.HasPrefix(line, "!") || strings.HasPrefix(line, "["))
+}
+
+// parseAdblockRule parses a rule of the DNS compatible subset of the AdBlock syntax and converts it
+// to list entries: "||example.com^" blocks the domain with all sub domains, "example.com^" only the domain
+// itself and "/regex/" is a regular expression. Rules with the prefix "@@" are exception rules.
+// Cosmetic rules, URL rules and rules with modifiers (except "$important") are not supported
+func parseAdblockRule(line string) (entries []string, exception bool, ok bool) {
+	if strings.HasPrefix(line, "@@") {
+		exception = true
+		line = line[2:]
+	}
+
+	if _, isRegex := regexPattern(line); isRegex {
+		return []string{line}, exception, true
+	}
+
+	if idx := strings.Index(line, "$"); idx >= 0 {
+		if line[idx+1:] != "important" {
+			return nil, exception, false
+		}
+
+		line = line[:idx]
+	}
+
+	subdomains := strings.HasPrefix(line, "||")
+	line = strings.TrimPrefix(line, "||")
+	line = strings.TrimSuffix(strings.TrimSuffix(line, "|"), "^")
+	line = strings.ToLower(line)
+
+	if !adblockDomainRegex.MatchString(line) {
+		return nil, exception, false
+	}
+
+	if subdomains {
+		return []string{line, wildcardPrefix + line}, exception, true
+	}
+
+	return []string{line}, exception, true
+}
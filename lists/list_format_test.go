@@ -0,0 +1,48 @@
+package lists
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("ListFormat", func() {
+	DescribeTable("detection of the format",
+		func(line string, expected listFormat) {
+			Expect(detectFormat(line)).Should(Equal(expected))
+		},
+		Entry("empty line", "", formatUnknown),
+		Entry("hosts comment", "# comment", formatUnknown),
+		Entry("adblock header", "[Adblock Plus 2.0]", formatAdblock),
+		Entry("adblock comment", "! Title: list", formatAdblock),
+		Entry("adblock rule", "||example.com^", formatAdblock),
+		Entry("adblock exception", "@@||example.com^", formatAdblock),
+		Entry("hosts line", "0.0.0.0 example.com", formatHosts),
+		Entry("plain domain", "example.com", formatHosts),
+	)
+
+	DescribeTable("parsing of AdBlock rules",
+		func(line string, expectedEntries []string, expectedException, expectedOk bool) {
+			entries, exception, ok := parseAdblockRule(line)
+			Expect(ok).Should(Equal(expectedOk))
+			Expect(exception).Should(Equal(expectedException))
+			if expectedEntries == nil {
+				Expect(entries).Should(BeEmpty())
+			} else {
+				Expect(entries).Should(Equal(expectedEntries))
+			}
+		},
+		Entry("domain with sub domains", "||Ads.Example.com^",
+			[]string{"ads.example.com", "*.ads.example.com"}, false, true),
+		Entry("domain with end anchor", "||example.com^|", []string{"example.com", "*.example.com"}, false, true),
+		Entry("domain only", "example.com^", []string{"example.com"}, false, true),
+		Entry("exception", "@@||example.com^", []string{"example.com", "*.example.com"}, true, true),
+		Entry("important modifier", "||example.com^$important", []string{"example.com", "*.example.com"}, false, true),
+		Entry("regular expression", "/^ad[0-9]+\\./", []string{"/^ad[0-9]+\\./"}, false, true),
+		Entry("unsupported modifier", "||example.com^$third-party", nil, false, false),
+		Entry("url rule", "||example.com/ads/banner.png", nil, false, false),
+		Entry("cosmetic rule", "example.com##.banner", nil, false, false),
+		Entry("start anchor", "|https://example.com", nil, false, false),
+		Entry("wildcard inside domain", "||ads*.example.com^", nil, false, false),
+	)
+})
@@ -0,0 +1,265 @@
+package lists
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/privacyherodev/ph-blocky/metrics"
+	"github.com/privacyherodev/ph-blocky/util"
+)
+
+// overlaySource is the source of overlay entries, for example in the explanation of a match
+const overlaySource = "overlay"
+
+// Overlay contains single entries per list type and group, which were added at runtime (for example via REST API).
+// The entries are stored in a file, so they survive restarts. List refreshes don't change the overlay
+type Overlay struct {
+	// empty, if the entries should be kept only in memory
+	file    string
+	lock    sync.RWMutex
+	entries map[ListCacheType]map[string][]string
+	// caches matching the entries, updated with each change
+	caches map[ListCacheType]*ListCache
+}
+
+// overlayFile is the persisted content of the overlay
+type overlayFile struct {
+	BlackLists map[string][]string `json:"blackLists"`
+	WhiteLists map[string][]string `json:"whiteLists"`
+}
+
+// NewOverlay reads the entries from the file. A missing file means no entries, an unreadable file is an error
+func NewOverlay(file string) (*Overlay, error) {
+	o := &Overlay{
+		file: file,
+		entries: map[ListCacheType]map[string][]string{
+			BLACKLIST: {},
+			WHITELIST: {},
+		},
+		caches: make(map[ListCacheType]*ListCache),
+	}
+
+	if len(file) == 0 {
+		return o, nil
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return o, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("can't read list overlay file: %v", err)
+	}
+
+	var content overlayFile
+	if err := json.Unmarshal(data, &content); err != nil {
+		return nil, fmt.Errorf("can't parse list overlay file: %v", err)
+	}
+
+	for t, groups := range map[ListCacheType]map[string][]string{
+		BLACKLIST: content.BlackLists,
+		WHITELIST: content.WhiteLists,
+	} {
+		for group, entries := range groups {
+			for _, entry := range entries {
+				if normalized, err := NormalizeEntry(entry); err == nil {
+					o.entries[t][group] = append(o.entries[t][group], normalized)
+				} else {
+					logger().WithField("group", group).Warnf("ignoring overlay entry: %v", err)
+				}
+			}
+
+			sort.Strings(o.entries[t][group])
+		}
+	}
+
+	return o, nil
+}
+
+// NormalizeEntry validates the entry (domain name, wildcard "*.example.com", IP address or IP range "10.0.0.0/8")
+// and returns it in lower case without trailing dot
+func NormalizeEntry(entry string) (string, error) {
+	entry = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(entry)), ".")
+
+	if ip := net.ParseIP(entry); ip != nil {
+		return ip.String(), nil
+	}
+
+	if _, ipNet, err := net.ParseCIDR(entry); err == nil {
+		return ipNet.String(), nil
+	}
+
+	if !adblockDomainRegex.MatchString(strings.TrimPrefix(entry, wildcardPrefix)) {
+		return "", fmt.Errorf("invalid entry '%s', please use a domain name, a wildcard (*.example.com), an IP address or an IP range",
+			entry)
+	}
+
+	return entry, nil
+}
+
+// Groups returns the sorted names of the groups with entries of the list type
+func (o *Overlay) Groups(t ListCacheType) []string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	groups := make([]string, 0, len(o.entries[t]))
+	for group := range o.entries[t] {
+		groups = append(groups, group)
+	}
+
+	sort.Strings(groups)
+
+	return groups
+}
+
+// Entries returns the sorted entries of the group
+func (o *Overlay) Entries(t ListCacheType, group string) []string {
+	o.lock.RLock()
+	defer o.lock.RUnlock()
+
+	return append([]string{}, o.entries[t][group]...)
+}
+
+// Attach passes the entries of the list type to the cache and updates them in the cache with each change
+func (o *Overlay) Attach(t ListCacheType, cache *ListCache) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	o.caches[t] = cache
+
+	for group, entries := range o.entries[t] {
+		cache.SetOverlay(group, entries)
+	}
+}
+
+// Add adds the entry to the group and stores the overlay. Returns false, if the group already contains the entry
+func (o *Overlay) Add(t ListCacheType, group, entry string) (bool, error) {
+	entry, err := NormalizeEntry(entry)
+	if err != nil {
+		return false, err
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	entries := o.entries[t][group]
+
+	idx := sort.SearchStrings(entries, entry)
+	if idx < len(entries) && entries[idx] == entry {
+		return false, nil
+	}
+
+	updated := make([]string, 0, len(entries)+1)
+	updated = append(updated, entries[:idx]...)
+	updated = append(updated, entry)
+	updated = append(updated, entries[idx:]...)
+
+	return true, o.update(t, group, updated)
+}
+
+// Remove removes the entry from the group and stores the overlay. Returns false, if the group doesn't contain the entry
+func (o *Overlay) Remove(t ListCacheType, group, entry string) (bool, error) {
+	entry, err := NormalizeEntry(entry)
+	if err != nil {
+		// invalid entries can't be added, so the group doesn't contain it
+		return false, nil
+	}
+
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	entries := o.entries[t][group]
+
+	idx := sort.SearchStrings(entries, entry)
+	if idx == len(entries) || entries[idx] != entry {
+		return false, nil
+	}
+
+	updated := make([]string, 0, len(entries)-1)
+	updated = append(updated, entries[:idx]...)
+	updated = append(updated, entries[idx+1:]...)
+
+	return true, o.update(t, group, updated)
+}
+
+// update replaces the entries of the group, stores the overlay and updates the attached cache.
+// On error the previous entries are kept. The caller must hold the lock, so the cache gets the changes in order
+func (o *Overlay) update(t ListCacheType, group string, entries []string) error {
+	previous, existed := o.entries[t][group]
+
+	if len(entries) > 0 {
+		o.entries[t][group] = entries
+	} else {
+		delete(o.entries[t], group)
+	}
+
+	if err := o.save(); err != nil {
+		if existed {
+			o.entries[t][group] = previous
+		} else {
+			delete(o.entries[t], group)
+		}
+
+		return err
+	}
+
+	if cache, found := o.caches[t]; found {
+		cache.SetOverlay(group, entries)
+	}
+
+	return nil
+}
+
+// save writes the overlay atomically to the file
+func (o *Overlay) save() error {
+	if len(o.file) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(overlayFile{
+		BlackLists: o.entries[BLACKLIST],
+		WhiteLists: o.entries[WHITELIST],
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := util.WriteFileAtomic(o.file, data); err != nil {
+		return fmt.Errorf("can't write list overlay file: %v", err)
+	}
+
+	return nil
+}
+
+// SetOverlay replaces the overlay entries of the group. Overlay entries are matched like entries of the lists
+// of the group and are kept on list refreshes
+func (b *ListCache) SetOverlay(group string, entries []string) {
+	builder := &entriesBuilder{}
+	for i, entry := range entries {
+		builder.add(entry, i+1)
+	}
+
+	b.lock.Lock()
+
+	if builder.empty() {
+		delete(b.overlaySets, group)
+	} else {
+		b.overlaySets[group] = builder.build(overlaySource)
+	}
+
+	b.groupCaches[group] = b.createGroupCache(group)
+	count := b.groupCaches[group].count()
+
+	b.lock.Unlock()
+
+	if metrics.IsEnabled() {
+		b.counter.WithLabelValues(group).Set(float64(count))
+	}
+}
@@ -0,0 +1,166 @@
+package lists
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/privacyherodev/ph-blocky/helpertest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Overlay", func() {
+	var (
+		dir  string
+		file string
+	)
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "overlay")
+		Expect(err).Should(Succeed())
+		file = filepath.Join(dir, "overlay.json")
+	})
+	AfterEach(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	When("entries are added and removed", func() {
+		It("should store the entries in the file", func() {
+			sut, err := NewOverlay(file)
+			Expect(err).Should(Succeed())
+			Expect(sut.Groups(WHITELIST)).Should(BeEmpty())
+
+			added, err := sut.Add(WHITELIST, "ads", "B.com.")
+			Expect(err).Should(Succeed())
+			Expect(added).Should(BeTrue())
+
+			added, err = sut.Add(WHITELIST, "ads", "*.a.com")
+			Expect(err).Should(Succeed())
+			Expect(added).Should(BeTrue())
+
+			added, err = sut.Add(WHITELIST, "ads", "b.com")
+			Expect(err).Should(Succeed())
+			Expect(added).Should(BeFalse())
+
+			_, err = sut.Add(BLACKLIST, "ads", "192.168.178.1")
+			Expect(err).Should(Succeed())
+
+			removed, err := sut.Remove(BLACKLIST, "ads", "192.168.178.1")
+			Expect(err).Should(Succeed())
+			Expect(removed).Should(BeTrue())
+
+			removed, err = sut.Remove(BLACKLIST, "ads", "192.168.178.1")
+			Expect(err).Should(Succeed())
+			Expect(removed).Should(BeFalse())
+
+			By("reading the file again", func() {
+				reloaded, err := NewOverlay(file)
+				Expect(err).Should(Succeed())
+				Expect(reloaded.Groups(WHITELIST)).Should(Equal([]string{"ads"}))
+				Expect(reloaded.Entries(WHITELIST, "ads")).Should(Equal([]string{"*.a.com", "b.com"}))
+				Expect(reloaded.Groups(BLACKLIST)).Should(BeEmpty())
+			})
+		})
+	})
+	When("entries are removed in other notation", func() {
+		It("should normalize the entries like on adding", func() {
+			sut, _ := NewOverlay("")
+
+			_, err := sut.Add(BLACKLIST, "ads", "::1")
+			Expect(err).Should(Succeed())
+			_, err = sut.Add(BLACKLIST, "ads", "10.1.0.0/16")
+			Expect(err).Should(Succeed())
+
+			removed, err := sut.Remove(BLACKLIST, "ads", "::0001")
+			Expect(err).Should(Succeed())
+			Expect(removed).Should(BeTrue())
+
+			removed, err = sut.Remove(BLACKLIST, "ads", "10.1.2.3/16")
+			Expect(err).Should(Succeed())
+			Expect(removed).Should(BeTrue())
+
+			removed, err = sut.Remove(BLACKLIST, "ads", "http://example.com/ads")
+			Expect(err).Should(Succeed())
+			Expect(removed).Should(BeFalse())
+		})
+	})
+	When("a list cache is attached", func() {
+		It("should pass the entries and each change to the cache", func() {
+			sut, _ := NewOverlay("")
+			_, err := sut.Add(BLACKLIST, "gr1", "added.com")
+			Expect(err).Should(Succeed())
+
+			cache := NewListCache(BLACKLIST, map[string][]string{"gr1": {}}, -1, MatchExact, "")
+			sut.Attach(BLACKLIST, cache)
+
+			found, _, _ := cache.Match("added.com", []string{"gr1"})
+			Expect(found).Should(BeTrue())
+
+			_, err = sut.Add(BLACKLIST, "gr1", "other.com")
+			Expect(err).Should(Succeed())
+			_, err = sut.Remove(BLACKLIST, "gr1", "added.com")
+			Expect(err).Should(Succeed())
+
+			found, _, _ = cache.Match("added.com", []string{"gr1"})
+			Expect(found).Should(BeFalse())
+			found, _, _ = cache.Match("other.com", []string{"gr1"})
+			Expect(found).Should(BeTrue())
+		})
+	})
+	When("entry is invalid", func() {
+		It("should return error", func() {
+			sut, _ := NewOverlay("")
+
+			_, err := sut.Add(BLACKLIST, "ads", "http://example.com/ads")
+			Expect(err).Should(HaveOccurred())
+
+			_, err = sut.Add(BLACKLIST, "ads", "/regex/")
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+	When("file is not valid", func() {
+		It("should return error", func() {
+			Expect(ioutil.WriteFile(file, []byte("not json"), 0600)).Should(Succeed())
+
+			_, err := NewOverlay(file)
+			Expect(err).Should(HaveOccurred())
+		})
+	})
+	When("overlay entries are set in the list cache", func() {
+		It("should match the entries and keep them on refresh", func() {
+			listFile := TempFile("blocked1.com")
+			defer os.Remove(listFile.Name())
+
+			sut := NewListCache(BLACKLIST, map[string][]string{"gr1": {listFile.Name()}}, -1, MatchExact, "")
+			sut.SetOverlay("gr1", []string{"added.com"})
+			sut.SetOverlay("gr2", []string{"*.other.com"})
+
+			found, group, entry := sut.Match("added.com", []string{"gr1"})
+			Expect(found).Should(BeTrue())
+			Expect(group).Should(Equal("gr1"))
+			Expect(entry).Should(Equal("added.com"))
+
+			found, group, _ = sut.Match("www.other.com", []string{"gr1", "gr2"})
+			Expect(found).Should(BeTrue())
+			Expect(group).Should(Equal("gr2"))
+
+			sut.refresh()
+
+			found, _, _ = sut.Match("added.com", []string{"gr1"})
+			Expect(found).Should(BeTrue())
+			found, _, _ = sut.Match("blocked1.com", []string{"gr1"})
+			Expect(found).Should(BeTrue())
+
+			Expect(sut.Explain("added.com", []string{"gr1"})).Should(Equal([]MatchResult{
+				{Group: "gr1", Found: true, Entry: "added.com", Source: "overlay", Line: 1},
+			}))
+
+			sut.SetOverlay("gr1", nil)
+
+			found, _, _ = sut.Match("added.com", []string{"gr1"})
+			Expect(found).Should(BeFalse())
+		})
+	})
+})
@@ -0,0 +1,146 @@
+package lists
+
+import (
+	"bytes"
+	"sort"
+)
+
+// sortedBlob contains sorted distinct strings in one byte slice. Compared to a []string, it needs no string header
+// (16 bytes) and no separate allocation per entry, only a 4 byte offset
+type sortedBlob struct {
+	data []byte
+	// start of each entry in data, the entry ends at the start of the next entry
+	offsets []uint32
+}
+
+func (s *sortedBlob) len() int {
+	return len(s.offsets)
+}
+
+// at returns the entry with the index, the returned slice must not be modified
+func (s *sortedBlob) at(i int) []byte {
+	end := len(s.data)
+	if i+1 < len(s.offsets) {
+		end = int(s.offsets[i+1])
+	}
+
+	return s.data[s.offsets[i]:end]
+}
+
+// search returns the index of the value, the comparisons don't allocate memory
+func (s *sortedBlob) search(value string) (int, bool) {
+	idx := sort.Search(len(s.offsets), func(i int) bool {
+		return string(s.at(i)) >= value
+	})
+
+	return idx, idx < len(s.offsets) && string(s.at(idx)) == value
+}
+
+// blobBuilder collects entries in insertion order with their line numbers and builds the sortedBlob
+type blobBuilder struct {
+	data    []byte
+	offsets []uint32
+	lines   []int32
+}
+
+func (b *blobBuilder) add(value string, line int) {
+	b.offsets = append(b.offsets, uint32(len(b.data)))
+	b.data = append(b.data, value...)
+	b.lines = append(b.lines, int32(line))
+}
+
+// append adds all entries of the other builder
+func (b *blobBuilder) append(other *blobBuilder) {
+	base := uint32(len(b.data))
+
+	for _, offset := range other.offsets {
+		b.offsets = append(b.offsets, base+offset)
+	}
+
+	b.data = append(b.data, other.data...)
+	b.lines = append(b.lines, other.lines...)
+}
+
+func (b *blobBuilder) value(i uint32) []byte {
+	end := len(b.data)
+	if int(i)+1 < len(b.offsets) {
+		end = int(b.offsets[i+1])
+	}
+
+	return b.data[b.offsets[i]:end]
+}
+
+// build sorts the entries and removes duplicates, the line number of the first occurrence is kept.
+// The builder must not be used afterwards
+func (b *blobBuilder) build() (sortedBlob, []int32) {
+	idx := make([]uint32, len(b.offsets))
+	for i := range idx {
+		idx[i] = uint32(i)
+	}
+
+	sort.Slice(idx, func(i, j int) bool {
+		if c := bytes.Compare(b.value(idx[i]), b.value(idx[j])); c != 0 {
+			return c < 0
+		}
+
+		return idx[i] < idx[j]
+	})
+
+	result := sortedBlob{data: make([]byte, 0, len(b.data))}
+	lines := make([]int32, 0, len(idx))
+
+	var previous []byte
+
+	for n, i := range idx {
+		v := b.value(i)
+		if n > 0 && bytes.Equal(v, previous) {
+			continue
+		}
+
+		result.offsets = append(result.offsets, uint32(len(result.data)))
+		result.data = append(result.data, v...)
+		lines = append(lines, b.lines[i])
+		previous = v
+	}
+
+	*b = blobBuilder{}
+
+	return result, lines
+}
+
+// countDistinct returns the number of distinct entries of all blobs
+func countDistinct(blobs []*sortedBlob) int {
+	if len(blobs) == 1 {
+		return blobs[0].len()
+	}
+
+	pos := make([]int, len(blobs))
+	count := 0
+
+	for {
+		var lowest []byte
+
+		found := false
+
+		for i, blob := range blobs {
+			if pos[i] < blob.len() {
+				if v := blob.at(pos[i]); !found || bytes.Compare(v, lowest) < 0 {
+					lowest = v
+					found = true
+				}
+			}
+		}
+
+		if !found {
+			return count
+		}
+
+		count++
+
+		for i, blob := range blobs {
+			if pos[i] < blob.len() && bytes.Equal(blob.at(pos[i]), lowest) {
+				pos[i]++
+			}
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package lists
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SortedBlob", func() {
+	var (
+		sut   sortedBlob
+		lines []int32
+	)
+
+	BeforeEach(func() {
+		b := &blobBuilder{}
+		b.add("c.com", 1)
+		b.add("a.com", 2)
+		b.add("c.com", 3)
+
+		other := &blobBuilder{}
+		other.add("b.com", 4)
+		other.add("a.com", 5)
+		b.append(other)
+
+		sut, lines = b.build()
+	})
+
+	It("should contain sorted distinct entries with the line of the first occurrence", func() {
+		Expect(sut.len()).Should(Equal(3))
+		Expect(string(sut.at(0))).Should(Equal("a.com"))
+		Expect(string(sut.at(1))).Should(Equal("b.com"))
+		Expect(string(sut.at(2))).Should(Equal("c.com"))
+		Expect(lines).Should(Equal([]int32{2, 4, 1}))
+		Expect(sut.data).Should(HaveLen(15))
+	})
+
+	It("should find entries", func() {
+		idx, found := sut.search("b.com")
+		Expect(found).Should(BeTrue())
+		Expect(idx).Should(Equal(1))
+
+		_, found = sut.search("d.com")
+		Expect(found).Should(BeFalse())
+
+		_, found = sut.search("a.co")
+		Expect(found).Should(BeFalse())
+	})
+
+	It("should count distinct entries of several blobs", func() {
+		b := &blobBuilder{}
+		b.add("b.com", 1)
+		b.add("d.com", 2)
+		other, _ := b.build()
+
+		empty := &blobBuilder{}
+		emptyBlob, _ := empty.build()
+
+		Expect(countDistinct([]*sortedBlob{&sut, &other, &emptyBlob})).Should(Equal(4))
+		Expect(countDistinct([]*sortedBlob{&sut})).Should(Equal(3))
+		Expect(countDistinct(nil)).Should(Equal(0))
+	})
+})
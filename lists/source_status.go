@@ -0,0 +1,174 @@
+package lists
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// SourceStatus contains the result of the last processing of a list source (link or file)
+type SourceStatus struct {
+	Link string
+	// number of entries from the last processing
+	Entries     int
+	LastAttempt time.Time
+	// time of the last successful download (or read of a file)
+	LastSuccess time.Time
+	// error of the last attempt, empty if the last attempt was successful
+	LastError string
+	// HTTP status code of the last download, 0 for files
+	HTTPStatus int
+	// duration of the last download (or read of a file) including parsing
+	Duration time.Duration
+	// number of bytes of the last download (or read of a file), 0 if the list was not modified
+	Bytes int64
+	// download time of the stored copy, which is used because the download failed. Zero if not used
+	FallbackDownloaded time.Time
+}
+
+// GroupStatus contains the status of all sources of a group
+type GroupStatus struct {
+	Group string
+	// number of entries in the group cache
+	Entries int
+	Sources []SourceStatus
+}
+
+// Status returns the status of all groups (sorted by name) and their sources
+func (b *ListCache) Status() []GroupStatus {
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+
+	result := make([]GroupStatus, 0, len(b.groupToLinks))
+
+	for group, links := range b.groupToLinks {
+		gs := GroupStatus{Group: group, Sources: make([]SourceStatus, 0, len(links))}
+
+		if c, ok := b.groupCaches[group]; ok {
+			gs.Entries = c.count()
+		}
+
+		for _, link := range links {
+			s, ok := b.sourceStatuses[sourceKey{group: group, link: link}]
+			if !ok {
+				s = SourceStatus{Link: link}
+			}
+
+			s.FallbackDownloaded = b.fallbackTimes[link]
+
+			gs.Sources = append(gs.Sources, s)
+		}
+
+		result = append(result, gs)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Group < result[j].Group
+	})
+
+	return result
+}
+
+// sourceKey identifies the status of a link in a group, groups with the same link have their own status
+type sourceKey struct {
+	group string
+	link  string
+}
+
+// updateSourceStatus stores the result of the processing of the link for the group. The entries and the time of
+// the last success are kept from the previous status, if the processing didn't replace them.
+// The caller must hold the lock
+func (b *ListCache) updateSourceStatus(group string, result sourceResult) {
+	key := sourceKey{group: group, link: result.status.Link}
+	status, previous := result.status, b.sourceStatuses[key]
+
+	if result.notModified || result.failed {
+		status.Entries = previous.Entries
+	}
+
+	if status.LastSuccess.IsZero() {
+		status.LastSuccess = previous.LastSuccess
+	}
+
+	b.sourceStatuses[key] = status
+}
+
+// countingReader counts the read bytes
+type countingReader struct {
+	io.ReadCloser
+	count int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.count += int64(n)
+
+	return n, err
+}
+
+// sourceStatusCollector exports the status of each source as metrics
+type sourceStatusCollector struct {
+	cache       *ListCache
+	entries     *prometheus.Desc
+	lastSuccess *prometheus.Desc
+	errorStatus *prometheus.Desc
+	httpStatus  *prometheus.Desc
+	duration    *prometheus.Desc
+	bytes       *prometheus.Desc
+}
+
+func newSourceStatusCollector(t ListCacheType, cache *ListCache) *sourceStatusCollector {
+	labels := []string{"group", "link"}
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(fmt.Sprintf("blocky_%s_source_%s", t, name), help, labels, nil)
+	}
+
+	return &sourceStatusCollector{
+		cache:       cache,
+		entries:     desc("entries", "Number of entries of the list source"),
+		lastSuccess: desc("last_success_timestamp_seconds", "Time of the last successful download of the list source"),
+		errorStatus: desc("error", "1 if the last download of the list source failed, otherwise 0"),
+		httpStatus:  desc("http_status", "HTTP status code of the last download of the list source"),
+		duration:    desc("download_duration_seconds", "Duration of the last download of the list source"),
+		bytes:       desc("bytes", "Size of the last download of the list source"),
+	}
+}
+
+func (c *sourceStatusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.entries
+	ch <- c.lastSuccess
+	ch <- c.errorStatus
+	ch <- c.httpStatus
+	ch <- c.duration
+	ch <- c.bytes
+}
+
+func (c *sourceStatusCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, group := range c.cache.Status() {
+		for _, s := range group.Sources {
+			gauge := func(desc *prometheus.Desc, value float64) {
+				ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, group.Group, s.Link)
+			}
+
+			var lastSuccess, failed float64
+
+			if !s.LastSuccess.IsZero() {
+				lastSuccess = float64(s.LastSuccess.Unix())
+			}
+
+			if len(s.LastError) > 0 {
+				failed = 1
+			}
+
+			gauge(c.entries, float64(s.Entries))
+			gauge(c.lastSuccess, lastSuccess)
+			gauge(c.errorStatus, failed)
+			gauge(c.httpStatus, float64(s.HTTPStatus))
+			gauge(c.duration, s.Duration.Seconds())
+			gauge(c.bytes, float64(s.Bytes))
+		}
+	}
+}
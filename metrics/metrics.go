@@ -14,8 +14,15 @@ var reg = prometheus.NewRegistry()
 // nolint
 var enabled bool
 
+// RegisterMetric registers the collector. An already registered collector with the same
+// description (for example from a resolver replaced by a config reload) will be replaced
 func RegisterMetric(c prometheus.Collector) {
-	_ = reg.Register(c)
+	if err := reg.Register(c); err != nil {
+		if are, ok := err.(prometheus.AlreadyRegisteredError); ok {
+			reg.Unregister(are.ExistingCollector)
+			_ = reg.Register(c)
+		}
+	}
 }
 
 func Start(router *chi.Mux, cfg config.PrometheusConfig) {
@@ -0,0 +1,180 @@
+package resolver
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/log"
+
+	"github.com/patrickmn/go-cache"
+)
+
+const (
+	// how long block decisions are kept for the block page
+	blockDecisionTTL = 10 * time.Minute
+	// max count of stored unblock requests, the oldest requests are removed first
+	maxUnblockRequests = 100
+)
+
+// BlockDecision is a recent decision of the blocking resolver, which is shown on the block page
+type BlockDecision struct {
+	Host   string
+	Group  string
+	Reason string
+	Time   time.Time
+}
+
+// blockDecisions records the recent block decisions per client IP and host
+type blockDecisions struct {
+	cache *cache.Cache
+}
+
+func newBlockDecisions() *blockDecisions {
+	return &blockDecisions{cache: cache.New(blockDecisionTTL, blockDecisionTTL)}
+}
+
+func (d *blockDecisions) add(clientIP net.IP, host, group, reason string) {
+	if clientIP == nil {
+		return
+	}
+
+	host = normalizeHost(host)
+
+	d.cache.SetDefault(decisionKey(clientIP, host), BlockDecision{
+		Host:   host,
+		Group:  group,
+		Reason: reason,
+		Time:   time.Now(),
+	})
+}
+
+func (d *blockDecisions) get(clientIP net.IP, host string) (BlockDecision, bool) {
+	if clientIP == nil {
+		return BlockDecision{}, false
+	}
+
+	if val, found := d.cache.Get(decisionKey(clientIP, normalizeHost(host))); found {
+		return val.(BlockDecision), true
+	}
+
+	return BlockDecision{}, false
+}
+
+func decisionKey(clientIP net.IP, host string) string {
+	return clientIP.String() + " " + host
+}
+
+// normalizeHost converts the domain name of the DNS question or the host of a HTTP request to lower case
+// without trailing dot
+func normalizeHost(host string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(host)), ".")
+}
+
+// BlockDecision returns the recent block decision for the host and client IP, for example to show it on the block page
+func (r *BlockingResolver) BlockDecision(clientIP net.IP, host string) (BlockDecision, bool) {
+	return r.decisions.get(clientIP, host)
+}
+
+// unblockRequests contains the unblock requests of clients from the block page, newest request last
+type unblockRequests struct {
+	lock    sync.Mutex
+	entries []api.UnblockRequestEntry
+}
+
+// add stores the request, a repeated request of the same client and host replaces the previous one
+func (u *unblockRequests) add(entry api.UnblockRequestEntry) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	for i, e := range u.entries {
+		if e.Client == entry.Client && e.Host == entry.Host {
+			u.entries = append(u.entries[:i], u.entries[i+1:]...)
+
+			break
+		}
+	}
+
+	u.entries = append(u.entries, entry)
+
+	if len(u.entries) > maxUnblockRequests {
+		u.entries = u.entries[len(u.entries)-maxUnblockRequests:]
+	}
+}
+
+func (u *unblockRequests) list() []api.UnblockRequestEntry {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+
+	result := make([]api.UnblockRequestEntry, len(u.entries))
+	copy(result, u.entries)
+
+	return result
+}
+
+// apiUnblockRequestAdd is the http endpoint to request the unblock of a blocked host, used by the block page
+// @Summary Request unblock
+// @Description stores the request of the calling client to unblock a host together with the recent block decision.
+// @Description The requests can be reviewed with GET, the host is not unblocked automatically
+// @Tags blocking
+// @Accept  json
+// @Param request body api.UnblockRequest true "blocked host"
+// @Success 200   "Request was stored"
+// @Failure 400   "Host is missing"
+// @Router /blocking/unblock-requests [post]
+func (r *BlockingResolver) apiUnblockRequestAdd(rw http.ResponseWriter, req *http.Request) {
+	var unblockRequest api.UnblockRequest
+	if err := json.NewDecoder(req.Body).Decode(&unblockRequest); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	host := normalizeHost(unblockRequest.Host)
+	if len(host) == 0 {
+		http.Error(rw, "host is missing", http.StatusBadRequest)
+
+		return
+	}
+
+	clientIP := remoteIP(req)
+	entry := api.UnblockRequestEntry{Client: clientIP.String(), Host: host, RequestedAt: time.Now()}
+
+	if decision, found := r.decisions.get(clientIP, host); found {
+		entry.Group = decision.Group
+		entry.Reason = decision.Reason
+	}
+
+	log.Logger.Infof("client '%s' requests unblock of '%s'", entry.Client, host)
+	r.unblockRequests.add(entry)
+}
+
+// apiUnblockRequests is the http endpoint to get the unblock requests of the clients
+// @Summary Unblock requests
+// @Description get the unblock requests of the clients from the block page, newest request last
+// @Tags blocking
+// @Produce  json
+// @Success 200 {array} api.UnblockRequestEntry "Returns the unblock requests"
+// @Router /blocking/unblock-requests [get]
+func (r *BlockingResolver) apiUnblockRequests(rw http.ResponseWriter, _ *http.Request) {
+	response, _ := json.Marshal(r.unblockRequests.list())
+	_, err := rw.Write(response)
+
+	if err != nil {
+		log.Logger.Fatal("unable to write response ", err)
+	}
+}
+
+// remoteIP returns the IP address of the HTTP client
+func remoteIP(req *http.Request) net.IP {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	return net.ParseIP(host)
+}
@@ -0,0 +1,271 @@
+package resolver
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/log"
+)
+
+// pauseScope is the part of the blocking, which is paused: all groups of one client (empty group), one group for all
+// clients (empty client) or one group of one client
+type pauseScope struct {
+	// client IP, name or MAC address in lower case
+	client string
+	group  string
+}
+
+type pause struct {
+	// zero, if the pause lasts until blocking is enabled again
+	end   time.Time
+	timer *time.Timer
+}
+
+// pauses contains the active pauses of clients and groups, each pause has its own auto enable timer
+type pauses struct {
+	lock   sync.RWMutex
+	active map[pauseScope]*pause
+	// called after each change without holding the lock, for example to store the state
+	onChange func()
+}
+
+func newPauses() *pauses {
+	return &pauses{active: make(map[pauseScope]*pause), onChange: func() {}}
+}
+
+// add pauses the blocking for the scope, an existing pause of the same scope is replaced
+func (p *pauses) add(scope pauseScope, duration time.Duration) {
+	var end time.Time
+
+	if duration > 0 {
+		end = time.Now().Add(duration)
+
+		log.Logger.Infof("pause blocking for %s for %s", scope, duration)
+	} else {
+		log.Logger.Infof("pause blocking for %s", scope)
+	}
+
+	p.addUntil(scope, end)
+	p.onChange()
+}
+
+// restore adds a pause from the stored state, end is nil if the pause has no end
+func (p *pauses) restore(scope pauseScope, end *time.Time) {
+	if end == nil {
+		log.Logger.Infof("blocking is paused for %s (restored)", scope)
+		p.addUntil(scope, time.Time{})
+
+		return
+	}
+
+	log.Logger.Infof("blocking is paused for %s for %s (restored)", scope,
+		time.Until(*end).Round(time.Second))
+	p.addUntil(scope, *end)
+}
+
+// addUntil adds the pause, a zero end means no end
+func (p *pauses) addUntil(scope pauseScope, end time.Time) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.stop(scope)
+
+	entry := &pause{end: end}
+
+	if !end.IsZero() {
+		entry.timer = time.AfterFunc(time.Until(end), func() {
+			p.lock.Lock()
+
+			// the pause could be replaced in the meantime
+			if p.active[scope] != entry {
+				p.lock.Unlock()
+
+				return
+			}
+
+			delete(p.active, scope)
+			p.lock.Unlock()
+
+			log.Logger.Infof("blocking enabled again for %s", scope)
+			p.onChange()
+		})
+	}
+
+	p.active[scope] = entry
+}
+
+// remove ends the pause of the scope, returns false if the scope was not paused
+func (p *pauses) remove(scope pauseScope) bool {
+	p.lock.Lock()
+
+	if _, found := p.active[scope]; !found {
+		p.lock.Unlock()
+
+		return false
+	}
+
+	p.stop(scope)
+	p.lock.Unlock()
+
+	log.Logger.Infof("blocking enabled again for %s", scope)
+	p.onChange()
+
+	return true
+}
+
+// stop stops the timer of the scope and removes the pause, the caller must hold the lock
+func (p *pauses) stop(scope pauseScope) {
+	if existing, found := p.active[scope]; found {
+		if existing.timer != nil {
+			existing.timer.Stop()
+		}
+
+		delete(p.active, scope)
+	}
+}
+
+// stopAll stops all timers and removes all pauses
+func (p *pauses) stopAll() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	for scope := range p.active {
+		p.stop(scope)
+	}
+}
+
+// filter removes the paused groups of the client from the groups, the client is identified by each of the ids
+func (p *pauses) filter(ids []string, groups []string) []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	if len(p.active) == 0 {
+		return groups
+	}
+
+	clients := clientSet(ids)
+	paused := make(map[string]bool)
+
+	for scope := range p.active {
+		if clients[scope.client] {
+			if len(scope.group) == 0 {
+				// all groups of the client are paused
+				return []string{}
+			}
+
+			paused[scope.group] = true
+		}
+	}
+
+	result := make([]string, 0, len(groups))
+
+	for _, g := range groups {
+		if !paused[g] {
+			result = append(result, g)
+		}
+	}
+
+	return result
+}
+
+// describe returns the active pauses, which affect the client
+func (p *pauses) describe(ids []string) (result []string) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	clients := clientSet(ids)
+
+	for scope := range p.active {
+		if clients[scope.client] {
+			result = append(result, "blocking is paused for "+scope.String())
+		}
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// list returns all active pauses sorted by client and group
+func (p *pauses) list() []api.BlockingPause {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	result := make([]api.BlockingPause, 0, len(p.active))
+
+	for scope, entry := range p.active {
+		var autoEnableDuration time.Duration
+		if !entry.end.IsZero() {
+			autoEnableDuration = time.Until(entry.end)
+		}
+
+		result = append(result, api.BlockingPause{
+			Client:          scope.client,
+			Group:           scope.group,
+			AutoEnableInSec: uint(autoEnableDuration.Seconds()),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Client != result[j].Client {
+			return result[i].Client < result[j].Client
+		}
+
+		return result[i].Group < result[j].Group
+	})
+
+	return result
+}
+
+// state returns the active pauses for the state file
+func (p *pauses) state() []pauseState {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	result := make([]pauseState, 0, len(p.active))
+
+	for scope, entry := range p.active {
+		state := pauseState{Client: scope.client, Group: scope.group}
+
+		if !entry.end.IsZero() {
+			end := entry.end
+			state.End = &end
+		}
+
+		result = append(result, state)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Client != result[j].Client {
+			return result[i].Client < result[j].Client
+		}
+
+		return result[i].Group < result[j].Group
+	})
+
+	return result
+}
+
+func (s pauseScope) String() string {
+	switch {
+	case len(s.group) == 0:
+		return "client '" + s.client + "'"
+	case len(s.client) == 0:
+		return "group '" + s.group + "'"
+	}
+
+	return "group '" + s.group + "' of client '" + s.client + "'"
+}
+
+// clientSet contains the lower case ids of the client and the empty client of pauses for all clients
+func clientSet(ids []string) map[string]bool {
+	result := map[string]bool{"": true}
+	for _, id := range ids {
+		result[strings.ToLower(id)] = true
+	}
+
+	return result
+}
@@ -1,6 +1,7 @@
 package resolver
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"net"
@@ -8,6 +9,7 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/privacyherodev/ph-blocky/api"
@@ -23,8 +25,9 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 )
 
-func createBlockHandler(cfg config.BlockingConfig) blockHandler {
-	cfgBlockType := strings.TrimSpace(strings.ToUpper(cfg.BlockType))
+// createBlockHandler creates the handler for the configured block type (ZeroIP, NxDomain or IP addresses)
+func createBlockHandler(blockType string) blockHandler {
+	cfgBlockType := strings.TrimSpace(strings.ToUpper(blockType))
 	if cfgBlockType == "" || cfgBlockType == "ZEROIP" {
 		return zeroIPBlockHandler{}
 	}
@@ -53,58 +56,45 @@ func createBlockHandler(cfg config.BlockingConfig) blockHandler {
 	return zeroIPBlockHandler{}
 }
 
-type status struct {
-	enabled      bool
-	enabledGauge prometheus.Gauge
-	enableTimer  *time.Timer
-	disableEnd   time.Time
-}
-
-func (s *status) enableBlocking() {
-	s.enableTimer.Stop()
-	s.enabled = true
-
-	if metrics.IsEnabled() {
-		s.enabledGauge.Set(1)
-	}
-}
-
-func (s *status) disableBlocking(duration time.Duration) {
-	s.enableTimer.Stop()
-	s.enabled = false
-
-	if metrics.IsEnabled() {
-		s.enabledGauge.Set(0)
-	}
-
-	s.disableEnd = time.Now().Add(duration)
-
-	if duration == 0 {
-		log.Logger.Info("disable blocking")
-	} else {
-		log.Logger.Infof("disable blocking for %s", duration)
-		s.enableTimer = time.AfterFunc(duration, func() {
-			s.enableBlocking()
-			log.Logger.Info("blocking enabled again")
-		})
-	}
-}
-
 // checks request's question (domain name) against black and white lists
 type BlockingResolver struct {
 	NextResolver
-	blacklistMatcher    lists.Matcher
-	whitelistMatcher    lists.Matcher
+	blacklistMatcher    *lists.ListCache
+	whitelistMatcher    *lists.ListCache
 	cfg                 config.BlockingConfig
 	blockHandler        blockHandler
+	groupBlockHandlers  map[string]blockHandler
 	whitelistOnlyGroups []string
-	status              status
+	status              *status
+	listsRefresher      *listsRefresher
+	overlay             *lists.Overlay
+	schedules           map[string]*schedule
+	pauses              *pauses
+	toggles             *toggles
+	clientGroups        *clientGroupsMatcher
+	decisions           *blockDecisions
+	unblockRequests     *unblockRequests
+	// serializes writes of the state file, shared with reused resolvers
+	stateLock *sync.Mutex
 }
 
 func NewBlockingResolver(router *chi.Mux, cfg config.BlockingConfig) ChainedResolver {
-	blockHandler := createBlockHandler(cfg)
-	blacklistMatcher := lists.NewListCache(lists.BLACKLIST, cfg.BlackLists, cfg.RefreshPeriod)
-	whitelistMatcher := lists.NewListCache(lists.WHITELIST, cfg.WhiteLists, cfg.RefreshPeriod)
+	defaultBlockHandler := createBlockHandler(cfg.BlockType)
+
+	groupBlockHandlers := make(map[string]blockHandler, len(cfg.GroupBlockTypes))
+	for group, blockType := range cfg.GroupBlockTypes {
+		groupBlockHandlers[group] = createBlockHandler(blockType)
+	}
+
+	matchMode, err := lists.ParseMatchMode(cfg.MatchMode)
+	if err != nil {
+		log.Logger.Fatal(err)
+	}
+
+	blacklistMatcher := lists.NewListCache(lists.BLACKLIST, cfg.BlackLists, cfg.RefreshPeriod, matchMode,
+		cfg.DownloadCacheDir)
+	whitelistMatcher := lists.NewListCache(lists.WHITELIST, cfg.WhiteLists, cfg.RefreshPeriod, matchMode,
+		cfg.DownloadCacheDir)
 	whitelistOnlyGroups := determineWhitelistOnlyGroups(&cfg)
 
 	var enabledGauge prometheus.Gauge
@@ -120,35 +110,98 @@ func NewBlockingResolver(router *chi.Mux, cfg config.BlockingConfig) ChainedReso
 	}
 
 	res := &BlockingResolver{
-		blockHandler:        blockHandler,
+		blockHandler:        defaultBlockHandler,
+		groupBlockHandlers:  groupBlockHandlers,
 		cfg:                 cfg,
 		blacklistMatcher:    blacklistMatcher,
 		whitelistMatcher:    whitelistMatcher,
 		whitelistOnlyGroups: whitelistOnlyGroups,
-		status: status{
-			enabledGauge: enabledGauge,
-			enabled:      true,
-			enableTimer:  time.NewTimer(0),
-		},
+		listsRefresher:      newListsRefresher(blacklistMatcher, whitelistMatcher),
+		overlay: createOverlay(cfg.OverlayFile, map[lists.ListCacheType]*lists.ListCache{
+			lists.BLACKLIST: blacklistMatcher,
+			lists.WHITELIST: whitelistMatcher,
+		}),
+		schedules:       newSchedules(cfg.Schedules),
+		pauses:          newPauses(),
+		toggles:         newToggles(cfg),
+		clientGroups:    newClientGroupsMatcher(cfg.ClientGroupsBlock),
+		status:          newStatus(enabledGauge),
+		decisions:       newBlockDecisions(),
+		unblockRequests: &unblockRequests{},
+		stateLock:       &sync.Mutex{},
 	}
 
+	res.restoreState()
+	res.status.onChange = res.saveState
+	res.pauses.onChange = res.saveState
+	res.toggles.onChange = res.saveState
+
 	// register API endpoints
 	router.Get(api.BlockingEnablePath, res.apiBlockingEnable)
 	router.Get(api.BlockingDisablePath, res.apiBlockingDisable)
 	router.Get(api.BlockingStatusPath, res.apiBlockingStatus)
+	router.Get(api.SchedulesPath, res.apiSchedules)
+	router.Post(api.UnblockRequestsPath, res.apiUnblockRequestAdd)
+	router.Get(api.UnblockRequestsPath, res.apiUnblockRequests)
+	router.Get(api.TogglesPath, res.apiToggles)
+	router.Post(api.TogglesPath, res.apiTogglesSet)
+	router.Delete(api.TogglesPath, res.apiTogglesReset)
+	router.Get(api.ListsPath, res.apiLists)
+	router.Post(api.ListsRefreshPath, res.apiListsRefresh)
+	router.Get(api.ListsRefreshPath, res.apiListsRefreshStatus)
+	router.Get(api.ListEntriesPath, res.apiListEntries)
+	router.Post(api.ListEntriesPath, res.apiListEntriesAdd)
+	router.Delete(api.ListEntryPath, res.apiListEntriesRemove)
 
 	return res
 }
 
+// Reuse returns a new resolver sharing lists, status, pauses and toggles with this one, the next resolver of this
+// one is not changed. The API endpoints stay registered for this resolver, they work on the shared state
+func (r *BlockingResolver) Reuse() ChainedResolver {
+	res := *r
+	res.next = nil
+
+	return &res
+}
+
 // apiBlockingEnable is the http endpoint to enable the blocking status
 // @Summary Enable blocking
-// @Description enable the blocking status
+// @Description enable the blocking status or end the pause of a client and/or groups
 // @Tags blocking
+// @Param client query string false "client IP, name or MAC address of the pause"
+// @Param groups query string false "comma separated groups of the pause"
 // @Success 200   "Blocking is enabled"
+// @Failure 400   "Unknown group"
+// @Failure 404   "No pause for the client and groups"
 // @Router /blocking/enable [get]
-func (r *BlockingResolver) apiBlockingEnable(_ http.ResponseWriter, _ *http.Request) {
-	log.Logger.Info("enabling blocking...")
-	r.status.enableBlocking()
+func (r *BlockingResolver) apiBlockingEnable(rw http.ResponseWriter, req *http.Request) {
+	scopes, err := r.pauseScopes(req)
+	if err != nil {
+		log.Logger.Error(err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if len(scopes) == 0 {
+		log.Logger.Info("enabling blocking...")
+		r.status.enableBlocking()
+
+		return
+	}
+
+	removed := false
+
+	for _, scope := range scopes {
+		if r.pauses.remove(scope) {
+			removed = true
+		}
+	}
+
+	if !removed {
+		http.Error(rw, "blocking is not paused for the client and groups", http.StatusNotFound)
+	}
 }
 
 // apiBlockingStatus is the http endpoint to get current blocking status
@@ -159,14 +212,67 @@ func (r *BlockingResolver) apiBlockingEnable(_ http.ResponseWriter, _ *http.Requ
 // @Success 200 {object} api.BlockingStatus "Returns current blocking status"
 // @Router /blocking/status [get]
 func (r *BlockingResolver) apiBlockingStatus(rw http.ResponseWriter, _ *http.Request) {
+	enabled, disableEnd := r.status.get()
+
 	var autoEnableDuration time.Duration
-	if !r.status.enabled && r.status.disableEnd.After(time.Now()) {
-		autoEnableDuration = time.Until(r.status.disableEnd)
+	if !enabled && disableEnd.After(time.Now()) {
+		autoEnableDuration = time.Until(disableEnd)
 	}
 
 	response, _ := json.Marshal(api.BlockingStatus{
-		Enabled:         r.status.enabled,
+		Enabled:         enabled,
 		AutoEnableInSec: uint(autoEnableDuration.Seconds()),
+		Pauses:          r.pauses.list(),
+	})
+	_, err := rw.Write(response)
+
+	if err != nil {
+		log.Logger.Fatal("unable to write response ", err)
+	}
+}
+
+// apiSchedules is the http endpoint to get the schedules and whether they are currently active
+// @Summary Schedules
+// @Description get all schedules with their current state and the client groups which use them
+// @Tags blocking
+// @Produce  json
+// @Success 200 {array} api.ScheduleStatus "Returns the schedules"
+// @Router /blocking/schedules [get]
+func (r *BlockingResolver) apiSchedules(rw http.ResponseWriter, _ *http.Request) {
+	now := time.Now()
+	usages := r.scheduleUsages()
+
+	names := util.SortedKeys(r.schedules)
+	result := make([]api.ScheduleStatus, 0, len(names))
+
+	for _, name := range names {
+		result = append(result, api.ScheduleStatus{
+			Name:     name,
+			TimeZone: r.schedules[name].location.String(),
+			Active:   r.schedules[name].active(now),
+			UsedBy:   usages[name],
+		})
+	}
+
+	response, _ := json.Marshal(result)
+	_, err := rw.Write(response)
+
+	if err != nil {
+		log.Logger.Fatal("unable to write response ", err)
+	}
+}
+
+// apiLists is the http endpoint to get the status of all black and white lists
+// @Summary List status
+// @Description get the status of each list source per group (entries, last download, errors)
+// @Tags lists
+// @Produce  json
+// @Success 200 {object} api.ListsStatus "Returns the status of all lists"
+// @Router /lists [get]
+func (r *BlockingResolver) apiLists(rw http.ResponseWriter, _ *http.Request) {
+	response, _ := json.Marshal(api.ListsStatus{
+		BlackLists: toListGroups(r.blacklistMatcher.Status()),
+		WhiteLists: toListGroups(r.whitelistMatcher.Status()),
 	})
 	_, err := rw.Write(response)
 
@@ -175,13 +281,54 @@ func (r *BlockingResolver) apiBlockingStatus(rw http.ResponseWriter, _ *http.Req
 	}
 }
 
+func toListGroups(status []lists.GroupStatus) []api.ListGroup {
+	result := make([]api.ListGroup, 0, len(status))
+
+	optionalTime := func(t time.Time) *time.Time {
+		if t.IsZero() {
+			return nil
+		}
+
+		return &t
+	}
+
+	for _, g := range status {
+		group := api.ListGroup{Group: g.Group, Entries: g.Entries, Sources: make([]api.ListSource, 0, len(g.Sources))}
+
+		for _, s := range g.Sources {
+			source := api.ListSource{
+				Link:               s.Link,
+				Entries:            s.Entries,
+				LastAttempt:        optionalTime(s.LastAttempt),
+				LastSuccess:        optionalTime(s.LastSuccess),
+				LastError:          s.LastError,
+				HTTPStatus:         s.HTTPStatus,
+				DownloadDurationMs: s.Duration.Milliseconds(),
+				Bytes:              s.Bytes,
+			}
+
+			if !s.FallbackDownloaded.IsZero() {
+				source.FallbackAgeInSec = uint(time.Since(s.FallbackDownloaded).Seconds())
+			}
+
+			group.Sources = append(group.Sources, source)
+		}
+
+		result = append(result, group)
+	}
+
+	return result
+}
+
 // apiBlockingDisable is the http endpoint to disable the blocking status
 // @Summary Disable blocking
-// @Description disable the blocking status
+// @Description disable the blocking status or pause the blocking only for a client and/or groups
 // @Tags blocking
 // @Param duration query string false "duration of blocking (Example: 300s, 5m, 1h, 5m30s)" Format(duration)
+// @Param client query string false "client IP, name or MAC address, whose blocking should be paused"
+// @Param groups query string false "comma separated groups, which should be paused"
 // @Success 200   "Blocking is disabled"
-// @Failure 400   "Wrong duration format"
+// @Failure 400   "Wrong duration format or unknown group"
 // @Router /blocking/disable [get]
 func (r *BlockingResolver) apiBlockingDisable(rw http.ResponseWriter, req *http.Request) {
 	var (
@@ -201,7 +348,66 @@ func (r *BlockingResolver) apiBlockingDisable(rw http.ResponseWriter, req *http.
 		}
 	}
 
-	r.status.disableBlocking(duration)
+	scopes, err := r.pauseScopes(req)
+	if err != nil {
+		log.Logger.Error(err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if len(scopes) == 0 {
+		r.status.disableBlocking(duration)
+
+		return
+	}
+
+	for _, scope := range scopes {
+		r.pauses.add(scope, duration)
+	}
+}
+
+// pauseScopes returns the scopes of the query parameters "client" and "groups", empty for the global blocking status
+func (r *BlockingResolver) pauseScopes(req *http.Request) ([]pauseScope, error) {
+	client := strings.ToLower(strings.TrimSpace(req.URL.Query().Get("client")))
+
+	var groups []string
+
+	for _, g := range strings.Split(req.URL.Query().Get("groups"), ",") {
+		if g = strings.TrimSpace(g); len(g) > 0 {
+			_, inBlackLists := r.cfg.BlackLists[g]
+			_, inWhiteLists := r.cfg.WhiteLists[g]
+
+			if !inBlackLists && !inWhiteLists {
+				return nil, fmt.Errorf("group '%s' is not defined", g)
+			}
+
+			groups = append(groups, g)
+		}
+	}
+
+	if len(groups) == 0 {
+		if len(client) == 0 {
+			return nil, nil
+		}
+
+		return []pauseScope{{client: client}}, nil
+	}
+
+	scopes := make([]pauseScope, 0, len(groups))
+	for _, g := range groups {
+		scopes = append(scopes, pauseScope{client: client, group: g})
+	}
+
+	return scopes, nil
+}
+
+// Stop terminates the periodical refresh of black and white lists
+func (r *BlockingResolver) Stop() {
+	r.blacklistMatcher.Stop()
+	r.whitelistMatcher.Stop()
+	r.status.stop()
+	r.pauses.stopAll()
 }
 
 // returns groups, which have only whitelist entries
@@ -219,13 +425,34 @@ func determineWhitelistOnlyGroups(cfg *config.BlockingConfig) (result []string)
 	return
 }
 
-// sets answer and/or return code for DNS response, if request should be blocked
+// handleBlocked creates the response with the block type of the group, which blocked the query. If the client sent
+// EDNS0, the response contains an Extended DNS Error with the group as extra text
 func (r *BlockingResolver) handleBlocked(logger *logrus.Entry,
-	request *Request, question dns.Question, reason string) (*Response, error) {
+	request *Request, question dns.Question, reason string, group string) (*Response, error) {
 	response := new(dns.Msg)
 	response.SetReply(request.Req)
 
-	r.blockHandler.handleBlock(question, response)
+	handler, found := r.groupBlockHandlers[group]
+	if !found {
+		handler = r.blockHandler
+	}
+
+	handler.handleBlock(question, response)
+	r.decisions.add(request.Client.IP, question.Name, group, reason)
+
+	if opt := request.Req.IsEdns0(); opt != nil {
+		infoCode := uint16(edeBlocked)
+		extraText := group
+
+		if len(group) == 0 {
+			// only domains of the white lists are allowed for the client
+			infoCode = edeFiltered
+			extraText = "whitelist only"
+		}
+
+		response.SetEdns0(opt.UDPSize(), opt.Do())
+		response.IsEdns0().Option = append(response.IsEdns0().Option, newEDE(infoCode, extraText))
+	}
 
 	logger.Debugf("blocking request '%s'", reason)
 
@@ -244,8 +471,16 @@ func (r *BlockingResolver) Configuration() (result []string) {
 			result = append(result, fmt.Sprintf("  %s = \"%t\"", key, val))
 		}
 
+		result = append(result, fmt.Sprintf("toggles = \"%s\"", strings.Join(r.toggles.names(), ";")))
+
 		result = append(result, fmt.Sprintf("blockType = \"%s\"", r.cfg.BlockType))
 
+		for _, group := range util.SortedKeys(r.cfg.GroupBlockTypes) {
+			result = append(result, fmt.Sprintf("  %s: blockType = \"%s\"", group, r.cfg.GroupBlockTypes[group]))
+		}
+
+		result = append(result, fmt.Sprintf("matchMode = \"%s\"", r.blacklistMatcher.MatchMode()))
+
 		result = append(result, "blacklist:")
 		for _, c := range r.blacklistMatcher.Configuration() {
 			result = append(result, fmt.Sprintf("  %s", c))
@@ -262,10 +497,6 @@ func (r *BlockingResolver) Configuration() (result []string) {
 	return
 }
 
-func shouldHandle(question dns.Question) bool {
-	return question.Qtype == dns.TypeA || question.Qtype == dns.TypeAAAA
-}
-
 func (r *BlockingResolver) handleBlacklist(groupsToCheck []string,
 	request *Request, logger *logrus.Entry) (*Response, error) {
 
@@ -273,24 +504,21 @@ func (r *BlockingResolver) handleBlacklist(groupsToCheck []string,
 	whitelistOnlyAllowed := reflect.DeepEqual(groupsToCheck, r.whitelistOnlyGroups)
 
 	for _, question := range request.Req.Question {
-		if !shouldHandle(question) {
-			return r.next.Resolve(request)
-		}
-
 		domain := util.ExtractDomain(question)
 		logger := logger.WithField("domain", domain)
 
-		if whitelisted, group := r.matches(groupsToCheck, r.whitelistMatcher, domain); whitelisted {
-			logger.WithField("group", group).Debugf("domain is whitelisted")
+		if whitelisted, group, entry := r.whitelisted(groupsToCheck, domain); whitelisted {
+			logger.WithFields(logrus.Fields{"group": group, "entry": entry}).Debugf("domain is whitelisted")
 			return r.next.Resolve(request)
 		}
 
 		if whitelistOnlyAllowed {
-			return r.handleBlocked(logger, request, question, "BLOCKED (WHITELIST ONLY)")
+			return r.handleBlocked(logger, request, question, "BLOCKED (WHITELIST ONLY)", "")
 		}
 
-		if blocked, group := r.matches(groupsToCheck, r.blacklistMatcher, domain); blocked {
-			return r.handleBlocked(logger, request, question, fmt.Sprintf("BLOCKED (%s)", group))
+		if blocked, group, entry := r.matches(groupsToCheck, r.blacklistMatcher, domain); blocked {
+			return r.handleBlocked(logger.WithField("entry", entry), request, question, fmt.Sprintf("BLOCKED (%s)", group),
+				group)
 		}
 	}
 
@@ -300,8 +528,12 @@ func (r *BlockingResolver) handleBlacklist(groupsToCheck []string,
 func (r *BlockingResolver) Resolve(request *Request) (*Response, error) {
 	logger := withPrefix(request.Log, "blacklist_resolver")
 	groupsToCheck := r.groupsToCheckForClient(request)
+	enabled := r.status.isEnabled()
+
+	// the assigned groups are read by the query log after the request was resolved
+	request.Client.Groups = groupsToCheck
 
-	if r.status.enabled && len(groupsToCheck) > 0 {
+	if enabled && len(groupsToCheck) > 0 {
 		resp, err := r.handleBlacklist(groupsToCheck, request, logger)
 		if resp != nil || err != nil {
 			return resp, err
@@ -310,16 +542,22 @@ func (r *BlockingResolver) Resolve(request *Request) (*Response, error) {
 
 	respFromNext, err := r.next.Resolve(request)
 
-	if err == nil && r.status.enabled && len(groupsToCheck) > 0 && respFromNext.Res != nil {
+	if err == nil && enabled && len(groupsToCheck) > 0 && respFromNext.Res != nil {
 		for _, rr := range respFromNext.Res.Answer {
 			entryToCheck, tName := extractEntryToCheckFromResponse(rr)
 			if len(entryToCheck) > 0 {
 				logger := logger.WithField("response_entry", entryToCheck)
 
-				if whitelisted, group := r.matches(groupsToCheck, r.whitelistMatcher, entryToCheck); whitelisted {
-					logger.WithField("group", group).Debugf("%s is whitelisted", tName)
-				} else if blocked, group := r.matches(groupsToCheck, r.blacklistMatcher, entryToCheck); blocked {
-					return r.handleBlocked(logger, request, request.Req.Question[0], fmt.Sprintf("BLOCKED %s (%s)", tName, group))
+				if whitelisted, group, entry := r.whitelisted(groupsToCheck, entryToCheck); whitelisted {
+					logger.WithFields(logrus.Fields{"group": group, "entry": entry}).Debugf("%s is whitelisted", tName)
+				} else if blocked, group, entry := r.matches(groupsToCheck, r.blacklistMatcher, entryToCheck); blocked {
+					reason := fmt.Sprintf("BLOCKED %s (%s)", tName, group)
+					if _, _, err := net.ParseCIDR(entry); err == nil {
+						// the IP is inside of a blocked range
+						reason = fmt.Sprintf("BLOCKED %s (%s: %s)", tName, group, entry)
+					}
+
+					return r.handleBlocked(logger.WithField("entry", entry), request, request.Req.Question[0], reason, group)
 				}
 			}
 		}
@@ -328,72 +566,178 @@ func (r *BlockingResolver) Resolve(request *Request) (*Response, error) {
 	return respFromNext, err
 }
 
-func extractEntryToCheckFromResponse(rr dns.RR) (entryToCheck string, tName string) {
-	switch v := rr.(type) {
-	case *dns.A:
-		entryToCheck = v.A.String()
-		tName = "IP"
-	case *dns.AAAA:
-		entryToCheck = strings.ToLower(v.AAAA.String())
-		tName = "IP"
-	case *dns.CNAME:
-		entryToCheck = util.ExtractDomainOnly(v.Target)
-		tName = "CNAME"
+// Explain checks the domain against black and white lists of the client's groups like Resolve does.
+// Answers (IP addresses and CNAME targets) of the upstream response are not evaluated
+func (r *BlockingResolver) Explain(request *Request) api.ExplainStep {
+	question := request.Req.Question[0]
+	domain := util.ExtractDomain(question)
+	groupsToCheck := r.groupsToCheckForClient(request)
+
+	step := api.ExplainStep{Result: "not blocked", Groups: groupsToCheck}
+	step.Details = append(step.Details, r.explainClientGroups(request)...)
+
+	if global, ok := r.cfg.Global[domain]; ok {
+		step.Details = append(step.Details, fmt.Sprintf("global entry '%s = %t' overrides list matches", domain, global))
 	}
 
-	return
-}
+	switch {
+	case !r.status.isEnabled():
+		step.Details = append(step.Details, "blocking is disabled")
+		return step
+	case len(groupsToCheck) == 0:
+		step.Details = append(step.Details, "no groups to check for the client")
+		return step
+	}
 
-// returns groups which should be checked for client's request
-func (r *BlockingResolver) groupsToCheckForClient(request *Request) (groups []string) {
-	getEdnsData(request, r.cfg.ClientGroupsBlock, &groups)
+	step.Matches = append(step.Matches, toExplainMatches("whitelist", r.whitelistMatcher.Explain(domain, groupsToCheck))...)
+	step.Matches = append(step.Matches,
+		toExplainMatches("exception", r.blacklistMatcher.ExplainExceptions(domain, groupsToCheck))...)
+
+	if whitelisted, group, _ := r.whitelisted(groupsToCheck, domain); whitelisted {
+		step.Result = fmt.Sprintf("WHITELISTED (%s)", group)
+		step.Decisive = true
+
+		return step
+	}
+
+	if reflect.DeepEqual(groupsToCheck, r.whitelistOnlyGroups) {
+		step.Result = "BLOCKED (WHITELIST ONLY)"
+		step.Decisive = true
+		step.Details = append(step.Details, "all groups of the client have only white lists")
+
+		return step
+	}
+
+	step.Matches = append(step.Matches, toExplainMatches("blacklist", r.blacklistMatcher.Explain(domain, groupsToCheck))...)
 
-	uniqueGroups := buildGroupsMap(groups)
-	toggles := map[string]bool{"adblock": false, "malware": false, "adult": false, "whitelist": false}
-	for k, v := range r.cfg.Global {
-		toggles[k] = v
+	if blocked, group, _ := r.matches(groupsToCheck, r.blacklistMatcher, domain); blocked {
+		step.Result = fmt.Sprintf("BLOCKED (%s)", group)
+		step.Decisive = true
+
+		return step
 	}
 
-	for k, v := range toggles {
-		v2, _ := uniqueGroups[k]
+	step.Details = append(step.Details, "IP addresses and CNAME targets of the upstream response are not evaluated")
+
+	return step
+}
 
-		if v && v2 {
-			toggles[k] = true
-			continue
+// explains how the groups of the client were determined
+func (r *BlockingResolver) explainClientGroups(request *Request) (details []string) {
+	var entries, requested []string
+
+	if mac := request.Client.MAC; len(mac) > 0 {
+		key, groups, found := r.clientGroups.byMAC(mac)
+		entries = append(entries, groups...)
+		requested = append(requested, groups...)
+
+		switch {
+		case !found:
+			details = append(details, fmt.Sprintf("client MAC %s has no groups", mac))
+		case key != mac:
+			details = append(details, fmt.Sprintf("client MAC %s matches '%s' and requests groups: %s", mac, key,
+				strings.Join(groups, ", ")))
+		default:
+			details = append(details, fmt.Sprintf("client MAC %s requests groups: %s", mac, strings.Join(groups, ", ")))
 		}
-		toggles[k] = false
-		continue
 	}
 
-	groups = []string{}
-	for k, v := range toggles {
-		if v {
-			groups = append(groups, k)
+	if id := request.Client.ID; len(id) > 0 {
+		if groups, found := r.clientGroups.byName(id); found {
+			entries = append(entries, groups...)
+			requested = append(requested, groups...)
+			details = append(details, fmt.Sprintf("client ID '%s' requests groups: %s", id, strings.Join(groups, ", ")))
+		} else {
+			details = append(details, fmt.Sprintf("client ID '%s' has no groups", id))
 		}
+	}
 
+	if len(request.Client.MAC) > 0 || len(request.Client.ID) > 0 {
+		details = append(details, r.toggles.describe(request.Client.ids(), buildGroupsMap(r.activeGroups(requested)))...)
 	}
 
-	for _, cName := range request.ClientNames {
-		groupsByName, found := r.cfg.ClientGroupsBlock[cName]
-		if found {
-			groups = append(groups, groupsByName...)
+	for _, name := range request.Client.Names {
+		if groups, found := r.clientGroups.byName(name); found {
+			entries = append(entries, groups...)
+			details = append(details, fmt.Sprintf("client name '%s' has groups: %s", name, strings.Join(groups, ", ")))
 		}
 	}
 
-	// try IP
-	groupsByIP, found := r.cfg.ClientGroupsBlock[request.ClientIP.String()]
+	if key, groups, found := r.clientGroups.byIP(request.Client.IP); found {
+		entries = append(entries, groups...)
 
-	if found {
-		groups = append(groups, groupsByIP...)
+		if key != request.Client.IP.String() {
+			details = append(details, fmt.Sprintf("client IP %s matches '%s' with groups: %s", request.Client.IP, key,
+				strings.Join(groups, ", ")))
+		} else {
+			details = append(details, fmt.Sprintf("client IP %s has groups: %s", request.Client.IP, strings.Join(groups, ", ")))
+		}
 	}
 
-	if len(groups) == 0 {
-		if !found {
-			// return default
-			groups = r.cfg.ClientGroupsBlock["default"]
+	details = append(details, r.pauses.describe(request.Client.ids())...)
+
+	now := time.Now()
+	explained := make(map[string]bool)
+
+	for _, entry := range append(entries, r.cfg.ClientGroupsBlock["default"]...) {
+		if _, name := config.SplitGroupSchedule(entry); len(name) > 0 && !explained[name] {
+			explained[name] = true
+			details = append(details, fmt.Sprintf("schedule '%s' is active: %t", name, r.scheduleActive(name, now)))
 		}
 	}
 
+	return details
+}
+
+func toExplainMatches(list string, results []lists.MatchResult) []api.ExplainMatch {
+	matches := make([]api.ExplainMatch, 0, len(results))
+
+	for _, res := range results {
+		matches = append(matches, api.ExplainMatch{
+			List:    list,
+			Group:   res.Group,
+			Matched: res.Found,
+			Entry:   res.Entry,
+			Source:  res.Source,
+			Line:    res.Line,
+		})
+	}
+
+	return matches
+}
+
+func extractEntryToCheckFromResponse(rr dns.RR) (entryToCheck string, tName string) {
+	switch v := rr.(type) {
+	case *dns.A:
+		entryToCheck = v.A.String()
+		tName = "IP"
+	case *dns.AAAA:
+		entryToCheck = strings.ToLower(v.AAAA.String())
+		tName = "IP"
+	case *dns.CNAME:
+		entryToCheck = util.ExtractDomainOnly(v.Target)
+		tName = "CNAME"
+	}
+
+	return
+}
+
+// returns groups which should be checked for client's request
+func (r *BlockingResolver) groupsToCheckForClient(request *Request) (groups []string) {
+	deviceGroups, clientGroups, ipFound := r.clientGroups.byClient(&request.Client)
+
+	// groups with a schedule are only checked inside of the schedule's time windows,
+	// groups requested by the client's MAC address or client ID are checked only if they are toggled on
+	groups = r.toggles.filter(request.Client.ids(), r.activeGroups(deviceGroups))
+
+	if len(groups) == 0 && len(clientGroups) == 0 && !ipFound {
+		// return default
+		clientGroups = r.cfg.ClientGroupsBlock["default"]
+	}
+
+	groups = append(groups, r.activeGroups(clientGroups)...)
+	groups = r.pauses.filter(request.Client.ids(), groups)
+
 	// if whitelist is the only group.
 	// remove it
 	if len(groups) == 1 {
@@ -408,24 +752,53 @@ func (r *BlockingResolver) groupsToCheckForClient(request *Request) (groups []st
 }
 
 func (r *BlockingResolver) matches(groupsToCheck []string, m lists.Matcher,
-	domain string) (blocked bool, group string) {
+	domain string) (blocked bool, group string, entry string) {
 	if len(groupsToCheck) > 0 {
-		found, group := m.Match(domain, groupsToCheck)
+		found, group, entry := m.Match(domain, groupsToCheck)
 		global, ok := r.cfg.Global[domain]
 		if !ok {
 			global = found
 		}
 
 		if found && global {
-			return true, group
+			return true, group, entry
 		}
 	}
 
-	return false, ""
+	return false, "", ""
+}
+
+// checks the domain against the white lists and the exception rules of the black lists
+func (r *BlockingResolver) whitelisted(groupsToCheck []string, domain string) (found bool, group string, entry string) {
+	if found, group, entry := r.matches(groupsToCheck, r.whitelistMatcher, domain); found {
+		return true, group, entry
+	}
+
+	return r.matches(groupsToCheck, r.blacklistMatcher.Exceptions(), domain)
 }
 
 const blockTTL = 6 * 60 * 60
 
+const (
+	// option code of Extended DNS Errors (RFC 8914)
+	ednsOptionCodeEDE = 15
+	// info code "Blocked": the domain is on a block list of the operator
+	edeBlocked = 15
+	// info code "Filtered": the domain is not allowed for the client
+	edeFiltered = 17
+)
+
+// newEDE creates an Extended DNS Error option. The used dns library version has no type for this option,
+// EDNS0_LOCAL produces the same wire format
+func newEDE(infoCode uint16, extraText string) *dns.EDNS0_LOCAL {
+	data := make([]byte, 2, 2+len(extraText))
+	binary.BigEndian.PutUint16(data, infoCode)
+
+	return &dns.EDNS0_LOCAL{Code: ednsOptionCodeEDE, Data: append(data, extraText...)}
+}
+
+// blockHandler creates the response for a blocked query. Blocked domains are blocked for all query types, types
+// without an address (MX, TXT, HTTPS, ...) get an empty answer (NODATA), unless the handler returns NXDOMAIN
 type blockHandler interface {
 	handleBlock(question dns.Question, response *dns.Msg)
 }
@@ -445,10 +818,13 @@ func (b zeroIPBlockHandler) handleBlock(question dns.Question, response *dns.Msg
 	var zeroIP net.IP
 
 	switch question.Qtype {
+	case dns.TypeA:
+		zeroIP = net.IPv4zero
 	case dns.TypeAAAA:
 		zeroIP = net.IPv6zero
 	default:
-		zeroIP = net.IPv4zero
+		// NODATA: the domain exists, but has no records of the type
+		return
 	}
 
 	rr, _ := util.CreateAnswerFromQuestion(question, zeroIP, blockTTL)
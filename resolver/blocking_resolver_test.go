@@ -10,8 +10,14 @@ import (
 	"github.com/privacyherodev/ph-blocky/util"
 
 	"encoding/json"
+	"io/ioutil"
+	"net"
 	"net/http"
+	"net/http/httptest"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/go-chi/chi"
@@ -46,6 +52,8 @@ var _ = Describe("BlockingResolver", func() {
 			`blocked3.com
 123.145.123.145
 2001:db8:85a3:08d3::370:7344
+10.20.0.0/16
+2001:db9::/32
 badcnamedomain.com`)
 	})
 
@@ -217,6 +225,60 @@ badcnamedomain.com`)
 				Expect(resp.Res.Answer).Should(BeDNSRecord("blocked3.com.", dns.TypeA, 21600, "0.0.0.0"))
 			})
 		})
+		When("match mode is 'subdomains'", func() {
+			BeforeEach(func() {
+				sutConfig.MatchMode = "subdomains"
+			})
+			It("should block sub domains of black list entries", func() {
+				resp, err = sut.Resolve(newRequestWithClient("ads.blocked3.com.", dns.TypeA, "1.2.1.2", "unknown"))
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"))
+				Expect(resp.Res.Answer).Should(BeDNSRecord("ads.blocked3.com.", dns.TypeA, 21600, "0.0.0.0"))
+			})
+		})
+		When("request is not A or AAAA and domain is on the black list", func() {
+			BeforeEach(func() {
+				sutConfig = config.BlockingConfig{
+					BlackLists: map[string][]string{"gr1": {group1File.Name()}},
+					ClientGroupsBlock: map[string][]string{
+						"default": {"gr1"},
+					},
+				}
+			})
+			It("should block the request instead of delegating it", func() {
+				resp, err = sut.Resolve(newRequestWithClient("domain1.com.", dns.TypeMX, "1.2.1.2", "unknown"))
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (gr1)"))
+				Expect(resp.Res.Answer).Should(BeEmpty())
+				Expect(m.Calls).Should(BeEmpty())
+			})
+		})
+		When("query type has no address", func() {
+			BeforeEach(func() {
+				sutConfig = config.BlockingConfig{
+					BlackLists: map[string][]string{
+						"defaultGroup": {defaultGroupFile.Name()},
+					},
+					ClientGroupsBlock: map[string][]string{
+						"default": {"defaultGroup"},
+					},
+					BlockType: "ZeroIP",
+				}
+			})
+
+			It("should return NODATA for each blocked query type", func() {
+				// HTTPS (65) and SVCB (64) are not known by the used dns library version
+				for _, qType := range []uint16{65, 64, dns.TypeMX, dns.TypeTXT, dns.TypeSRV, dns.TypeCNAME} {
+					resp, err = sut.Resolve(newRequestWithClient("blocked3.com.", qType, "1.2.1.2", "unknown"))
+
+					Expect(err).Should(Succeed())
+					Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"), "query type %d", qType)
+					Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess), "query type %d", qType)
+					Expect(resp.Res.Answer).Should(BeEmpty(), "query type %d", qType)
+				}
+			})
+		})
+
 		When("BlockType is NxDomain", func() {
 			BeforeEach(func() {
 				sutConfig = config.BlockingConfig{
@@ -239,6 +301,14 @@ badcnamedomain.com`)
 				Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"))
 				Expect(resp.Res.Rcode).Should(Equal(dns.RcodeNameError))
 			})
+
+			It("should return NXDOMAIN for query types without address", func() {
+				resp, err = sut.Resolve(newRequestWithClient("blocked3.com.", 65, "1.2.1.2", "unknown"))
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"))
+				Expect(resp.Res.Rcode).Should(Equal(dns.RcodeNameError))
+				Expect(resp.Res.Answer).Should(BeEmpty())
+			})
 		})
 
 		When("BlockType is custom IP", func() {
@@ -267,6 +337,14 @@ badcnamedomain.com`)
 				Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"))
 				Expect(resp.Res.Answer).Should(BeDNSRecord("blocked3.com.", dns.TypeAAAA, 21600, "2001:db8:85a3::8a2e:370:7334"))
 			})
+
+			It("should return NODATA for MX query if query is blocked", func() {
+				resp, err = sut.Resolve(newRequestWithClient("blocked3.com.", dns.TypeMX, "1.2.1.2", "unknown"))
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"))
+				Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
+				Expect(resp.Res.Answer).Should(BeEmpty())
+			})
 		})
 
 		When("BlockType is custom IP only for ipv4", func() {
@@ -291,6 +369,61 @@ badcnamedomain.com`)
 
 		})
 
+		When("block type is defined for a group", func() {
+			BeforeEach(func() {
+				sutConfig.BlockType = "ZeroIP"
+				sutConfig.GroupBlockTypes = map[string]string{
+					"gr1": "12.12.12.12",
+					"gr2": "NxDomain",
+				}
+			})
+
+			It("should use the block type of the group, which blocked the query", func() {
+				resp, err = sut.Resolve(newRequestWithClient("domain1.com.", dns.TypeA, "1.2.1.2", "client1"))
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (gr1)"))
+				Expect(resp.Res.Answer).Should(BeDNSRecord("domain1.com.", dns.TypeA, 21600, "12.12.12.12"))
+			})
+
+			It("should return NXDOMAIN for the group with block type NxDomain", func() {
+				expectedReturnCode = dns.RcodeNameError
+
+				resp, err = sut.Resolve(newRequestWithClient("blocked2.com.", dns.TypeA, "1.2.1.2", "altName"))
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (gr2)"))
+				Expect(resp.Res.Answer).Should(BeEmpty())
+			})
+
+			It("should use the global block type for other groups", func() {
+				resp, err = sut.Resolve(newRequestWithClient("blocked3.com.", dns.TypeA, "1.2.1.2", "unknown"))
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"))
+				Expect(resp.Res.Answer).Should(BeDNSRecord("blocked3.com.", dns.TypeA, 21600, "0.0.0.0"))
+			})
+		})
+
+		When("client sent EDNS0", func() {
+			It("should add an extended DNS error with the group to the response", func() {
+				req := newRequestWithClient("blocked3.com.", dns.TypeA, "1.2.1.2", "unknown")
+				req.Req.SetEdns0(4096, false)
+
+				resp, err = sut.Resolve(req)
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"))
+				Expect(resp.Res.IsEdns0()).ShouldNot(BeNil())
+				Expect(resp.Res.IsEdns0().UDPSize()).Should(BeNumerically("==", 4096))
+				Expect(resp.Res.IsEdns0().Option).Should(ConsistOf(
+					&dns.EDNS0_LOCAL{Code: 15, Data: append([]byte{0, 15}, "defaultGroup"...)}))
+			})
+
+			It("should not add EDNS0 to the response if the client did not send it", func() {
+				resp, err = sut.Resolve(newRequestWithClient("blocked3.com.", dns.TypeA, "1.2.1.2", "unknown"))
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (defaultGroup)"))
+				Expect(resp.Res.IsEdns0()).Should(BeNil())
+			})
+		})
+
 		When("Blacklist contains IP", func() {
 			When("IP4", func() {
 				BeforeEach(func() {
@@ -316,6 +449,29 @@ badcnamedomain.com`)
 			})
 		})
 
+		When("Blacklist contains IP range", func() {
+			When("IP4 inside of the range", func() {
+				BeforeEach(func() {
+					mockAnswer, _ = util.NewMsgWithAnswer("example.com.", 300, dns.TypeA, "10.20.30.40")
+				})
+				It("should block query with the matching range in the reason", func() {
+					resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeA, "1.2.1.2", "unknown"))
+					Expect(resp.Reason).Should(Equal("BLOCKED IP (defaultGroup: 10.20.0.0/16)"))
+					Expect(resp.Res.Answer).Should(BeDNSRecord("example.com.", dns.TypeA, 21600, "0.0.0.0"))
+				})
+			})
+			When("IP6 inside of the range", func() {
+				BeforeEach(func() {
+					mockAnswer, _ = util.NewMsgWithAnswer("example.com.", 300, dns.TypeAAAA, "2001:db9:1::1")
+				})
+				It("should block query with the matching range in the reason", func() {
+					resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeAAAA, "1.2.1.2", "unknown"))
+					Expect(resp.Reason).Should(Equal("BLOCKED IP (defaultGroup: 2001:db9::/32)"))
+					Expect(resp.Res.Answer).Should(BeDNSRecord("example.com.", dns.TypeAAAA, 21600, "::"))
+				})
+			})
+		})
+
 		When("blacklist contains domain which is CNAME in response", func() {
 			BeforeEach(func() {
 				// reconfigure mock, to return CNAMEs
@@ -333,6 +489,80 @@ badcnamedomain.com`)
 		})
 	})
 
+	Describe("IP ranges and MAC address prefixes as client keys", func() {
+		BeforeEach(func() {
+			sutConfig = config.BlockingConfig{
+				BlackLists: map[string][]string{
+					"gr1": {group1File.Name()},
+					"gr2": {group2File.Name()},
+				},
+				ClientGroupsBlock: map[string][]string{
+					"default":           {"gr1"},
+					"192.168.0.0/16":    {"gr1"},
+					"192.168.10.0/24":   {"gr2"},
+					"192.168.10.5":      {"gr1", "gr2"},
+					"2001:db8:1:2::/64": {"gr2"},
+					"48:52:*":           {"gr1"},
+					"48:52:4a:*":        {"gr2"},
+					"48:52:4a:00:00:01": {"gr1", "gr2"},
+					"kids-tablet":       {"gr2", "gr3"},
+					"Phone-A":           {"gr2"},
+				},
+				Toggles: []string{"gr1", "gr2"},
+				Global:  map[string]bool{"gr1": true, "gr2": true},
+			}
+		})
+		JustBeforeEach(func() {
+			resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeA, "1.2.1.2", "unknown"))
+		})
+		It("should use the groups of the longest matching IP range", func() {
+			Expect(sut.groupsToCheckForClient(newRequestWithClient("example.com.", dns.TypeA,
+				"192.168.20.1"))).Should(Equal([]string{"gr1"}))
+			Expect(sut.groupsToCheckForClient(newRequestWithClient("example.com.", dns.TypeA,
+				"192.168.10.1"))).Should(Equal([]string{"gr2"}))
+			Expect(sut.groupsToCheckForClient(newRequestWithClient("example.com.", dns.TypeA,
+				"2001:db8:1:2::53"))).Should(Equal([]string{"gr2"}))
+		})
+		It("should prefer the exact IP address", func() {
+			Expect(sut.groupsToCheckForClient(newRequestWithClient("example.com.", dns.TypeA,
+				"192.168.10.5"))).Should(Equal([]string{"gr1", "gr2"}))
+		})
+		It("should use the default groups if no key matches", func() {
+			Expect(sut.groupsToCheckForClient(newRequestWithClient("example.com.", dns.TypeA,
+				"2001:db8:1:3::53"))).Should(Equal([]string{"gr1"}))
+		})
+		It("should use the groups of the longest matching MAC address prefix", func() {
+			Expect(sut.groupsToCheckForClient(newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "10.0.0.1",
+				[]byte{0x48, 0x52, 0x11, 0, 0, 1}))).Should(Equal([]string{"gr1"}))
+			Expect(sut.groupsToCheckForClient(newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "10.0.0.1",
+				[]byte{0x48, 0x52, 0x4a, 0, 0, 2}))).Should(Equal([]string{"gr2"}))
+			Expect(sut.groupsToCheckForClient(newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "10.0.0.1",
+				[]byte{0x48, 0x52, 0x4a, 0, 0, 1}))).Should(Equal([]string{"gr1", "gr2"}))
+		})
+		It("should use the toggled groups of the client ID like the groups of a MAC address", func() {
+			request := newRequestWithClient("example.com.", dns.TypeA, "10.0.0.1")
+			request.Client.ID = "kids-tablet"
+
+			Expect(sut.groupsToCheckForClient(request)).Should(Equal([]string{"gr2"}))
+		})
+		It("should match keys with upper case letters in lower case", func() {
+			request := newRequestWithClient("example.com.", dns.TypeA, "10.0.0.1")
+			request.Client.ID = "phone-a"
+
+			Expect(sut.groupsToCheckForClient(request)).Should(Equal([]string{"gr2"}))
+		})
+		It("should block with the groups of the IP range", func() {
+			resp, err = sut.Resolve(newRequestWithClient("blocked2.com.", dns.TypeA, "192.168.10.1", "unknown"))
+
+			Expect(resp.Reason).Should(Equal("BLOCKED (gr2)"))
+		})
+		It("should explain the matching key", func() {
+			step := sut.Explain(newRequestWithClient("blocked2.com.", dns.TypeA, "192.168.10.1"))
+
+			Expect(step.Details).Should(ContainElement("client IP 192.168.10.1 matches '192.168.10.0/24' with groups: gr2"))
+		})
+	})
+
 	Describe("Whitelisting", func() {
 		When("Requested domain is on black and white list", func() {
 			BeforeEach(func() {
@@ -352,6 +582,35 @@ badcnamedomain.com`)
 			})
 		})
 
+		When("AdBlock formatted black list contains an exception rule", func() {
+			var adblockFile *os.File
+			BeforeEach(func() {
+				adblockFile = TempFile("||example.com^\n@@||good.example.com^")
+				sutConfig = config.BlockingConfig{
+					BlackLists: map[string][]string{"gr1": {adblockFile.Name()}},
+					ClientGroupsBlock: map[string][]string{
+						"default": {"gr1"},
+					},
+				}
+			})
+			AfterEach(func() {
+				_ = os.Remove(adblockFile.Name())
+			})
+			It("should block sub domains of the rule, but not the domain of the exception rule", func() {
+				By("querying sub domain of the blocking rule", func() {
+					resp, err = sut.Resolve(newRequestWithClient("ads.example.com.", dns.TypeA, "1.2.1.2", "unknown"))
+					Expect(resp.Reason).Should(Equal("BLOCKED (gr1)"))
+					Expect(m.Calls).Should(BeEmpty())
+				})
+
+				By("querying domain of the exception rule", func() {
+					resp, err = sut.Resolve(newRequestWithClient("good.example.com.", dns.TypeA, "1.2.1.2", "unknown"))
+					Expect(resp.RType).Should(Equal(RESOLVED))
+					Expect(m.Calls).Should(HaveLen(1))
+				})
+			})
+		})
+
 		When("Only whitelist is defined", func() {
 			BeforeEach(func() {
 				sutConfig = config.BlockingConfig{
@@ -375,6 +634,26 @@ badcnamedomain.com`)
 					Expect(resp.Reason).Should(Equal("BLOCKED (WHITELIST ONLY)"))
 				})
 			})
+			It("should block all query types of domains, which are not on the white list", func() {
+				for _, qType := range []uint16{dns.TypePTR, dns.TypeSOA, dns.TypeMX, 65} {
+					resp, err = sut.Resolve(newRequestWithClient("google.com.", qType, "1.2.1.2", "unknown"))
+
+					Expect(resp.Reason).Should(Equal("BLOCKED (WHITELIST ONLY)"), "query type %d", qType)
+					Expect(resp.Res.Answer).Should(BeEmpty(), "query type %d", qType)
+				}
+
+				Expect(m.Calls).Should(BeEmpty())
+			})
+			It("should add the extended DNS error 'Filtered' to the response", func() {
+				req := newRequestWithClient("google.com.", dns.TypeA, "1.2.1.2", "unknown")
+				req.Req.SetEdns0(4096, false)
+
+				resp, err = sut.Resolve(req)
+
+				Expect(resp.Reason).Should(Equal("BLOCKED (WHITELIST ONLY)"))
+				Expect(resp.Res.IsEdns0().Option).Should(ConsistOf(
+					&dns.EDNS0_LOCAL{Code: 15, Data: append([]byte{0, 17}, "whitelist only"...)}))
+			})
 		})
 
 		When("IP address is on black and white list", func() {
@@ -417,9 +696,9 @@ badcnamedomain.com`)
 				resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeA, "1.2.1.2", "unknown"))
 			})
 		})
-		When("request is not A or AAAA", func() {
+		When("domain is not blocked and request has a non-address type", func() {
 			It("should delegate to next resolver", func() {
-				resp, err = sut.Resolve(newRequestWithClient("domain1.com.", dns.TypeMX, "1.2.1.2", "unknown"))
+				resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeMX, "1.2.1.2", "unknown"))
 			})
 		})
 		When("no lists defined", func() {
@@ -432,6 +711,296 @@ badcnamedomain.com`)
 		})
 	})
 
+	Describe("Schedules", func() {
+		BeforeEach(func() {
+			sutConfig = config.BlockingConfig{
+				BlackLists: map[string][]string{
+					"gr1": {group1File.Name()},
+					"gr2": {group2File.Name()},
+				},
+				ClientGroupsBlock: map[string][]string{
+					"default": {"gr1@always", "gr2@never"},
+					"client1": {"gr2@never"},
+				},
+				Schedules: map[string]config.Schedule{
+					"always": {TimeZone: "UTC", Windows: []config.ScheduleWindow{{From: "00:00", To: "24:00"}}},
+					"never":  {TimeZone: "UTC"},
+				},
+			}
+		})
+		It("should check only groups with active schedule", func() {
+			resp, err = sut.Resolve(newRequestWithClient("domain1.com.", dns.TypeA, "1.2.1.2", "unknown"))
+			Expect(resp.Reason).Should(Equal("BLOCKED (gr1)"))
+
+			resp, err = sut.Resolve(newRequestWithClient("blocked2.com.", dns.TypeA, "1.2.1.2", "unknown"))
+			Expect(resp.RType).Should(Equal(RESOLVED))
+		})
+		It("should not check the default groups for clients whose groups are outside of their schedule", func() {
+			resp, err = sut.Resolve(newRequestWithClient("domain1.com.", dns.TypeA, "1.2.1.2", "client1"))
+			Expect(resp.RType).Should(Equal(RESOLVED))
+		})
+		It("should explain the state of the schedules", func() {
+			step := sut.Explain(newRequestWithClient("blocked2.com.", dns.TypeA, "1.2.1.2", "unknown"))
+
+			Expect(step.Groups).Should(Equal([]string{"gr1"}))
+			Expect(step.Details).Should(ContainElement("schedule 'never' is active: false"))
+			Expect(step.Details).Should(ContainElement("schedule 'always' is active: true"))
+		})
+		It("should return the schedules via API", func() {
+			httpCode, body := DoGetRequest(api.SchedulesPath, sut.apiSchedules)
+			Expect(httpCode).Should(Equal(http.StatusOK))
+
+			var result []api.ScheduleStatus
+			Expect(json.Unmarshal(body.Bytes(), &result)).Should(Succeed())
+
+			Expect(result).Should(Equal([]api.ScheduleStatus{
+				{Name: "always", TimeZone: "UTC", Active: true, UsedBy: []string{"default: gr1"}},
+				{Name: "never", TimeZone: "UTC", Active: false, UsedBy: []string{"client1: gr2", "default: gr2"}},
+			}))
+		})
+	})
+
+	Describe("Explain", func() {
+		var listFile *os.File
+		BeforeEach(func() {
+			listFile = TempFile("||example.com^\n@@||good.example.com^\n/^ad[0-9]+\\./")
+			sutConfig = config.BlockingConfig{
+				BlackLists: map[string][]string{
+					"gr1": {listFile.Name()},
+					"gr2": {group2File.Name()},
+				},
+				WhiteLists: map[string][]string{
+					"gr2": {group1File.Name()},
+				},
+				ClientGroupsBlock: map[string][]string{
+					"client1": {"gr1", "gr2"},
+				},
+			}
+		})
+		AfterEach(func() {
+			_ = os.Remove(listFile.Name())
+		})
+		When("domain is on the black list", func() {
+			It("should return the group, entry, source and line like Resolve blocks it", func() {
+				resp, err = sut.Resolve(newRequestWithClient("ads.example.com.", dns.TypeA, "1.2.1.2", "client1"))
+				Expect(resp.Reason).Should(Equal("BLOCKED (gr1)"))
+
+				step := sut.Explain(newRequestWithClient("ads.example.com.", dns.TypeA, "1.2.1.2", "client1"))
+				Expect(step.Decisive).Should(BeTrue())
+				Expect(step.Result).Should(Equal(resp.Reason))
+				Expect(step.Groups).Should(Equal([]string{"gr1", "gr2"}))
+				Expect(step.Details).Should(ContainElement("client name 'client1' has groups: gr1, gr2"))
+				Expect(step.Matches).Should(ContainElement(api.ExplainMatch{
+					List: "blacklist", Group: "gr1", Matched: true, Entry: "*.example.com", Source: listFile.Name(), Line: 1,
+				}))
+				Expect(step.Matches).Should(ContainElement(api.ExplainMatch{List: "blacklist", Group: "gr2"}))
+			})
+		})
+		When("domain matches a regular expression", func() {
+			It("should return the expression with its line", func() {
+				resp, err = sut.Resolve(newRequestWithClient("ad1.test.com.", dns.TypeA, "1.2.1.2", "client1"))
+
+				step := sut.Explain(newRequestWithClient("ad1.test.com.", dns.TypeA, "1.2.1.2", "client1"))
+				Expect(step.Result).Should(Equal("BLOCKED (gr1)"))
+				Expect(step.Matches).Should(ContainElement(api.ExplainMatch{
+					List: "blacklist", Group: "gr1", Matched: true, Entry: `/^ad[0-9]+\./`, Source: listFile.Name(), Line: 3,
+				}))
+			})
+		})
+		When("domain is on the white list or matches an exception rule", func() {
+			It("should return the whitelist decision", func() {
+				resp, err = sut.Resolve(newRequestWithClient("good.example.com.", dns.TypeA, "1.2.1.2", "client1"))
+				Expect(resp.RType).Should(Equal(RESOLVED))
+
+				step := sut.Explain(newRequestWithClient("good.example.com.", dns.TypeA, "1.2.1.2", "client1"))
+				Expect(step.Decisive).Should(BeTrue())
+				Expect(step.Result).Should(Equal("WHITELISTED (gr1)"))
+				Expect(step.Matches).Should(ContainElement(api.ExplainMatch{
+					List: "exception", Group: "gr1", Matched: true, Entry: "@@good.example.com", Source: listFile.Name(), Line: 2,
+				}))
+
+				step = sut.Explain(newRequestWithClient("domain1.com.", dns.TypeA, "1.2.1.2", "client1"))
+				Expect(step.Result).Should(Equal("WHITELISTED (gr2)"))
+			})
+		})
+		When("domain is not on any list", func() {
+			It("should not be decisive", func() {
+				resp, err = sut.Resolve(newRequestWithClient("google.com.", dns.TypeA, "1.2.1.2", "client1"))
+
+				step := sut.Explain(newRequestWithClient("google.com.", dns.TypeA, "1.2.1.2", "client1"))
+				Expect(step.Decisive).Should(BeFalse())
+				Expect(step.Result).Should(Equal("not blocked"))
+				Expect(step.Matches).Should(HaveLen(6))
+			})
+		})
+		When("blocking is disabled", func() {
+			It("should not be decisive", func() {
+				sut.status.disableBlocking(0)
+				resp, err = sut.Resolve(newRequestWithClient("ads.example.com.", dns.TypeA, "1.2.1.2", "client1"))
+
+				step := sut.Explain(newRequestWithClient("ads.example.com.", dns.TypeA, "1.2.1.2", "client1"))
+				Expect(step.Decisive).Should(BeFalse())
+				Expect(step.Details).Should(ContainElement("blocking is disabled"))
+			})
+		})
+	})
+
+	Describe("List entries via API", func() {
+		var (
+			router      *chi.Mux
+			overlayFile string
+		)
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "overlay")
+			Expect(err).Should(Succeed())
+			overlayFile = filepath.Join(dir, "overlay.json")
+
+			sutConfig = config.BlockingConfig{
+				BlackLists:        map[string][]string{"gr1": {group1File.Name()}},
+				ClientGroupsBlock: map[string][]string{"default": {"gr1"}},
+				OverlayFile:       overlayFile,
+			}
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(filepath.Dir(overlayFile))
+		})
+		JustBeforeEach(func() {
+			router = chi.NewRouter()
+			sut = NewBlockingResolver(router, sutConfig).(*BlockingResolver)
+			sut.Next(m)
+		})
+
+		doRequest := func(method, url, body string) (int, api.ListEntries) {
+			req := httptest.NewRequest(method, url, strings.NewReader(body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			var entries api.ListEntries
+			_ = json.Unmarshal(rr.Body.Bytes(), &entries)
+
+			return rr.Code, entries
+		}
+
+		It("should add and remove entries, which are matched immediately and stored", func() {
+			code, entries := doRequest(http.MethodPost, "/api/lists/blacklist/gr1/entries", `{"Entry": "Added.com"}`)
+			Expect(code).Should(Equal(http.StatusOK))
+			Expect(entries.Entries).Should(Equal([]string{"added.com"}))
+
+			resp, err = sut.Resolve(newRequestWithClient("added.com.", dns.TypeA, "1.2.1.2", "unknown"))
+			Expect(resp.Reason).Should(Equal("BLOCKED (gr1)"))
+
+			By("creating the resolver again, the entry is restored from the file", func() {
+				restored := NewBlockingResolver(chi.NewRouter(), sutConfig).(*BlockingResolver)
+				restored.Next(m)
+
+				resp, err = restored.Resolve(newRequestWithClient("added.com.", dns.TypeA, "1.2.1.2", "unknown"))
+				Expect(resp.Reason).Should(Equal("BLOCKED (gr1)"))
+			})
+
+			code, entries = doRequest(http.MethodGet, "/api/lists/blacklist/gr1/entries", "")
+			Expect(code).Should(Equal(http.StatusOK))
+			Expect(entries.Entries).Should(Equal([]string{"added.com"}))
+
+			code, entries = doRequest(http.MethodDelete, "/api/lists/blacklist/gr1/entries/added.com", "")
+			Expect(code).Should(Equal(http.StatusOK))
+			Expect(entries.Entries).Should(BeEmpty())
+
+			resp, err = sut.Resolve(newRequestWithClient("added.com.", dns.TypeA, "1.2.1.2", "unknown"))
+			Expect(resp.RType).Should(Equal(RESOLVED))
+		})
+		It("should remove IP ranges with escaped slash in the path", func() {
+			code, entries := doRequest(http.MethodPost, "/api/lists/blacklist/gr1/entries", `{"Entry": "10.0.0.0/8"}`)
+			Expect(code).Should(Equal(http.StatusOK))
+			Expect(entries.Entries).Should(Equal([]string{"10.0.0.0/8"}))
+
+			code, entries = doRequest(http.MethodDelete, "/api/lists/blacklist/gr1/entries/"+url.PathEscape("10.0.0.0/8"), "")
+			Expect(code).Should(Equal(http.StatusOK))
+			Expect(entries.Entries).Should(BeEmpty())
+		})
+		It("should reject unknown list types, groups and invalid entries", func() {
+			resp, err = sut.Resolve(newRequestWithClient("added.com.", dns.TypeA, "1.2.1.2", "unknown"))
+
+			code, _ := doRequest(http.MethodPost, "/api/lists/greylist/gr1/entries", `{"Entry": "added.com"}`)
+			Expect(code).Should(Equal(http.StatusBadRequest))
+
+			code, _ = doRequest(http.MethodPost, "/api/lists/whitelist/unknown/entries", `{"Entry": "added.com"}`)
+			Expect(code).Should(Equal(http.StatusBadRequest))
+
+			code, _ = doRequest(http.MethodPost, "/api/lists/whitelist/gr1/entries", `{"Entry": "http://added.com/"}`)
+			Expect(code).Should(Equal(http.StatusBadRequest))
+
+			code, _ = doRequest(http.MethodDelete, "/api/lists/blacklist/gr1/entries/added.com", "")
+			Expect(code).Should(Equal(http.StatusNotFound))
+		})
+		It("should reject groups, which are not defined for the list type", func() {
+			code, _ := doRequest(http.MethodPost, "/api/lists/whitelist/gr1/entries", `{"Entry": "added.com"}`)
+			Expect(code).Should(Equal(http.StatusBadRequest))
+
+			code, _ = doRequest(http.MethodGet, "/api/lists/whitelist/gr1/entries", "")
+			Expect(code).Should(Equal(http.StatusBadRequest))
+
+			code, _ = doRequest(http.MethodDelete, "/api/lists/whitelist/gr1/entries/added.com", "")
+			Expect(code).Should(Equal(http.StatusBadRequest))
+		})
+	})
+
+	Describe("Block decisions and unblock requests", func() {
+		BeforeEach(func() {
+			sutConfig = config.BlockingConfig{
+				BlackLists:        map[string][]string{"gr1": {group1File.Name()}},
+				ClientGroupsBlock: map[string][]string{"default": {"gr1"}},
+			}
+		})
+		JustBeforeEach(func() {
+			// httptest requests are sent from 192.0.2.1
+			resp, err = sut.Resolve(newRequestWithClient("Domain1.com.", dns.TypeA, "192.0.2.1", "unknown"))
+			Expect(resp.Reason).Should(Equal("BLOCKED (gr1)"))
+		})
+
+		It("should return the recent block decision for the client and host", func() {
+			decision, found := sut.BlockDecision(net.ParseIP("192.0.2.1"), "domain1.com")
+			Expect(found).Should(BeTrue())
+			Expect(decision.Host).Should(Equal("domain1.com"))
+			Expect(decision.Group).Should(Equal("gr1"))
+			Expect(decision.Reason).Should(Equal("BLOCKED (gr1)"))
+
+			_, found = sut.BlockDecision(net.ParseIP("192.0.2.2"), "domain1.com")
+			Expect(found).Should(BeFalse())
+		})
+
+		It("should store unblock requests with the block decision", func() {
+			router := chi.NewRouter()
+			sut = NewBlockingResolver(router, sutConfig).(*BlockingResolver)
+			sut.Next(m)
+
+			resp, err = sut.Resolve(newRequestWithClient("domain1.com.", dns.TypeA, "192.0.2.1", "unknown"))
+
+			for _, body := range []string{`{"Host": "domain1.com"}`, `{"host": "DOMAIN1.com."}`, `{"host": "other.com"}`} {
+				rr := httptest.NewRecorder()
+				router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, api.UnblockRequestsPath, strings.NewReader(body)))
+				Expect(rr.Code).Should(Equal(http.StatusOK))
+			}
+
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, api.UnblockRequestsPath, strings.NewReader(`{}`)))
+			Expect(rr.Code).Should(Equal(http.StatusBadRequest))
+
+			rr = httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, api.UnblockRequestsPath, nil))
+			Expect(rr.Code).Should(Equal(http.StatusOK))
+
+			var result []api.UnblockRequestEntry
+			Expect(json.Unmarshal(rr.Body.Bytes(), &result)).Should(Succeed())
+			Expect(result).Should(HaveLen(2))
+			Expect(result[0].Client).Should(Equal("192.0.2.1"))
+			Expect(result[0].Host).Should(Equal("domain1.com"))
+			Expect(result[0].Group).Should(Equal("gr1"))
+			Expect(result[0].Reason).Should(Equal("BLOCKED (gr1)"))
+			Expect(result[1].Host).Should(Equal("other.com"))
+			Expect(result[1].Group).Should(BeEmpty())
+		})
+	})
+
 	Describe("Control status via API", func() {
 		BeforeEach(func() {
 			sutConfig = config.BlockingConfig{
@@ -470,6 +1039,87 @@ badcnamedomain.com`)
 			})
 		})
 
+		When("List status is requested", func() {
+			It("should return the status of each list", func() {
+				resp, err = sut.Resolve(newRequestWithClient("blocked3.com.", dns.TypeA, "1.2.1.2", "unknown"))
+
+				httpCode, body := DoGetRequest(api.ListsPath, sut.apiLists)
+				Expect(httpCode).Should(Equal(http.StatusOK))
+
+				var result api.ListsStatus
+				Expect(json.Unmarshal(body.Bytes(), &result)).Should(Succeed())
+
+				Expect(result.WhiteLists).Should(BeEmpty())
+				Expect(result.BlackLists).Should(HaveLen(1))
+				Expect(result.BlackLists[0].Group).Should(Equal("defaultGroup"))
+				Expect(result.BlackLists[0].Entries).Should(Equal(6))
+				Expect(result.BlackLists[0].Sources).Should(HaveLen(1))
+
+				source := result.BlackLists[0].Sources[0]
+				Expect(source.Link).Should(Equal(defaultGroupFile.Name()))
+				Expect(source.Entries).Should(Equal(6))
+				Expect(source.LastSuccess).ShouldNot(BeNil())
+				Expect(source.LastError).Should(BeEmpty())
+				Expect(source.Bytes).Should(BeNumerically(">", 0))
+			})
+		})
+
+		When("List refresh is requested", func() {
+			var listFile *os.File
+			BeforeEach(func() {
+				listFile = TempFile("refresh1.com")
+				sutConfig = config.BlockingConfig{
+					BlackLists: map[string][]string{
+						"gr1": {listFile.Name()},
+					},
+					ClientGroupsBlock: map[string][]string{
+						"default": {"gr1"},
+					},
+				}
+			})
+			AfterEach(func() {
+				_ = os.Remove(listFile.Name())
+			})
+			It("should refresh the lists in background and report the result", func() {
+				resp, err = sut.Resolve(newRequestWithClient("refresh1.com.", dns.TypeA, "1.2.1.2", "unknown"))
+				Expect(resp.RType).Should(Equal(BLOCKED))
+
+				Expect(ioutil.WriteFile(listFile.Name(), []byte("refresh2.com"), 0600)).Should(Succeed())
+
+				httpCode, body := DoGetRequest(api.ListsRefreshPath+"?type=blacklist&group=gr1", sut.apiListsRefresh)
+				Expect(httpCode).Should(Equal(http.StatusAccepted))
+
+				var status api.ListsRefreshStatus
+				Expect(json.Unmarshal(body.Bytes(), &status)).Should(Succeed())
+				Expect(status.GroupsTotal).Should(Equal(1))
+				Expect(status.ListType).Should(Equal("blacklist"))
+				Expect(status.Group).Should(Equal("gr1"))
+
+				Eventually(func() bool {
+					_, body := DoGetRequest(api.ListsRefreshPath, sut.apiListsRefreshStatus)
+					Expect(json.Unmarshal(body.Bytes(), &status)).Should(Succeed())
+
+					return status.Running
+				}).Should(BeFalse())
+
+				Expect(status.GroupsDone).Should(Equal(1))
+				Expect(status.FinishedAt).ShouldNot(BeNil())
+				Expect(status.Errors).Should(BeEmpty())
+
+				resp, err = sut.Resolve(newRequestWithClient("refresh2.com.", dns.TypeA, "1.2.1.2", "unknown"))
+				Expect(resp.RType).Should(Equal(BLOCKED))
+			})
+			It("should reject unknown groups and list types", func() {
+				resp, err = sut.Resolve(newRequestWithClient("refresh1.com.", dns.TypeA, "1.2.1.2", "unknown"))
+
+				httpCode, _ := DoGetRequest(api.ListsRefreshPath+"?group=unknown", sut.apiListsRefresh)
+				Expect(httpCode).Should(Equal(http.StatusBadRequest))
+
+				httpCode, _ = DoGetRequest(api.ListsRefreshPath+"?type=greylist", sut.apiListsRefresh)
+				Expect(httpCode).Should(Equal(http.StatusBadRequest))
+			})
+		})
+
 		When("Disable blocking is called with a wrong parameter", func() {
 			It("Should return http bad request as return code", func() {
 				httpCode, _ := DoGetRequest("/api/blocking/disable?duration=xyz", sut.apiBlockingDisable)
@@ -546,6 +1196,339 @@ badcnamedomain.com`)
 				})
 			})
 		})
+
+		When("Blocking is paused for a client or a group", func() {
+			blocked := func(clientIP string, clientNames ...string) bool {
+				resp, err := sut.Resolve(newRequestWithClient("blocked3.com.", dns.TypeA, clientIP, clientNames...))
+				Expect(err).Should(Succeed())
+
+				return resp.RType == BLOCKED
+			}
+			pauses := func() []api.BlockingPause {
+				_, body := DoGetRequest("/api/blocking/status", sut.apiBlockingStatus)
+
+				var result api.BlockingStatus
+				Expect(json.NewDecoder(body).Decode(&result)).Should(Succeed())
+				Expect(result.Enabled).Should(BeTrue())
+
+				return result.Pauses
+			}
+
+			It("should pause only the blocking of the client until the pause ends", func() {
+				httpCode, _ := DoGetRequest("/api/blocking/disable?client=Laptop&duration=500ms", sut.apiBlockingDisable)
+				Expect(httpCode).Should(Equal(http.StatusOK))
+
+				Expect(blocked("1.2.1.2", "laptop")).Should(BeFalse())
+				Expect(blocked("1.2.1.3", "tablet")).Should(BeTrue())
+				Expect(pauses()).Should(HaveLen(1))
+				Expect(pauses()[0].Client).Should(Equal("laptop"))
+				Expect(pauses()[0].Group).Should(BeEmpty())
+
+				Eventually(func() bool {
+					return blocked("1.2.1.2", "laptop")
+				}, "2s").Should(BeTrue())
+				Expect(pauses()).Should(BeEmpty())
+			})
+
+			It("should pause the group for all clients until it is enabled again", func() {
+				httpCode, _ := DoGetRequest("/api/blocking/disable?groups=defaultGroup", sut.apiBlockingDisable)
+				Expect(httpCode).Should(Equal(http.StatusOK))
+
+				Expect(blocked("1.2.1.2", "laptop")).Should(BeFalse())
+				Expect(blocked("1.2.1.3", "tablet")).Should(BeFalse())
+				Expect(pauses()).Should(Equal([]api.BlockingPause{{Group: "defaultGroup"}}))
+
+				httpCode, _ = DoGetRequest("/api/blocking/enable?groups=defaultGroup", sut.apiBlockingEnable)
+				Expect(httpCode).Should(Equal(http.StatusOK))
+
+				Expect(blocked("1.2.1.2", "laptop")).Should(BeTrue())
+				Expect(pauses()).Should(BeEmpty())
+			})
+
+			It("should pause the group of a client identified by IP", func() {
+				httpCode, _ := DoGetRequest("/api/blocking/disable?client=1.2.1.2&groups=defaultGroup&duration=1m",
+					sut.apiBlockingDisable)
+				Expect(httpCode).Should(Equal(http.StatusOK))
+
+				Expect(blocked("1.2.1.2", "laptop")).Should(BeFalse())
+				Expect(blocked("1.2.1.3", "laptop")).Should(BeTrue())
+
+				result := pauses()
+				Expect(result).Should(HaveLen(1))
+				Expect(result[0].AutoEnableInSec).Should(BeNumerically("~", 60, 1))
+			})
+
+			It("should reject unknown groups and not paused scopes", func() {
+				httpCode, _ := DoGetRequest("/api/blocking/disable?groups=unknown", sut.apiBlockingDisable)
+				Expect(httpCode).Should(Equal(http.StatusBadRequest))
+
+				httpCode, _ = DoGetRequest("/api/blocking/enable?client=laptop", sut.apiBlockingEnable)
+				Expect(httpCode).Should(Equal(http.StatusNotFound))
+
+				Expect(pauses()).Should(BeEmpty())
+			})
+		})
+	})
+
+	Describe("Toggles via API", func() {
+		var (
+			router    *chi.Mux
+			stateFile string
+		)
+
+		// MAC address 48:52:4a requests the group "gambling", which is toggleable:
+		// runtime state of client | runtime global state | configured global state | result for client
+		// ------------------------------------------------------------------------------------------
+		// -                       | -                    | ON                      | ON
+		// -                       | OFF                  | ON                      | OFF
+		// ON                      | OFF                  | ON                      | ON
+		// OFF                     | ON                   | ON                      | OFF
+		mac := []byte{72, 82, 74}
+
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "state")
+			Expect(err).Should(Succeed())
+			stateFile = filepath.Join(dir, "state.json")
+
+			sutConfig = config.BlockingConfig{
+				BlackLists:        map[string][]string{"gambling": {group1File.Name()}},
+				ClientGroupsBlock: map[string][]string{"48:52:4a": {"gambling"}},
+				Toggles:           []string{"gambling"},
+				Global:            map[string]bool{"gambling": true},
+				StateFile:         stateFile,
+			}
+		})
+		JustBeforeEach(func() {
+			router = chi.NewRouter()
+			sut = NewBlockingResolver(router, sutConfig).(*BlockingResolver)
+			sut.Next(m)
+
+			resp, err = sut.Resolve(newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "1.2.1.2", mac))
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(filepath.Dir(stateFile))
+		})
+
+		doRequest := func(method, url, body string) (int, api.TogglesStatus) {
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, httptest.NewRequest(method, url, strings.NewReader(body)))
+
+			var status api.TogglesStatus
+			_ = json.Unmarshal(rr.Body.Bytes(), &status)
+
+			return rr.Code, status
+		}
+
+		blocked := func(ip string) bool {
+			resp, err = sut.Resolve(newRequestWithClientAndEDNS0("domain1.com.", dns.TypeA, ip, mac))
+
+			return resp.RType == BLOCKED
+		}
+
+		It("should use the client state before the global state and the configured state", func() {
+			Expect(blocked("1.2.1.2")).Should(BeTrue())
+
+			code, status := doRequest(http.MethodPost, api.TogglesPath, `{"toggle": "gambling", "enabled": false}`)
+			Expect(code).Should(Equal(http.StatusOK))
+			Expect(status.Global).Should(Equal(map[string]bool{"gambling": false}))
+			Expect(blocked("1.2.1.2")).Should(BeFalse())
+
+			code, status = doRequest(http.MethodPost, api.TogglesPath,
+				`{"toggle": "gambling", "enabled": true, "client": "1.2.1.2"}`)
+			Expect(code).Should(Equal(http.StatusOK))
+			Expect(status.Clients).Should(Equal(map[string]map[string]bool{"1.2.1.2": {"gambling": true}}))
+			Expect(blocked("1.2.1.2")).Should(BeTrue())
+			Expect(blocked("1.2.1.3")).Should(BeFalse())
+
+			By("resetting the global state to the configured one", func() {
+				_, status = doRequest(http.MethodDelete, api.TogglesPath, "")
+				Expect(status.Global).Should(Equal(map[string]bool{"gambling": true}))
+				Expect(blocked("1.2.1.3")).Should(BeTrue())
+			})
+
+			By("switching off the toggle for the MAC address of the client", func() {
+				doRequest(http.MethodPost, api.TogglesPath, `{"toggle": "gambling", "enabled": false, "client": "48:52:4A"}`)
+				Expect(blocked("1.2.1.3")).Should(BeFalse())
+				// the states of the IP address and the MAC address differ, the toggle is off
+				Expect(blocked("1.2.1.2")).Should(BeFalse())
+			})
+
+			By("resetting the client states", func() {
+				doRequest(http.MethodDelete, api.TogglesPath+"?client=48:52:4a", "")
+				_, status = doRequest(http.MethodDelete, api.TogglesPath+"?client=1.2.1.2&toggle=gambling", "")
+				Expect(status.Clients).Should(BeEmpty())
+				Expect(blocked("1.2.1.2")).Should(BeTrue())
+			})
+		})
+
+		It("should reject unknown toggles", func() {
+			code, _ := doRequest(http.MethodPost, api.TogglesPath, `{"toggle": "adblock", "enabled": true}`)
+			Expect(code).Should(Equal(http.StatusBadRequest))
+
+			code, _ = doRequest(http.MethodDelete, api.TogglesPath+"?toggle=adblock", "")
+			Expect(code).Should(Equal(http.StatusBadRequest))
+		})
+
+		It("should restore the toggles from the state file", func() {
+			doRequest(http.MethodPost, api.TogglesPath, `{"toggle": "gambling", "enabled": false}`)
+			doRequest(http.MethodPost, api.TogglesPath, `{"toggle": "gambling", "enabled": true, "client": "Laptop"}`)
+
+			sut.Stop()
+			router = chi.NewRouter()
+			sut = NewBlockingResolver(router, sutConfig).(*BlockingResolver)
+
+			_, status := doRequest(http.MethodGet, api.TogglesPath, "")
+			Expect(status.Global).Should(Equal(map[string]bool{"gambling": false}))
+			Expect(status.Clients).Should(Equal(map[string]map[string]bool{"laptop": {"gambling": true}}))
+		})
+
+		It("should explain the state of the toggles", func() {
+			doRequest(http.MethodPost, api.TogglesPath, `{"toggle": "gambling", "enabled": false}`)
+
+			step := sut.Explain(newRequestWithClientAndEDNS0("domain1.com.", dns.TypeA, "1.2.1.2", mac))
+			Expect(step.Details).Should(ContainElement("toggle 'gambling = false', requested by client: true"))
+		})
+	})
+
+	Describe("Blocking state", func() {
+		var stateFile string
+
+		BeforeEach(func() {
+			dir, err := ioutil.TempDir("", "state")
+			Expect(err).Should(Succeed())
+			stateFile = filepath.Join(dir, "state.json")
+
+			sutConfig = config.BlockingConfig{
+				BlackLists:        map[string][]string{"defaultGroup": {defaultGroupFile.Name()}},
+				ClientGroupsBlock: map[string][]string{"default": {"defaultGroup"}},
+				StateFile:         stateFile,
+			}
+		})
+		JustBeforeEach(func() {
+			resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeA, "1.2.1.2", "unknown"))
+		})
+		AfterEach(func() {
+			_ = os.RemoveAll(filepath.Dir(stateFile))
+		})
+
+		restored := func() (*BlockingResolver, api.BlockingStatus) {
+			sut.Stop()
+
+			restored := NewBlockingResolver(chi.NewRouter(), sutConfig).(*BlockingResolver)
+			_, body := DoGetRequest("/api/blocking/status", restored.apiBlockingStatus)
+
+			var result api.BlockingStatus
+			Expect(json.NewDecoder(body).Decode(&result)).Should(Succeed())
+
+			return restored, result
+		}
+
+		It("should restore disabled blocking with the pending auto enable deadline", func() {
+			httpCode, _ := DoGetRequest("/api/blocking/disable?duration=2h", sut.apiBlockingDisable)
+			Expect(httpCode).Should(Equal(http.StatusOK))
+
+			restored, result := restored()
+			defer restored.Stop()
+
+			Expect(result.Enabled).Should(BeFalse())
+			Expect(result.AutoEnableInSec).Should(BeNumerically("~", 7200, 2))
+		})
+		It("should restore disabled blocking without end and the pauses", func() {
+			DoGetRequest("/api/blocking/disable", sut.apiBlockingDisable)
+			DoGetRequest("/api/blocking/disable?client=laptop&duration=10m", sut.apiBlockingDisable)
+			DoGetRequest("/api/blocking/disable?groups=defaultGroup", sut.apiBlockingDisable)
+
+			restored, result := restored()
+			defer restored.Stop()
+
+			Expect(result.Enabled).Should(BeFalse())
+			Expect(result.AutoEnableInSec).Should(BeZero())
+			Expect(result.Pauses).Should(HaveLen(2))
+			Expect(result.Pauses[0].Group).Should(Equal("defaultGroup"))
+			Expect(result.Pauses[1].Client).Should(Equal("laptop"))
+			Expect(result.Pauses[1].AutoEnableInSec).Should(BeNumerically("~", 600, 2))
+		})
+		It("should enable blocking, if the deadline expired or blocking was enabled again", func() {
+			DoGetRequest("/api/blocking/disable?duration=100ms&client=laptop", sut.apiBlockingDisable)
+			DoGetRequest("/api/blocking/disable?duration=1h", sut.apiBlockingDisable)
+			DoGetRequest("/api/blocking/enable", sut.apiBlockingEnable)
+
+			time.Sleep(200 * time.Millisecond)
+
+			restored, result := restored()
+			defer restored.Stop()
+
+			Expect(result.Enabled).Should(BeTrue())
+			Expect(result.Pauses).Should(BeEmpty())
+		})
+		It("should take over the state of a replaced resolver without state file", func() {
+			DoGetRequest("/api/blocking/disable?duration=2h", sut.apiBlockingDisable)
+			DoGetRequest("/api/blocking/disable?client=laptop", sut.apiBlockingDisable)
+
+			sutConfig.StateFile = ""
+			replacement := NewBlockingResolver(chi.NewRouter(), sutConfig).(*BlockingResolver)
+			defer replacement.Stop()
+
+			replacement.TakeOverState(sut)
+
+			_, body := DoGetRequest("/api/blocking/status", replacement.apiBlockingStatus)
+
+			var result api.BlockingStatus
+			Expect(json.NewDecoder(body).Decode(&result)).Should(Succeed())
+
+			Expect(result.Enabled).Should(BeFalse())
+			Expect(result.AutoEnableInSec).Should(BeNumerically("~", 7200, 2))
+			Expect(result.Pauses).Should(HaveLen(1))
+			Expect(result.Pauses[0].Client).Should(Equal("laptop"))
+		})
+		It("should enable blocking, if the state file is invalid", func() {
+			Expect(ioutil.WriteFile(stateFile, []byte("invalid"), 0600)).Should(Succeed())
+
+			restored, result := restored()
+			defer restored.Stop()
+
+			Expect(result.Enabled).Should(BeTrue())
+		})
+		It("should be safe for concurrent changes and queries", func() {
+			done := make(chan bool)
+
+			for i := 0; i < 4; i++ {
+				go func(i int) {
+					defer GinkgoRecover()
+
+					for j := 0; j < 50; j++ {
+						switch (i + j) % 4 {
+						case 0:
+							DoGetRequest("/api/blocking/disable?duration=1ms", sut.apiBlockingDisable)
+						case 1:
+							DoGetRequest("/api/blocking/enable", sut.apiBlockingEnable)
+						case 2:
+							DoGetRequest("/api/blocking/disable?client=laptop&duration=1ms", sut.apiBlockingDisable)
+						default:
+							DoGetRequest("/api/blocking/status", sut.apiBlockingStatus)
+						}
+
+						_, err := sut.Resolve(newRequestWithClient("blocked3.com.", dns.TypeA, "1.2.1.2", "laptop"))
+						Expect(err).Should(Succeed())
+					}
+
+					done <- true
+				}(i)
+			}
+
+			for i := 0; i < 4; i++ {
+				Eventually(done, "5s").Should(Receive())
+			}
+
+			DoGetRequest("/api/blocking/enable", sut.apiBlockingEnable)
+
+			Eventually(func() bool {
+				resp, err := sut.Resolve(newRequestWithClient("blocked3.com.", dns.TypeA, "1.2.1.2", "laptop"))
+				Expect(err).Should(Succeed())
+
+				return resp.RType == BLOCKED
+			}, "1s").Should(BeTrue())
+		})
 	})
 
 	Describe("Configuration output", func() {
@@ -562,6 +1545,12 @@ badcnamedomain.com`)
 				c := sut.Configuration()
 				Expect(len(c) > 1).Should(BeTrue())
 			})
+			It("should contain the block types of groups", func() {
+				sutConfig.GroupBlockTypes = map[string]string{"gr1": "NxDomain"}
+				sut = NewBlockingResolver(chi.NewRouter(), sutConfig).(*BlockingResolver)
+
+				Expect(sut.Configuration()).Should(ContainElement(`  gr1: blockType = "NxDomain"`))
+			})
 		})
 
 		When("resolver is disabled", func() {
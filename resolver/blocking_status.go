@@ -0,0 +1,256 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/log"
+	"github.com/privacyherodev/ph-blocky/metrics"
+	"github.com/privacyherodev/ph-blocky/util"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// status is the global blocking status. It is read by each query and changed by the REST API and the auto enable
+// timer, so all accesses are synchronized
+type status struct {
+	lock         sync.RWMutex
+	enabled      bool
+	enabledGauge prometheus.Gauge
+	enableTimer  *time.Timer
+	disableEnd   time.Time
+	// incremented on each change, a timer enables the blocking only if no other change happened in the meantime
+	generation uint64
+	// called after each change without holding the lock, for example to store the state
+	onChange func()
+}
+
+func newStatus(enabledGauge prometheus.Gauge) *status {
+	return &status{
+		enabledGauge: enabledGauge,
+		enabled:      true,
+		onChange:     func() {},
+	}
+}
+
+// isEnabled returns true if the blocking is enabled
+func (s *status) isEnabled() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.enabled
+}
+
+// get returns the blocking status and the time, when disabled blocking will be enabled again (zero if never)
+func (s *status) get() (enabled bool, disableEnd time.Time) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.enabled, s.disableEnd
+}
+
+func (s *status) enableBlocking() {
+	s.lock.Lock()
+	s.setEnabled()
+	s.lock.Unlock()
+
+	s.onChange()
+}
+
+func (s *status) disableBlocking(duration time.Duration) {
+	if duration == 0 {
+		log.Logger.Info("disable blocking")
+	} else {
+		log.Logger.Infof("disable blocking for %s", duration)
+	}
+
+	s.disableUntil(time.Now().Add(duration), duration > 0)
+	s.onChange()
+}
+
+// disableUntil disables the blocking, if autoEnable is true, the blocking will be enabled again at the passed time
+func (s *status) disableUntil(end time.Time, autoEnable bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stopTimer()
+	s.generation++
+	s.enabled = false
+	s.disableEnd = time.Time{}
+
+	if metrics.IsEnabled() {
+		s.enabledGauge.Set(0)
+	}
+
+	if autoEnable {
+		s.disableEnd = end
+		generation := s.generation
+
+		s.enableTimer = time.AfterFunc(time.Until(end), func() {
+			s.lock.Lock()
+
+			// blocking was enabled or disabled again in the meantime
+			if s.generation != generation {
+				s.lock.Unlock()
+
+				return
+			}
+
+			s.setEnabled()
+			s.lock.Unlock()
+
+			log.Logger.Info("blocking enabled again")
+			s.onChange()
+		})
+	}
+}
+
+// setEnabled enables the blocking, the caller must hold the lock
+func (s *status) setEnabled() {
+	s.stopTimer()
+	s.generation++
+	s.enabled = true
+	s.disableEnd = time.Time{}
+
+	if metrics.IsEnabled() {
+		s.enabledGauge.Set(1)
+	}
+}
+
+// stop stops the auto enable timer without changing the status
+func (s *status) stop() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.stopTimer()
+}
+
+func (s *status) stopTimer() {
+	if s.enableTimer != nil {
+		s.enableTimer.Stop()
+		s.enableTimer = nil
+	}
+}
+
+// blockingState is the content of the state file: the global blocking status and the pauses of clients and groups
+type blockingState struct {
+	Enabled bool `json:"enabled"`
+	// time when disabled blocking will be enabled again, empty if the blocking is disabled until it is enabled
+	DisableEnd *time.Time   `json:"disableEnd,omitempty"`
+	Pauses     []pauseState `json:"pauses,omitempty"`
+	// toggles, which were changed at runtime
+	Toggles       map[string]bool            `json:"toggles,omitempty"`
+	ClientToggles map[string]map[string]bool `json:"clientToggles,omitempty"`
+}
+
+type pauseState struct {
+	Client string `json:"client,omitempty"`
+	Group  string `json:"group,omitempty"`
+	// time when the pause ends, empty if the pause lasts until blocking is enabled again
+	End *time.Time `json:"end,omitempty"`
+}
+
+// saveState writes the current blocking status and pauses to the state file
+func (r *BlockingResolver) saveState() {
+	if len(r.cfg.StateFile) == 0 {
+		return
+	}
+
+	// concurrent changes should not overwrite the file with an older state
+	r.stateLock.Lock()
+	defer r.stateLock.Unlock()
+
+	data, _ := json.MarshalIndent(r.state(), "", "  ")
+
+	if err := util.WriteFileAtomic(r.cfg.StateFile, data); err != nil {
+		logger("blocking_state").Errorf("can't write blocking state file: %v", err)
+	}
+}
+
+// restoreState restores the blocking status, the pauses and the toggles from the state file
+func (r *BlockingResolver) restoreState() {
+	if len(r.cfg.StateFile) == 0 {
+		return
+	}
+
+	state, err := readState(r.cfg.StateFile)
+	if err != nil {
+		logger("blocking_state").Errorf("can't restore blocking state, blocking is enabled: %v", err)
+
+		return
+	}
+
+	if state != nil {
+		r.applyState(state)
+	}
+}
+
+// TakeOverState applies the blocking status, pauses and toggles of the replaced resolver, so runtime changes survive
+// a reload of the configuration also without state file
+func (r *BlockingResolver) TakeOverState(replaced *BlockingResolver) {
+	state := replaced.state()
+	r.applyState(&state)
+	r.saveState()
+}
+
+// state returns the current blocking status, pauses and toggles
+func (r *BlockingResolver) state() blockingState {
+	enabled, disableEnd := r.status.get()
+
+	state := blockingState{Enabled: enabled, Pauses: r.pauses.state()}
+	state.Toggles, state.ClientToggles = r.toggles.state()
+
+	if !enabled && !disableEnd.IsZero() {
+		state.DisableEnd = &disableEnd
+	}
+
+	return state
+}
+
+// applyState applies the stored blocking status, pauses and toggles. Expired deadlines are ignored
+func (r *BlockingResolver) applyState(state *blockingState) {
+	now := time.Now()
+
+	if !state.Enabled {
+		switch {
+		case state.DisableEnd == nil:
+			log.Logger.Info("blocking is disabled (restored)")
+			r.status.disableUntil(time.Time{}, false)
+		case state.DisableEnd.After(now):
+			log.Logger.Infof("blocking is disabled for %s (restored)",
+				state.DisableEnd.Sub(now).Round(time.Second))
+			r.status.disableUntil(*state.DisableEnd, true)
+		}
+	}
+
+	r.toggles.restore(state.Toggles, state.ClientToggles)
+
+	for _, p := range state.Pauses {
+		if p.End == nil || p.End.After(now) {
+			r.pauses.restore(pauseScope{client: p.Client, group: p.Group}, p.End)
+		}
+	}
+}
+
+// readState reads the state file, returns nil if the file does not exist
+func readState(file string) (*blockingState, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var state blockingState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("invalid state file '%s': %v", file, err)
+	}
+
+	return &state, nil
+}
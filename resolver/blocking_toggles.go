@@ -0,0 +1,337 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/config"
+	"github.com/privacyherodev/ph-blocky/log"
+)
+
+// toggles switch the groups, which are requested by clients identified by MAC address (EDNS0) or client ID.
+// A requested group is checked only if it is toggleable and its toggle is on. The state of a toggle is the state,
+// which was set at runtime for the client, otherwise the global state, which was set at runtime, otherwise the
+// configured global state
+type toggles struct {
+	lock sync.RWMutex
+	// configured global state of each toggleable group
+	configured map[string]bool
+	// global states, which were changed at runtime
+	global map[string]bool
+	// states per client (IP, name or MAC address in lower case), which were changed at runtime
+	clients map[string]map[string]bool
+	// called after each change without holding the lock, for example to store the state
+	onChange func()
+}
+
+func newToggles(cfg config.BlockingConfig) *toggles {
+	names := cfg.ToggleNames()
+	configured := make(map[string]bool, len(names))
+	for _, name := range names {
+		configured[name] = cfg.Global[name]
+	}
+
+	return &toggles{
+		configured: configured,
+		global:     make(map[string]bool),
+		clients:    make(map[string]map[string]bool),
+		onChange:   func() {},
+	}
+}
+
+// names returns the sorted names of the toggleable groups
+func (t *toggles) names() []string {
+	result := make([]string, 0, len(t.configured))
+	for name := range t.configured {
+		result = append(result, name)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+func (t *toggles) check(name string) error {
+	if _, found := t.configured[name]; !found {
+		return fmt.Errorf("unknown toggle '%s', please use one of: %s", name, strings.Join(t.names(), ", "))
+	}
+
+	return nil
+}
+
+// set changes the state of the toggle for the client, empty client changes the global state
+func (t *toggles) set(client, name string, enabled bool) error {
+	if err := t.check(name); err != nil {
+		return err
+	}
+
+	t.lock.Lock()
+
+	if len(client) == 0 {
+		t.global[name] = enabled
+	} else {
+		client = strings.ToLower(client)
+		if t.clients[client] == nil {
+			t.clients[client] = make(map[string]bool)
+		}
+
+		t.clients[client][name] = enabled
+	}
+
+	t.lock.Unlock()
+
+	log.Logger.Infof("set toggle '%s' to %t for %s", name, enabled, toggleScope(client))
+	t.onChange()
+
+	return nil
+}
+
+// reset removes the runtime changes of the toggle (all toggles, if name is empty) for the client, empty client
+// resets the global states to the configured ones
+func (t *toggles) reset(client, name string) error {
+	if len(name) > 0 {
+		if err := t.check(name); err != nil {
+			return err
+		}
+	}
+
+	t.lock.Lock()
+
+	states := t.global
+	if len(client) > 0 {
+		client = strings.ToLower(client)
+		states = t.clients[client]
+	}
+
+	for k := range states {
+		if len(name) == 0 || k == name {
+			delete(states, k)
+		}
+	}
+
+	if len(client) > 0 && len(states) == 0 {
+		delete(t.clients, client)
+	}
+
+	t.lock.Unlock()
+
+	log.Logger.Infof("reset toggles for %s", toggleScope(client))
+	t.onChange()
+
+	return nil
+}
+
+// enabled returns the state of the toggle for the client, which is identified by each of the ids.
+// If the ids have different states, the toggle is off
+func (t *toggles) enabled(ids []string, name string) bool {
+	configured, found := t.configured[name]
+	if !found {
+		return false
+	}
+
+	result, clientFound := true, false
+
+	for id := range clientSet(ids) {
+		if state, found := t.clients[id][name]; found {
+			clientFound = true
+			result = result && state
+		}
+	}
+
+	if clientFound {
+		return result
+	}
+
+	if state, found := t.global[name]; found {
+		return state
+	}
+
+	return configured
+}
+
+// filter removes the groups, which are not toggleable or switched off for the client, and duplicates
+func (t *toggles) filter(ids []string, groups []string) []string {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	result := make([]string, 0, len(groups))
+	added := make(map[string]bool, len(groups))
+
+	for _, g := range groups {
+		if !added[g] && t.enabled(ids, g) {
+			added[g] = true
+			result = append(result, g)
+		}
+	}
+
+	return result
+}
+
+// describe returns the state of each toggle for the client
+func (t *toggles) describe(ids []string, requested map[string]bool) (result []string) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	for _, name := range t.names() {
+		result = append(result, fmt.Sprintf("toggle '%s = %t', requested by client: %t", name, t.enabled(ids, name),
+			requested[name]))
+	}
+
+	return result
+}
+
+// status returns the effective global states and the states, which were changed at runtime for clients
+func (t *toggles) status() api.TogglesStatus {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	result := api.TogglesStatus{Global: make(map[string]bool), Clients: make(map[string]map[string]bool)}
+
+	for name := range t.configured {
+		result.Global[name] = t.enabled(nil, name)
+	}
+
+	for client, states := range t.clients {
+		result.Clients[client] = copyStates(states)
+	}
+
+	return result
+}
+
+// state returns the runtime changes for the state file
+func (t *toggles) state() (global map[string]bool, clients map[string]map[string]bool) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if len(t.global) > 0 {
+		global = copyStates(t.global)
+	}
+
+	for client, states := range t.clients {
+		if clients == nil {
+			clients = make(map[string]map[string]bool)
+		}
+
+		clients[client] = copyStates(states)
+	}
+
+	return global, clients
+}
+
+// restore applies the runtime changes from the state file, toggles which are not configured anymore are ignored
+func (t *toggles) restore(global map[string]bool, clients map[string]map[string]bool) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	for name, state := range global {
+		if _, found := t.configured[name]; found {
+			t.global[name] = state
+		}
+	}
+
+	for client, states := range clients {
+		for name, state := range states {
+			if _, found := t.configured[name]; found {
+				if t.clients[client] == nil {
+					t.clients[client] = make(map[string]bool)
+				}
+
+				t.clients[client][name] = state
+			}
+		}
+	}
+}
+
+func copyStates(states map[string]bool) map[string]bool {
+	result := make(map[string]bool, len(states))
+	for k, v := range states {
+		result[k] = v
+	}
+
+	return result
+}
+
+func toggleScope(client string) string {
+	if len(client) == 0 {
+		return "all clients"
+	}
+
+	return "client '" + client + "'"
+}
+
+// apiToggles is the http endpoint to get the state of the toggles
+// @Summary Toggles
+// @Description get the global state of each toggleable group and the states, which were changed at runtime for clients
+// @Tags blocking
+// @Produce  json
+// @Success 200 {object} api.TogglesStatus "Returns the state of the toggles"
+// @Router /blocking/toggles [get]
+func (r *BlockingResolver) apiToggles(rw http.ResponseWriter, _ *http.Request) {
+	r.writeToggles(rw)
+}
+
+// apiTogglesSet is the http endpoint to change the state of a toggle
+// @Summary Set toggle
+// @Description switches a toggleable group on or off for all clients or for one client without restart.
+// @Description The state of a client has precedence over the global state
+// @Tags blocking
+// @Accept  json
+// @Produce  json
+// @Param toggle body api.ToggleRequest true "toggle, state and optional client"
+// @Success 200 {object} api.TogglesStatus "Toggle was changed, returns the state of the toggles"
+// @Failure 400   "Unknown toggle"
+// @Router /blocking/toggles [post]
+func (r *BlockingResolver) apiTogglesSet(rw http.ResponseWriter, req *http.Request) {
+	var toggleRequest api.ToggleRequest
+	if err := json.NewDecoder(req.Body).Decode(&toggleRequest); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if err := r.toggles.set(strings.TrimSpace(toggleRequest.Client), toggleRequest.Toggle,
+		toggleRequest.Enabled); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	r.writeToggles(rw)
+}
+
+// apiTogglesReset is the http endpoint to reset toggles, which were changed at runtime
+// @Summary Reset toggles
+// @Description removes the runtime changes of the toggles of a client. Without client, the global states are reset
+// @Description to the configured ones
+// @Tags blocking
+// @Produce  json
+// @Param client query string false "client IP, name or MAC address"
+// @Param toggle query string false "toggle to reset, empty for all toggles"
+// @Success 200 {object} api.TogglesStatus "Toggles were reset, returns the state of the toggles"
+// @Failure 400   "Unknown toggle"
+// @Router /blocking/toggles [delete]
+func (r *BlockingResolver) apiTogglesReset(rw http.ResponseWriter, req *http.Request) {
+	client := strings.TrimSpace(req.URL.Query().Get("client"))
+
+	if err := r.toggles.reset(client, req.URL.Query().Get("toggle")); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	r.writeToggles(rw)
+}
+
+func (r *BlockingResolver) writeToggles(rw http.ResponseWriter) {
+	response, _ := json.Marshal(r.toggles.status())
+	_, err := rw.Write(response)
+
+	if err != nil {
+		log.Logger.Fatal("unable to write response ", err)
+	}
+}
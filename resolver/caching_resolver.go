@@ -33,6 +33,14 @@ func NewCachingResolver(cfg config.CachingConfig) ChainedResolver {
 	}
 }
 
+// Reuse returns a new resolver sharing the cached answers with this one, the next resolver of this one is not changed
+func (r *CachingResolver) Reuse() ChainedResolver {
+	res := *r
+	res.next = nil
+
+	return &res
+}
+
 func (r *CachingResolver) getCache(queryType uint16) *cache.Cache {
 	return r.cachesPerType[queryType]
 }
@@ -71,7 +79,7 @@ func (r *CachingResolver) Resolve(request *Request) (response *Response, err err
 
 		// we can cache only A and AAAA queries
 		if question.Qtype == dns.TypeA || question.Qtype == dns.TypeAAAA {
-			val, expiresAt, found := r.getCache(question.Qtype).GetWithExpiration(domain)
+			val, expiresAt, found := r.getFromCache(question.Qtype, domain, request.Req)
 
 			if found {
 				logger.Debug("domain is cached")
@@ -98,8 +106,8 @@ func (r *CachingResolver) Resolve(request *Request) (response *Response, err err
 			logger.WithField("next_resolver", Name(r.next)).Debug("not in cache: go to next resolver")
 			response, err = r.next.Resolve(request)
 
-			if err == nil {
-				r.putInCache(response, domain, question.Qtype)
+			if err == nil && validForECSSubnet(request.Req, response.Res) {
+				r.putInCache(response, cacheKey(domain, request.Req, response.Res), question.Qtype)
 			}
 		} else {
 			logger.Debugf("not A/AAAA: go to next %s", r.next)
@@ -110,15 +118,53 @@ func (r *CachingResolver) Resolve(request *Request) (response *Response, err err
 	return response, err
 }
 
-func (r *CachingResolver) putInCache(response *Response, domain string, qType uint16) {
+// getFromCache returns the cached answer for the domain, answers valid for all clients are preferred over answers for
+// the client subnet of the query
+func (r *CachingResolver) getFromCache(qType uint16, domain string, request *dns.Msg) (interface{}, time.Time, bool) {
+	cache := r.getCache(qType)
+
+	if val, expiresAt, found := cache.GetWithExpiration(domain); found {
+		return val, expiresAt, true
+	}
+
+	if subnet := ecsCacheKey(request); len(subnet) > 0 {
+		return cache.GetWithExpiration(domain + " " + subnet)
+	}
+
+	return nil, time.Time{}, false
+}
+
+// cacheKey returns the domain for answers valid for all clients: answers to queries without ECS option and answers
+// without ECS option or with scope 0. Other answers are only valid for the client subnet of the query
+func cacheKey(domain string, request, response *dns.Msg) string {
+	if responseSubnet := ecsOption(response); responseSubnet == nil || responseSubnet.SourceScope == 0 {
+		return domain
+	}
+
+	if subnet := ecsCacheKey(request); len(subnet) > 0 {
+		return domain + " " + subnet
+	}
+
+	return domain
+}
+
+// validForECSSubnet returns false, if the scope of the response's ECS option is narrower than the client subnet of
+// the query. Such an answer is only valid for a part of the subnet and can't be cached for the whole subnet
+func validForECSSubnet(request, response *dns.Msg) bool {
+	requestSubnet, responseSubnet := ecsOption(request), ecsOption(response)
+
+	return requestSubnet == nil || responseSubnet == nil || responseSubnet.SourceScope <= requestSubnet.SourceNetmask
+}
+
+func (r *CachingResolver) putInCache(response *Response, key string, qType uint16) {
 	answer := response.Res.Answer
 
 	if response.Res.Rcode == dns.RcodeSuccess {
 		// put value into cache
-		r.getCache(qType).Set(domain, answer, time.Duration(r.adjustTTLs(answer))*time.Second)
+		r.getCache(qType).Set(key, answer, time.Duration(r.adjustTTLs(answer))*time.Second)
 	} else if response.Res.Rcode == dns.RcodeNameError {
 		// put return code if NXDOMAIN
-		r.getCache(qType).Set(domain, response.Res.Rcode, cacheTimeNegative)
+		r.getCache(qType).Set(key, response.Res.Rcode, cacheTimeNegative)
 	}
 }
 
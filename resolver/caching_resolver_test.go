@@ -5,6 +5,7 @@ import (
 	. "github.com/privacyherodev/ph-blocky/helpertest"
 	"github.com/privacyherodev/ph-blocky/util"
 
+	"net"
 	"time"
 
 	"github.com/miekg/dns"
@@ -41,6 +42,27 @@ var _ = Describe("CachingResolver", func() {
 		sut.Next(m)
 	})
 
+	Describe("Reuse in a new chain", func() {
+		BeforeEach(func() {
+			mockAnswer, _ = util.NewMsgWithAnswer("example.com.", 600, dns.TypeA, "123.122.121.120")
+		})
+		It("should share the cache and keep the next resolver of the reused resolver", func() {
+			resp, err = sut.Resolve(newRequest("example.com.", dns.TypeA))
+			Expect(err).Should(Succeed())
+			Expect(resp.RType).Should(Equal(RESOLVED))
+
+			reused := sut.(ReusableResolver).Reuse()
+			Expect(reused.GetNext()).Should(BeNil())
+			reused.Next(&resolverMock{})
+
+			Expect(sut.GetNext()).Should(BeIdenticalTo(m))
+
+			resp, err = reused.Resolve(newRequest("example.com.", dns.TypeA))
+			Expect(err).Should(Succeed())
+			Expect(resp.RType).Should(Equal(CACHED))
+		})
+	})
+
 	Describe("Caching responses", func() {
 		When("min caching time is defined", func() {
 			BeforeEach(func() {
@@ -278,6 +300,120 @@ var _ = Describe("CachingResolver", func() {
 		})
 	})
 
+	Describe("Caching responses with EDNS client subnet", func() {
+		requestWithSubnet := func(address string, netmask uint8) *Request {
+			request := newRequest("example.com.", dns.TypeA)
+			setECSOption(request.Req, &dns.EDNS0_SUBNET{
+				Code:          dns.EDNS0SUBNET,
+				Family:        ecsFamilyIPv4,
+				SourceNetmask: netmask,
+				Address:       net.ParseIP(address).To4(),
+			})
+
+			return request
+		}
+
+		answerWithScope := func(scope uint8) {
+			mockAnswer.SetEdns0(dns.DefaultMsgSize, false)
+			setECSOption(mockAnswer, &dns.EDNS0_SUBNET{
+				Code:          dns.EDNS0SUBNET,
+				Family:        ecsFamilyIPv4,
+				SourceNetmask: 24,
+				SourceScope:   scope,
+				Address:       net.ParseIP("192.0.2.0").To4(),
+			})
+		}
+
+		BeforeEach(func() {
+			mockAnswer, _ = util.NewMsgWithAnswer("example.com.", 600, dns.TypeA, "123.122.121.120")
+		})
+
+		It("should cache the response per client subnet", func() {
+			answerWithScope(24)
+
+			By("first request of the subnet", func() {
+				resp, err = sut.Resolve(requestWithSubnet("192.0.2.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(RESOLVED))
+				Expect(m.Calls).Should(HaveLen(1))
+			})
+
+			By("second request of the same subnet", func() {
+				resp, err = sut.Resolve(requestWithSubnet("192.0.2.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(CACHED))
+				Expect(m.Calls).Should(HaveLen(1))
+			})
+
+			By("request of another subnet", func() {
+				resp, err = sut.Resolve(requestWithSubnet("198.51.100.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(RESOLVED))
+				Expect(m.Calls).Should(HaveLen(2))
+			})
+
+			By("request without client subnet", func() {
+				resp, err = sut.Resolve(newRequest("example.com.", dns.TypeA))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(RESOLVED))
+				Expect(m.Calls).Should(HaveLen(3))
+			})
+		})
+
+		When("the response is valid for all client subnets", func() {
+			It("should cache the response with scope 0 once for the domain", func() {
+				answerWithScope(0)
+
+				resp, err = sut.Resolve(requestWithSubnet("192.0.2.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(RESOLVED))
+
+				resp, err = sut.Resolve(requestWithSubnet("198.51.100.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(CACHED))
+
+				resp, err = sut.Resolve(newRequest("example.com.", dns.TypeA))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(CACHED))
+				Expect(m.Calls).Should(HaveLen(1))
+			})
+			It("should cache the response without ECS option once for the domain", func() {
+				resp, err = sut.Resolve(requestWithSubnet("192.0.2.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(RESOLVED))
+
+				resp, err = sut.Resolve(requestWithSubnet("198.51.100.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(CACHED))
+				Expect(m.Calls).Should(HaveLen(1))
+			})
+		})
+
+		When("the scope of the response is narrower than the client subnet", func() {
+			BeforeEach(func() {
+				mockAnswer.SetEdns0(dns.DefaultMsgSize, false)
+				setECSOption(mockAnswer, &dns.EDNS0_SUBNET{
+					Code:          dns.EDNS0SUBNET,
+					Family:        ecsFamilyIPv4,
+					SourceNetmask: 24,
+					SourceScope:   28,
+					Address:       net.ParseIP("192.0.2.0").To4(),
+				})
+			})
+
+			It("should not cache the response", func() {
+				resp, err = sut.Resolve(requestWithSubnet("192.0.2.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(RESOLVED))
+
+				resp, err = sut.Resolve(requestWithSubnet("192.0.2.0", 24))
+				Expect(err).Should(Succeed())
+				Expect(resp.RType).Should(Equal(RESOLVED))
+				Expect(m.Calls).Should(HaveLen(2))
+			})
+		})
+	})
+
 	Describe("Configuration output", func() {
 		When("resolver is enabled", func() {
 			BeforeEach(func() {
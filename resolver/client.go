@@ -0,0 +1,49 @@
+package resolver
+
+import (
+	"net"
+	"strings"
+)
+
+// Client is the identity of the requesting client. The server sets the IP address and the client ID, the
+// ClientIdentityResolver completes it once per request at the start of the chain, so each resolver reads the
+// identity from here instead of determining it again
+type Client struct {
+	// IP address of the client
+	IP net.IP
+	// MAC address of the client (EDNS0), empty if unknown
+	MAC string
+	// names of the client (mapping or reverse lookup), the IP address if no name was found
+	Names []string
+	// client ID, which was passed with the request (DoH path or EDNS0 option), empty if unknown
+	ID string
+	// groups, which the blocking resolver assigned to the client
+	Groups []string
+}
+
+// Identifier returns the most specific identifier of the client: client ID, MAC address or IP address
+func (c *Client) Identifier() string {
+	switch {
+	case len(c.ID) > 0:
+		return c.ID
+	case len(c.MAC) > 0:
+		return c.MAC
+	default:
+		return c.IP.String()
+	}
+}
+
+// ids returns the IP address, names, client ID and MAC address (lower case) of the client
+func (c *Client) ids() []string {
+	ids := append([]string{c.IP.String()}, c.Names...)
+
+	if len(c.ID) > 0 {
+		ids = append(ids, strings.ToLower(c.ID))
+	}
+
+	if len(c.MAC) > 0 {
+		ids = append(ids, strings.ToLower(c.MAC))
+	}
+
+	return ids
+}
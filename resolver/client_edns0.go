@@ -0,0 +1,90 @@
+package resolver
+
+import (
+	"encoding/base64"
+	"net"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/privacyherodev/ph-blocky/config"
+
+	"github.com/miekg/dns"
+)
+
+// clientFromEDNS0 reads the MAC address and the client ID from the EDNS0 options with the configured codes.
+// Options with other codes and invalid values are ignored
+func clientFromEDNS0(msg *dns.Msg, cfg config.EDNS0Config) (mac, clientID string) {
+	opt := msg.IsEdns0()
+	if opt == nil {
+		return "", ""
+	}
+
+	for _, o := range opt.Option {
+		local, ok := o.(*dns.EDNS0_LOCAL)
+		if !ok || local.Code == 0 {
+			continue
+		}
+
+		switch local.Code {
+		case cfg.MACBinary:
+			if len(mac) == 0 {
+				mac = macFromBinary(local.Data)
+			}
+		case cfg.MACText:
+			if len(mac) == 0 {
+				mac = macFromText(string(local.Data))
+			}
+		case cfg.ClientID:
+			if len(clientID) == 0 && utf8.Valid(local.Data) {
+				clientID = strings.TrimSpace(string(local.Data))
+			}
+		}
+	}
+
+	return mac, clientID
+}
+
+// stripClientEDNS0 removes the EDNS0 options with the configured codes from the message
+func stripClientEDNS0(msg *dns.Msg, cfg config.EDNS0Config) {
+	opt := msg.IsEdns0()
+	if opt == nil {
+		return
+	}
+
+	options := opt.Option[:0]
+
+	for _, o := range opt.Option {
+		if local, ok := o.(*dns.EDNS0_LOCAL); ok && local.Code != 0 &&
+			(local.Code == cfg.MACBinary || local.Code == cfg.MACText || local.Code == cfg.ClientID) {
+			continue
+		}
+
+		options = append(options, o)
+	}
+
+	opt.Option = options
+}
+
+// macFromBinary returns the formatted MAC address of 6 (EUI-48) or 8 (EUI-64) bytes
+func macFromBinary(data []byte) string {
+	if len(data) != 6 && len(data) != 8 {
+		return ""
+	}
+
+	return net.HardwareAddr(data).String()
+}
+
+// macFromText returns the formatted MAC address of text ("aa:bb:cc:dd:ee:ff") or base64 encoded bytes
+func macFromText(text string) string {
+	text = strings.TrimSpace(text)
+
+	if mac, err := net.ParseMAC(text); err == nil {
+		return mac.String()
+	}
+
+	if data, err := base64.StdEncoding.DecodeString(text); err == nil {
+		return macFromBinary(data)
+	}
+
+	return ""
+}
@@ -0,0 +1,86 @@
+package resolver
+
+import (
+	"github.com/privacyherodev/ph-blocky/config"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client EDNS0 options", func() {
+	cfg := config.EDNS0Config{
+		MACBinary: config.EDNS0OptionMACBinary,
+		MACText:   config.EDNS0OptionMACText,
+		ClientID:  config.EDNS0OptionClientID,
+	}
+
+	msgWithOptions := func(options ...*dns.EDNS0_LOCAL) *dns.Msg {
+		msg := new(dns.Msg)
+		msg.SetQuestion("example.com.", dns.TypeA)
+		msg.SetEdns0(4096, false)
+
+		opt := msg.IsEdns0()
+		for _, o := range options {
+			opt.Option = append(opt.Option, o)
+		}
+
+		return msg
+	}
+
+	DescribeTable("reading the identity of the client",
+		func(option *dns.EDNS0_LOCAL, expectedMAC, expectedClientID string) {
+			mac, clientID := clientFromEDNS0(msgWithOptions(option), cfg)
+
+			Expect(mac).Should(Equal(expectedMAC))
+			Expect(clientID).Should(Equal(expectedClientID))
+		},
+		Entry("binary MAC address", &dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0x48, 0x52, 0x4a, 0, 0, 1}},
+			"48:52:4a:00:00:01", ""),
+		Entry("binary MAC address with invalid length", &dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0x48, 0x52, 0x4a}},
+			"", ""),
+		Entry("MAC address as text", &dns.EDNS0_LOCAL{Code: 65073, Data: []byte("48:52:4A:00:00:01")},
+			"48:52:4a:00:00:01", ""),
+		Entry("MAC address as base64", &dns.EDNS0_LOCAL{Code: 65073, Data: []byte("SFJKAAAB")},
+			"48:52:4a:00:00:01", ""),
+		Entry("invalid MAC address text", &dns.EDNS0_LOCAL{Code: 65073, Data: []byte("kids-tablet")},
+			"", ""),
+		Entry("client ID", &dns.EDNS0_LOCAL{Code: 65074, Data: []byte("kids-tablet")},
+			"", "kids-tablet"),
+		Entry("option with other code", &dns.EDNS0_LOCAL{Code: 65002, Data: []byte{0x48, 0x52, 0x4a, 0, 0, 1}},
+			"", ""),
+	)
+
+	It("should read MAC address and client ID of several options", func() {
+		mac, clientID := clientFromEDNS0(msgWithOptions(
+			&dns.EDNS0_LOCAL{Code: 65002, Data: []byte("other")},
+			&dns.EDNS0_LOCAL{Code: 65074, Data: []byte("kids-tablet")},
+			&dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0x48, 0x52, 0x4a, 0, 0, 1}}), cfg)
+
+		Expect(mac).Should(Equal("48:52:4a:00:00:01"))
+		Expect(clientID).Should(Equal("kids-tablet"))
+	})
+
+	It("should ignore options with disabled codes", func() {
+		mac, clientID := clientFromEDNS0(msgWithOptions(
+			&dns.EDNS0_LOCAL{Code: 65074, Data: []byte("kids-tablet")},
+			&dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0x48, 0x52, 0x4a, 0, 0, 1}}),
+			config.EDNS0Config{MACText: config.EDNS0OptionMACText})
+
+		Expect(mac).Should(BeEmpty())
+		Expect(clientID).Should(BeEmpty())
+	})
+
+	It("should strip only the configured options", func() {
+		msg := msgWithOptions(
+			&dns.EDNS0_LOCAL{Code: 65002, Data: []byte("other")},
+			&dns.EDNS0_LOCAL{Code: 65074, Data: []byte("kids-tablet")},
+			&dns.EDNS0_LOCAL{Code: 65001, Data: []byte{0x48, 0x52, 0x4a, 0, 0, 1}})
+
+		stripClientEDNS0(msg, cfg)
+
+		Expect(msg.IsEdns0().UDPSize()).Should(Equal(uint16(4096)))
+		Expect(msg.IsEdns0().Option).Should(ConsistOf(&dns.EDNS0_LOCAL{Code: 65002, Data: []byte("other")}))
+	})
+})
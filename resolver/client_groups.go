@@ -0,0 +1,158 @@
+package resolver
+
+import (
+	"net"
+	"sort"
+	"strings"
+
+	"github.com/privacyherodev/ph-blocky/config"
+	"github.com/privacyherodev/ph-blocky/util"
+)
+
+// clientGroupsMatcher finds the groups of a client in clientGroupsBlock. Besides exact client names, client IDs, IP
+// and MAC addresses, the keys can be IP ranges in CIDR notation ("192.168.10.0/24") and MAC address prefixes ("aa:bb:cc:*").
+// An exact key has precedence over ranges and prefixes, of several ranges or prefixes the longest one wins.
+// Keys are matched case insensitive
+type clientGroupsMatcher struct {
+	// keys in lower case
+	cfg map[string][]string
+	// sorted by prefix length, longest first
+	cidrs []clientCIDR
+	// sorted by prefix length, longest first
+	macPrefixes []clientMACPrefix
+}
+
+type clientCIDR struct {
+	key    string
+	ipNet  *net.IPNet
+	length int
+}
+
+type clientMACPrefix struct {
+	key    string
+	prefix string
+	length int
+}
+
+func newClientGroupsMatcher(cfg map[string][]string) *clientGroupsMatcher {
+	m := &clientGroupsMatcher{cfg: make(map[string][]string, len(cfg))}
+
+	// keys, which differ only in case, share their groups
+	for _, key := range util.SortedKeys(cfg) {
+		lower := strings.ToLower(key)
+		m.cfg[lower] = append(m.cfg[lower], cfg[key]...)
+	}
+
+	for key := range m.cfg {
+		switch {
+		case config.IsCIDRKey(key):
+			_, ipNet, err := net.ParseCIDR(key)
+			if err != nil {
+				logger("client_groups").Errorf("invalid IP range '%s' will be ignored", key)
+
+				continue
+			}
+
+			length, _ := ipNet.Mask.Size()
+			m.cidrs = append(m.cidrs, clientCIDR{key: key, ipNet: ipNet, length: length})
+		case config.IsMACPrefixKey(key):
+			prefix, length, err := config.ParseMACPrefix(key)
+			if err != nil {
+				logger("client_groups").Errorf("%v, will be ignored", err)
+
+				continue
+			}
+
+			m.macPrefixes = append(m.macPrefixes, clientMACPrefix{key: key, prefix: prefix, length: length})
+		}
+	}
+
+	// equal lengths are sorted by key to get a stable result
+	sort.Slice(m.cidrs, func(i, j int) bool {
+		if m.cidrs[i].length != m.cidrs[j].length {
+			return m.cidrs[i].length > m.cidrs[j].length
+		}
+
+		return m.cidrs[i].key < m.cidrs[j].key
+	})
+
+	sort.Slice(m.macPrefixes, func(i, j int) bool {
+		if m.macPrefixes[i].length != m.macPrefixes[j].length {
+			return m.macPrefixes[i].length > m.macPrefixes[j].length
+		}
+
+		return m.macPrefixes[i].key < m.macPrefixes[j].key
+	})
+
+	return m
+}
+
+// byName returns the groups of the client name or client ID
+func (m *clientGroupsMatcher) byName(name string) ([]string, bool) {
+	groups, found := m.cfg[strings.ToLower(name)]
+
+	return groups, found
+}
+
+// byIP returns the matching key and the groups of the exact IP address or the longest IP range, which contains it
+func (m *clientGroupsMatcher) byIP(ip net.IP) (key string, groups []string, found bool) {
+	if groups, found := m.cfg[ip.String()]; found {
+		return ip.String(), groups, true
+	}
+
+	if ip == nil {
+		return "", nil, false
+	}
+
+	for _, c := range m.cidrs {
+		if c.ipNet.Contains(ip) {
+			return c.key, m.cfg[c.key], true
+		}
+	}
+
+	return "", nil, false
+}
+
+// byMAC returns the matching key and the groups of the exact MAC address or the longest MAC prefix
+func (m *clientGroupsMatcher) byMAC(mac string) (key string, groups []string, found bool) {
+	if len(mac) == 0 {
+		return "", nil, false
+	}
+
+	mac = strings.ToLower(mac)
+
+	if groups, found := m.cfg[mac]; found {
+		return mac, groups, true
+	}
+
+	for _, p := range m.macPrefixes {
+		if strings.HasPrefix(mac, p.prefix) {
+			return p.key, m.cfg[p.key], true
+		}
+	}
+
+	return "", nil, false
+}
+
+// byClient returns the groups of the client's device (MAC address and client ID) and the groups of its names and
+// IP address. ipFound is true if the IP address matches a key
+func (m *clientGroupsMatcher) byClient(client *Client) (deviceGroups, clientGroups []string, ipFound bool) {
+	_, macGroups, _ := m.byMAC(client.MAC)
+	deviceGroups = append(deviceGroups, macGroups...)
+
+	if len(client.ID) > 0 {
+		idGroups, _ := m.byName(client.ID)
+		deviceGroups = append(deviceGroups, idGroups...)
+	}
+
+	for _, name := range client.Names {
+		if groups, found := m.byName(name); found {
+			clientGroups = append(clientGroups, groups...)
+		}
+	}
+
+	_, groups, ipFound := m.byIP(client.IP)
+	clientGroups = append(clientGroups, groups...)
+
+	return deviceGroups, clientGroups, ipFound
+}
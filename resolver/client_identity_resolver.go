@@ -0,0 +1,236 @@
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/config"
+	"github.com/privacyherodev/ph-blocky/util"
+
+	"github.com/miekg/dns"
+	"github.com/patrickmn/go-cache"
+	"github.com/sirupsen/logrus"
+)
+
+// ClientIdentityResolver determines the identity of the client once per request at the start of the chain: the MAC
+// address (EDNS0) and the client names. It tries to determine the client name by asking responsible DNS server
+// vie rDNS (reverse lookup)
+type ClientIdentityResolver struct {
+	cache            *cache.Cache
+	externalResolver Resolver
+	singleNameOrder  []uint
+	clientIPMapping  map[string][]net.IP
+	edns0            config.EDNS0Config
+	NextResolver
+}
+
+func NewClientIdentityResolver(cfg config.ClientLookupConfig) ChainedResolver {
+	var r Resolver
+	if (config.Upstream{}) != cfg.Upstream {
+		r = NewUpstreamResolver(cfg.Upstream)
+	}
+
+	return &ClientIdentityResolver{
+		cache:            cache.New(1*time.Hour, 1*time.Hour),
+		externalResolver: r,
+		singleNameOrder:  cfg.SingleNameOrder,
+		clientIPMapping:  cfg.ClientnameIPMapping,
+		edns0:            cfg.EDNS0,
+	}
+}
+
+// Reuse returns a new resolver sharing the cache with this one, the next resolver of this one is not changed
+func (r *ClientIdentityResolver) Reuse() ChainedResolver {
+	res := *r
+	res.next = nil
+
+	return &res
+}
+
+func (r *ClientIdentityResolver) Configuration() (result []string) {
+	if r.externalResolver != nil || len(r.clientIPMapping) > 0 {
+		result = append(result, fmt.Sprintf("singleNameOrder = \"%v\"", r.singleNameOrder))
+		if r.externalResolver != nil {
+			result = append(result, fmt.Sprintf("externalResolver = \"%s\"", r.externalResolver))
+		}
+
+		result = append(result, fmt.Sprintf("cache item count = %d", r.cache.ItemCount()))
+
+		if len(r.clientIPMapping) > 0 {
+			result = append(result, "client IP mapping:")
+
+			for k, v := range r.clientIPMapping {
+				result = append(result, fmt.Sprintf("%s -> %s", k, v))
+			}
+		}
+	} else {
+		result = []string{"deactivated, use only IP address"}
+	}
+
+	if r.edns0 != (config.EDNS0Config{}) {
+		result = append(result, fmt.Sprintf("EDNS0 options: macBinary = %d, macText = %d, clientID = %d, strip = %t",
+			r.edns0.MACBinary, r.edns0.MACText, r.edns0.ClientID, r.edns0.Strip))
+	}
+
+	return
+}
+
+// Resolve completes the identity of the client (MAC address and names), which is read by the following resolvers
+func (r *ClientIdentityResolver) Resolve(request *Request) (*Response, error) {
+	r.identify(request)
+
+	request.Client.Names = r.getClientNames(request)
+	request.Log = request.Log.WithField("client_names", strings.Join(request.Client.Names, "; "))
+
+	if len(request.Client.MAC) > 0 {
+		request.Log = request.Log.WithField("client_mac", request.Client.MAC)
+	}
+
+	if len(request.Client.ID) > 0 {
+		request.Log = request.Log.WithField("client_id", request.Client.ID)
+	}
+
+	return r.next.Resolve(request)
+}
+
+// Explain determines the MAC address and the client names, if they were not passed with the request
+func (r *ClientIdentityResolver) Explain(request *Request) api.ExplainStep {
+	r.identify(request)
+
+	step := api.ExplainStep{}
+
+	if len(request.Client.Names) == 0 && request.Client.IP != nil {
+		request.Client.Names = r.getClientNames(request)
+		step.Details = append(step.Details, fmt.Sprintf("client names resolved from IP %s", request.Client.IP))
+	}
+
+	step.Result = fmt.Sprintf("client names: %s", strings.Join(request.Client.Names, "; "))
+
+	if len(request.Client.MAC) > 0 {
+		step.Details = append(step.Details, fmt.Sprintf("client MAC address: %s", request.Client.MAC))
+	}
+
+	if len(request.Client.ID) > 0 {
+		step.Details = append(step.Details, fmt.Sprintf("client ID: %s", request.Client.ID))
+	}
+
+	return step
+}
+
+// identify takes the MAC address and the client ID from the EDNS0 options of the request, if they are not known
+// yet. If configured, the options are removed, so they are not forwarded to the upstream servers
+func (r *ClientIdentityResolver) identify(request *Request) {
+	mac, clientID := clientFromEDNS0(request.Req, r.edns0)
+
+	if len(request.Client.MAC) == 0 {
+		request.Client.MAC = mac
+	}
+
+	if len(request.Client.ID) == 0 {
+		request.Client.ID = clientID
+	}
+
+	// client IDs of all transports (EDNS0, DoH path, TLS server name) are matched in lower case
+	request.Client.ID = strings.ToLower(request.Client.ID)
+
+	if r.edns0.Strip {
+		stripClientEDNS0(request.Req, r.edns0)
+	}
+}
+
+// returns names of client
+func (r *ClientIdentityResolver) getClientNames(request *Request) []string {
+	ip := request.Client.IP
+	c, found := r.cache.Get(ip.String())
+
+	if found {
+		if t, ok := c.([]string); ok {
+			return t
+		}
+	}
+
+	names := r.resolveClientNames(ip, withPrefix(request.Log, "client_names_resolver"))
+	r.cache.Set(ip.String(), names, cache.DefaultExpiration)
+
+	return names
+}
+
+// tries to resolve client name from mapping, performs reverse DNS lookup otherwise
+func (r *ClientIdentityResolver) resolveClientNames(ip net.IP, logger *logrus.Entry) (result []string) {
+	// try client mapping first
+	result = r.getNameFromIPMapping(ip, result)
+
+	if len(result) > 0 {
+		return
+	}
+
+	if r.externalResolver != nil {
+		reverse, err := dns.ReverseAddr(ip.String())
+
+		if err != nil {
+			logger.Warnf("can't create reverse address for %s", ip.String())
+			return
+		}
+
+		resp, err := r.externalResolver.Resolve(&Request{
+			Req: util.NewMsgWithQuestion(reverse, dns.TypePTR),
+			Log: logger,
+		})
+
+		if err != nil {
+			logger.Error("can't resolve client name: ", err)
+			return []string{ip.String()}
+		}
+
+		var clientNames []string
+
+		for _, answer := range resp.Res.Answer {
+			if t, ok := answer.(*dns.PTR); ok {
+				hostName := strings.TrimSuffix(t.Ptr, ".")
+				clientNames = append(clientNames, hostName)
+			}
+		}
+
+		if len(clientNames) == 0 {
+			clientNames = []string{ip.String()}
+		}
+
+		// optional: if singleNameOrder is set, use only one name in the defined order
+		if len(r.singleNameOrder) > 0 {
+			for _, i := range r.singleNameOrder {
+				if i > 0 && int(i) <= len(clientNames) {
+					result = []string{clientNames[i-1]}
+					break
+				}
+			}
+		} else {
+			result = clientNames
+		}
+
+		logger.WithField("client_names", strings.Join(result, "; ")).Debug("resolved client name(s) from external resolver")
+	} else {
+		result = []string{ip.String()}
+	}
+
+	return result
+}
+
+func (r *ClientIdentityResolver) getNameFromIPMapping(ip net.IP, result []string) []string {
+	for name, ips := range r.clientIPMapping {
+		for _, i := range ips {
+			if ip.String() == i.String() {
+				result = append(result, name)
+			}
+		}
+	}
+
+	return result
+}
+
+// reset client name cache
+func (r *ClientIdentityResolver) FlushCache() {
+	r.cache.Flush()
+}
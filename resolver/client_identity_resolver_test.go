@@ -11,12 +11,13 @@ import (
 	"github.com/miekg/dns"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/mock"
 )
 
 var _ = Describe("ClientResolver", func() {
 	var (
-		sut                          *ClientNamesResolver
+		sut                          *ClientIdentityResolver
 		sutConfig                    config.ClientLookupConfig
 		m                            *resolverMock
 		mockReverseUpstream          config.Upstream
@@ -44,7 +45,7 @@ var _ = Describe("ClientResolver", func() {
 	})
 
 	JustBeforeEach(func() {
-		sut = NewClientNamesResolver(sutConfig).(*ClientNamesResolver)
+		sut = NewClientIdentityResolver(sutConfig).(*ClientIdentityResolver)
 		m = &resolverMock{}
 		m.On("Resolve", mock.Anything).Return(&Response{Res: new(dns.Msg)}, nil)
 		sut.Next(m)
@@ -67,8 +68,8 @@ var _ = Describe("ClientResolver", func() {
 			resp, err = sut.Resolve(request)
 
 			Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-			Expect(request.ClientNames).Should(HaveLen(1))
-			Expect(request.ClientNames[0]).Should(Equal("client7"))
+			Expect(request.Client.Names).Should(HaveLen(1))
+			Expect(request.Client.Names[0]).Should(Equal("client7"))
 			Expect(mockReverseUpstreamCallCount).Should(Equal(0))
 		})
 
@@ -77,8 +78,8 @@ var _ = Describe("ClientResolver", func() {
 			resp, err = sut.Resolve(request)
 
 			Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-			Expect(request.ClientNames).Should(HaveLen(1))
-			Expect(request.ClientNames[0]).Should(Equal("client7"))
+			Expect(request.Client.Names).Should(HaveLen(1))
+			Expect(request.Client.Names[0]).Should(Equal("client7"))
 			Expect(mockReverseUpstreamCallCount).Should(Equal(0))
 		})
 		It("should resolve multiple names defined names", func() {
@@ -86,8 +87,8 @@ var _ = Describe("ClientResolver", func() {
 			resp, err = sut.Resolve(request)
 
 			Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-			Expect(request.ClientNames).Should(HaveLen(2))
-			Expect(request.ClientNames).Should(ContainElements("client7", "client8"))
+			Expect(request.Client.Names).Should(HaveLen(2))
+			Expect(request.Client.Names).Should(ContainElements("client7", "client8"))
 			Expect(mockReverseUpstreamCallCount).Should(Equal(0))
 		})
 	})
@@ -111,7 +112,7 @@ var _ = Describe("ClientResolver", func() {
 						resp, err = sut.Resolve(request)
 
 						Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-						Expect(request.ClientNames[0]).Should(Equal("host1"))
+						Expect(request.Client.Names[0]).Should(Equal("host1"))
 						Expect(mockReverseUpstreamCallCount).Should(Equal(1))
 					})
 
@@ -120,7 +121,7 @@ var _ = Describe("ClientResolver", func() {
 						resp, err = sut.Resolve(request)
 
 						Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-						Expect(request.ClientNames[0]).Should(Equal("host1"))
+						Expect(request.Client.Names[0]).Should(Equal("host1"))
 						// use cache -> call count 1
 						Expect(mockReverseUpstreamCallCount).Should(Equal(1))
 					})
@@ -133,7 +134,7 @@ var _ = Describe("ClientResolver", func() {
 						resp, err = sut.Resolve(request)
 
 						Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-						Expect(request.ClientNames[0]).Should(Equal("host1"))
+						Expect(request.Client.Names[0]).Should(Equal("host1"))
 						// no cache -> call count 2
 						Expect(mockReverseUpstreamCallCount).Should(Equal(2))
 					})
@@ -157,9 +158,9 @@ var _ = Describe("ClientResolver", func() {
 					resp, err = sut.Resolve(request)
 
 					Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-					Expect(request.ClientNames).Should(HaveLen(2))
-					Expect(request.ClientNames[0]).Should(Equal("myhost1"))
-					Expect(request.ClientNames[1]).Should(Equal("myhost2"))
+					Expect(request.Client.Names).Should(HaveLen(2))
+					Expect(request.Client.Names[0]).Should(Equal("myhost1"))
+					Expect(request.Client.Names[1]).Should(Equal("myhost2"))
 					Expect(mockReverseUpstreamCallCount).Should(Equal(1))
 				})
 			})
@@ -182,7 +183,7 @@ var _ = Describe("ClientResolver", func() {
 					resp, err = sut.Resolve(request)
 
 					Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-					Expect(request.ClientNames[0]).Should(Equal("host1"))
+					Expect(request.Client.Names[0]).Should(Equal("host1"))
 					Expect(mockReverseUpstreamCallCount).Should(Equal(1))
 				})
 			})
@@ -202,8 +203,8 @@ var _ = Describe("ClientResolver", func() {
 					resp, err = sut.Resolve(request)
 
 					Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-					Expect(request.ClientNames).Should(HaveLen(1))
-					Expect(request.ClientNames[0]).Should(Equal("myhost2"))
+					Expect(request.Client.Names).Should(HaveLen(1))
+					Expect(request.Client.Names[0]).Should(Equal("myhost2"))
 					Expect(mockReverseUpstreamCallCount).Should(Equal(1))
 				})
 			})
@@ -218,8 +219,8 @@ var _ = Describe("ClientResolver", func() {
 				resp, err = sut.Resolve(request)
 
 				Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-				Expect(request.ClientNames).Should(HaveLen(1))
-				Expect(request.ClientNames[0]).Should(Equal("192.168.178.25"))
+				Expect(request.Client.Names).Should(HaveLen(1))
+				Expect(request.Client.Names[0]).Should(Equal("192.168.178.25"))
 				Expect(mockReverseUpstreamCallCount).Should(Equal(1))
 			})
 		})
@@ -233,8 +234,8 @@ var _ = Describe("ClientResolver", func() {
 				request := newRequestWithClient("google.de.", dns.TypeA, "192.168.178.25")
 				resp, err = sut.Resolve(request)
 
-				Expect(request.ClientNames).Should(HaveLen(1))
-				Expect(request.ClientNames[0]).Should(Equal("192.168.178.25"))
+				Expect(request.Client.Names).Should(HaveLen(1))
+				Expect(request.Client.Names[0]).Should(Equal("192.168.178.25"))
 				Expect(mockReverseUpstreamCallCount).Should(Equal(0))
 			})
 		})
@@ -245,7 +246,7 @@ var _ = Describe("ClientResolver", func() {
 				resp, err = sut.Resolve(request)
 
 				Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
-				Expect(request.ClientNames).Should(BeEmpty())
+				Expect(request.Client.Names).Should(BeEmpty())
 				Expect(mockReverseUpstreamCallCount).Should(Equal(0))
 			})
 		})
@@ -258,13 +259,83 @@ var _ = Describe("ClientResolver", func() {
 				request := newRequestWithClient("google.de.", dns.TypeA, "192.168.178.25")
 				resp, err = sut.Resolve(request)
 
-				Expect(request.ClientNames).Should(HaveLen(1))
-				Expect(request.ClientNames[0]).Should(Equal("192.168.178.25"))
+				Expect(request.Client.Names).Should(HaveLen(1))
+				Expect(request.Client.Names[0]).Should(Equal("192.168.178.25"))
 				Expect(mockReverseUpstreamCallCount).Should(Equal(0))
 			})
 		})
 	})
 
+	Describe("Resolve client identity", func() {
+		BeforeEach(func() {
+			sutConfig = config.ClientLookupConfig{
+				EDNS0: config.EDNS0Config{MACBinary: config.EDNS0OptionMACBinary, ClientID: config.EDNS0OptionClientID},
+			}
+		})
+		It("should take the MAC address from EDNS0 and pass the identity to the next resolver", func() {
+			request := &Request{
+				Client: Client{IP: net.ParseIP("192.168.178.25"), ID: "kids-tablet"},
+				Req:    newMsgWithEDNS0("google.de.", dns.TypeA, []byte{0x48, 0x52, 0x4a, 0, 0, 1}),
+				Log:    logrus.NewEntry(logrus.New()),
+			}
+			resp, err = sut.Resolve(request)
+
+			Expect(resp.Res.Rcode).Should(Equal(dns.RcodeSuccess))
+			m.AssertCalled(GinkgoT(), "Resolve", request)
+			Expect(request.Client.MAC).Should(Equal("48:52:4a:00:00:01"))
+			Expect(request.Client.Names).Should(Equal([]string{"192.168.178.25"}))
+			Expect(request.Client.ID).Should(Equal("kids-tablet"))
+			Expect(request.Client.Identifier()).Should(Equal("kids-tablet"))
+		})
+		It("should take the client ID from EDNS0 and strip the options, if configured", func() {
+			sutConfig.EDNS0.Strip = true
+			sut = NewClientIdentityResolver(sutConfig).(*ClientIdentityResolver)
+			sut.Next(m)
+
+			request := &Request{
+				Client: Client{IP: net.ParseIP("192.168.178.25")},
+				Req:    newMsgWithEDNS0("google.de.", dns.TypeA, []byte{0x48, 0x52, 0x4a, 0, 0, 1}),
+				Log:    logrus.NewEntry(logrus.New()),
+			}
+			request.Req.IsEdns0().Option = append(request.Req.IsEdns0().Option,
+				&dns.EDNS0_LOCAL{Code: config.EDNS0OptionClientID, Data: []byte("kids-tablet")})
+			resp, err = sut.Resolve(request)
+
+			Expect(request.Client.MAC).Should(Equal("48:52:4a:00:00:01"))
+			Expect(request.Client.ID).Should(Equal("kids-tablet"))
+			Expect(request.Req.IsEdns0().Option).Should(BeEmpty())
+		})
+		It("should convert the client ID to lower case", func() {
+			request := &Request{
+				Client: Client{IP: net.ParseIP("192.168.178.25")},
+				Req:    newMsgWithEDNS0("google.de.", dns.TypeA, []byte{0x48, 0x52, 0x4a, 0, 0, 1}),
+				Log:    logrus.NewEntry(logrus.New()),
+			}
+			request.Req.IsEdns0().Option = append(request.Req.IsEdns0().Option,
+				&dns.EDNS0_LOCAL{Code: config.EDNS0OptionClientID, Data: []byte("Kids-Tablet")})
+			resp, err = sut.Resolve(request)
+
+			Expect(request.Client.ID).Should(Equal("kids-tablet"))
+		})
+		It("should identify the client by IP address without MAC address and client ID", func() {
+			request := newRequestWithClient("google.de.", dns.TypeA, "192.168.178.25")
+			resp, err = sut.Resolve(request)
+
+			Expect(request.Client.MAC).Should(BeEmpty())
+			Expect(request.Client.Identifier()).Should(Equal("192.168.178.25"))
+		})
+		It("should explain the MAC address", func() {
+			request := &Request{
+				Client: Client{IP: net.ParseIP("192.168.178.25")},
+				Req:    newMsgWithEDNS0("google.de.", dns.TypeA, []byte{0x48, 0x52, 0x4a, 0, 0, 1}),
+				Log:    logrus.NewEntry(logrus.New()),
+			}
+			step := sut.Explain(request)
+			Expect(step.Result).Should(Equal("client names: 192.168.178.25"))
+			Expect(step.Details).Should(ContainElement("client MAC address: 48:52:4a:00:00:01"))
+		})
+	})
+
 	Describe("Configuration output", func() {
 		When("resolver is enabled", func() {
 			BeforeEach(func() {
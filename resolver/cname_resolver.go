@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"github.com/miekg/dns"
+	"github.com/privacyherodev/ph-blocky/api"
 	"github.com/privacyherodev/ph-blocky/config"
 	"github.com/privacyherodev/ph-blocky/util"
 	"github.com/sirupsen/logrus"
@@ -14,12 +15,13 @@ import (
 
 type CnameResolver struct {
 	NextResolver
-	cfg config.CnameConfig
+	cfg          config.CnameConfig
+	clientGroups *clientGroupsMatcher
 }
 
 // NewCnameResolver resturns a new restriction resolver
 func NewCnameResolver(cfg config.CnameConfig) ChainedResolver {
-	return &CnameResolver{cfg: cfg}
+	return &CnameResolver{cfg: cfg, clientGroups: newClientGroupsMatcher(cfg.ClientGroupsBlock)}
 }
 
 // Configuration returns the string representation of the configuration
@@ -97,9 +99,33 @@ func (cr *CnameResolver) Resolve(req *Request) (*Response, error) {
 	return cr.next.Resolve(req)
 }
 
+// Explain returns the cname group of the client, which contains the domain
+func (cr *CnameResolver) Explain(request *Request) api.ExplainStep {
+	domain := util.ExtractDomain(request.Req.Question[0])
+	groups := cr.groupsToCheckForClient(request)
+	step := api.ExplainStep{Result: "no restriction", Groups: groups}
+
+	for _, g := range groups {
+		for _, d := range cr.cfg.Groups[g].Domains {
+			if d == domain {
+				step.Result = "RESTRICTED DNS"
+				step.Decisive = true
+				step.Details = []string{fmt.Sprintf("domain '%s' is in cname group '%s' and redirects to %s",
+					domain, g, cr.cfg.Groups[g].Cname)}
+
+				return step
+			}
+		}
+	}
+
+	return step
+}
+
+// returns the cname groups of the client's MAC address, client ID, names and IP address, the same keys as in
+// blocking.clientGroupsBlock are supported
 func (cr *CnameResolver) groupsToCheckForClient(request *Request) (groups []string) {
-	// try client names
-	getEdnsData(request, cr.cfg.ClientGroupsBlock, &groups)
+	deviceGroups, clientGroups, _ := cr.clientGroups.byClient(&request.Client)
+	groups = append(append([]string{}, deviceGroups...), clientGroups...)
 
 	if len(groups) == 0 {
 		groups = cr.cfg.ClientGroupsBlock["default"]
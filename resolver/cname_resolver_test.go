@@ -64,3 +64,24 @@ func TestCnameResolver_Resolve(t *testing.T) {
 	// was delegated to the next resolver
 
 }
+
+func TestCnameResolver_groupsToCheckForClient(t *testing.T) {
+	cr := NewCnameResolver(config.CnameConfig{
+		ClientGroupsBlock: map[string][]string{
+			"default":         {"youtube"},
+			"192.168.0.0/16":  {"youtube"},
+			"192.168.10.0/24": {"youtube", "search"},
+			"48:52:4a:*":      {"search"},
+		},
+	}).(*CnameResolver)
+
+	assert.Equal(t, []string{"search", "youtube"},
+		cr.groupsToCheckForClient(newRequestWithClient("example.com.", dns.TypeA, "192.168.10.1")))
+	assert.Equal(t, []string{"youtube"},
+		cr.groupsToCheckForClient(newRequestWithClient("example.com.", dns.TypeA, "192.168.20.1")))
+	assert.Equal(t, []string{"search"},
+		cr.groupsToCheckForClient(newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "10.0.0.1",
+			[]byte{0x48, 0x52, 0x4a, 0, 0, 1})))
+	assert.Equal(t, []string{"youtube"},
+		cr.groupsToCheckForClient(newRequestWithClient("example.com.", dns.TypeA, "10.0.0.1")))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/privacyherodev/ph-blocky/api"
 	"github.com/privacyherodev/ph-blocky/config"
 	"github.com/privacyherodev/ph-blocky/util"
 
@@ -37,6 +38,23 @@ func (r *ConditionalUpstreamResolver) Configuration() (result []string) {
 	return
 }
 
+// Explain returns the conditional upstream for the domain, the request is not sent to the upstream
+func (r *ConditionalUpstreamResolver) Explain(request *Request) api.ExplainStep {
+	domain := util.ExtractDomain(request.Req.Question[0])
+
+	for d := domain; len(d) > 0; d = parentDomain(d) {
+		if upstream, found := r.mapping[d]; found {
+			return api.ExplainStep{
+				Result:   "CONDITIONAL",
+				Decisive: true,
+				Details:  []string{fmt.Sprintf("domain '%s' matches mapping '%s' with upstream %s", domain, d, upstream)},
+			}
+		}
+	}
+
+	return api.ExplainStep{Result: "no conditional mapping"}
+}
+
 func (r *ConditionalUpstreamResolver) Resolve(request *Request) (*Response, error) {
 	logger := withPrefix(request.Log, "conditional_resolver")
 
@@ -5,6 +5,7 @@ import (
 	"net"
 	"strings"
 
+	"github.com/privacyherodev/ph-blocky/api"
 	"github.com/privacyherodev/ph-blocky/config"
 	"github.com/privacyherodev/ph-blocky/util"
 
@@ -46,6 +47,29 @@ func isSupportedType(ip net.IP, question dns.Question) bool {
 		(strings.Contains(ip.String(), ":") && question.Qtype == dns.TypeAAAA)
 }
 
+// Explain returns the custom mapping for the domain
+func (r *CustomDNSResolver) Explain(request *Request) api.ExplainStep {
+	question := request.Req.Question[0]
+	domain := util.ExtractDomain(question)
+
+	for d := domain; len(d) > 0; d = parentDomain(d) {
+		if ip, found := r.mapping[d]; found {
+			detail := fmt.Sprintf("domain '%s' matches mapping '%s' = %s", domain, d, ip)
+			if !isSupportedType(ip, question) {
+				detail += fmt.Sprintf(", query type %s is not supported: NXDOMAIN", dns.TypeToString[question.Qtype])
+			}
+
+			return api.ExplainStep{
+				Result:   "CUSTOM DNS",
+				Decisive: true,
+				Details:  []string{detail},
+			}
+		}
+	}
+
+	return api.ExplainStep{Result: "no custom mapping"}
+}
+
 func (r *CustomDNSResolver) Resolve(request *Request) (*Response, error) {
 	logger := withPrefix(request.Log, "custom_dns_resolver")
 
@@ -0,0 +1,228 @@
+package resolver
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/config"
+	"github.com/privacyherodev/ph-blocky/util"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	ecsFamilyIPv4 = 1
+	ecsFamilyIPv6 = 2
+)
+
+// ECSResolver applies the EDNS client subnet (ECS) policy of the queried domain before the query is cached and
+// forwarded to the conditional or default upstream servers: the option is stripped (default), forwarded unchanged or synthesized from the
+// truncated IP address of the client
+type ECSResolver struct {
+	NextResolver
+	cfg config.ECSConfig
+}
+
+// NewECSResolver returns a new resolver, which applies the ECS policy
+func NewECSResolver(cfg config.ECSConfig) ChainedResolver {
+	return &ECSResolver{cfg: cfg}
+}
+
+// Configuration returns the current resolver configuration
+func (r *ECSResolver) Configuration() (result []string) {
+	result = append(result, fmt.Sprintf("policy = \"%s\"", r.cfg.PolicyFor("")))
+	result = append(result, fmt.Sprintf("synthesized subnets = /%d, /%d", r.cfg.IPv4Mask, r.cfg.IPv6Mask))
+
+	for _, domain := range util.SortedKeys(r.cfg.Domains) {
+		result = append(result, fmt.Sprintf("  %s = \"%s\"", domain, r.cfg.Domains[domain]))
+	}
+
+	return
+}
+
+// Resolve changes the ECS option of the query according to the policy. The response contains an ECS option only if
+// the query of the client contained one
+func (r *ECSResolver) Resolve(request *Request) (*Response, error) {
+	logger := withPrefix(request.Log, "ecs_resolver")
+
+	hadOpt := request.Req.IsEdns0() != nil
+	hadECS := ecsOption(request.Req) != nil
+
+	policy := config.ECSPolicyStrip
+	if len(request.Req.Question) > 0 {
+		policy = r.cfg.PolicyFor(util.ExtractDomain(request.Req.Question[0]))
+	}
+
+	switch policy {
+	case config.ECSPolicyForward:
+	case config.ECSPolicySynthesize:
+		if subnet := r.synthesize(request.Client.IP); subnet != nil {
+			setECSOption(request.Req, subnet)
+			logger.Debugf("synthesized client subnet %s/%d", subnet.Address, subnet.SourceNetmask)
+		} else {
+			removeECSOption(request.Req)
+		}
+	default:
+		removeECSOption(request.Req)
+	}
+
+	response, err := r.next.Resolve(request)
+
+	if err == nil && response.Res != nil {
+		switch {
+		case !hadOpt:
+			removeOpt(response.Res)
+		case !hadECS:
+			removeECSOption(response.Res)
+		}
+	}
+
+	return response, err
+}
+
+// Explain returns the ECS policy of the queried domain
+func (r *ECSResolver) Explain(request *Request) api.ExplainStep {
+	policy := r.cfg.PolicyFor(util.ExtractDomain(request.Req.Question[0]))
+	step := api.ExplainStep{Result: fmt.Sprintf("ECS policy: %s", policy)}
+
+	if policy == config.ECSPolicySynthesize {
+		if subnet := r.synthesize(request.Client.IP); subnet != nil {
+			step.Details = append(step.Details, fmt.Sprintf("client subnet %s/%d", subnet.Address, subnet.SourceNetmask))
+		} else {
+			step.Details = append(step.Details, fmt.Sprintf("no client subnet for client IP %s", request.Client.IP))
+		}
+	}
+
+	return step
+}
+
+// synthesize returns the ECS option with the truncated IP address of the client. Clients with loopback, link local
+// or private addresses get no option, these addresses are not useful for the upstream servers
+func (r *ECSResolver) synthesize(ip net.IP) *dns.EDNS0_SUBNET {
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified() || isPrivateIP(ip) {
+		return nil
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        ecsFamilyIPv4,
+			SourceNetmask: r.cfg.IPv4Mask,
+			Address:       ip4.Mask(net.CIDRMask(int(r.cfg.IPv4Mask), 8*net.IPv4len)),
+		}
+	}
+
+	return &dns.EDNS0_SUBNET{
+		Code:          dns.EDNS0SUBNET,
+		Family:        ecsFamilyIPv6,
+		SourceNetmask: r.cfg.IPv6Mask,
+		Address:       ip.Mask(net.CIDRMask(int(r.cfg.IPv6Mask), 8*net.IPv6len)),
+	}
+}
+
+// nolint:gochecknoglobals
+var privateNetworks = []*net.IPNet{
+	mustParseCIDR("10.0.0.0/8"),
+	mustParseCIDR("172.16.0.0/12"),
+	mustParseCIDR("192.168.0.0/16"),
+	mustParseCIDR("100.64.0.0/10"),
+	mustParseCIDR("fc00::/7"),
+}
+
+func mustParseCIDR(cidr string) *net.IPNet {
+	_, ipNet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		panic(err)
+	}
+
+	return ipNet
+}
+
+func isPrivateIP(ip net.IP) bool {
+	for _, n := range privateNetworks {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ecsOption returns the ECS option of the message, nil if there is none
+func ecsOption(msg *dns.Msg) *dns.EDNS0_SUBNET {
+	if opt := msg.IsEdns0(); opt != nil {
+		for _, o := range opt.Option {
+			if subnet, ok := o.(*dns.EDNS0_SUBNET); ok {
+				return subnet
+			}
+		}
+	}
+
+	return nil
+}
+
+// setECSOption replaces the ECS option of the message, adds the OPT record if necessary
+func setECSOption(msg *dns.Msg, subnet *dns.EDNS0_SUBNET) {
+	removeECSOption(msg)
+
+	if msg.IsEdns0() == nil {
+		msg.SetEdns0(dns.DefaultMsgSize, false)
+	}
+
+	opt := msg.IsEdns0()
+	opt.Option = append(opt.Option, subnet)
+}
+
+// removeECSOption removes the ECS option from the message
+func removeECSOption(msg *dns.Msg) {
+	opt := msg.IsEdns0()
+	if opt == nil {
+		return
+	}
+
+	options := opt.Option[:0]
+
+	for _, o := range opt.Option {
+		if _, ok := o.(*dns.EDNS0_SUBNET); !ok {
+			options = append(options, o)
+		}
+	}
+
+	opt.Option = options
+}
+
+// removeOpt removes the OPT record from the message
+func removeOpt(msg *dns.Msg) {
+	extra := msg.Extra[:0]
+
+	for _, rr := range msg.Extra {
+		if rr.Header().Rrtype != dns.TypeOPT {
+			extra = append(extra, rr)
+		}
+	}
+
+	msg.Extra = extra
+}
+
+// ecsCacheKey returns the client subnet of the query's ECS option like "192.0.2.0/24", empty without option
+func ecsCacheKey(msg *dns.Msg) string {
+	subnet := ecsOption(msg)
+	if subnet == nil {
+		return ""
+	}
+
+	bits := 8 * net.IPv6len
+	if subnet.Family == ecsFamilyIPv4 {
+		bits = 8 * net.IPv4len
+	}
+
+	ones := int(subnet.SourceNetmask)
+	if ones > bits {
+		ones = bits
+	}
+
+	mask := net.CIDRMask(ones, bits)
+
+	return (&net.IPNet{IP: subnet.Address.Mask(mask), Mask: mask}).String()
+}
@@ -0,0 +1,191 @@
+package resolver
+
+import (
+	"net"
+
+	"github.com/privacyherodev/ph-blocky/config"
+	. "github.com/privacyherodev/ph-blocky/helpertest"
+	"github.com/privacyherodev/ph-blocky/util"
+
+	"github.com/miekg/dns"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/stretchr/testify/mock"
+)
+
+var _ = Describe("ECSResolver", func() {
+	var (
+		sut        ChainedResolver
+		sutConfig  config.ECSConfig
+		m          *resolverMock
+		mockAnswer *dns.Msg
+
+		err  error
+		resp *Response
+	)
+
+	BeforeEach(func() {
+		sutConfig = config.ECSConfig{IPv4Mask: 24, IPv6Mask: 56}
+		mockAnswer, _ = util.NewMsgWithAnswer("example.com.", 300, dns.TypeA, "123.122.121.120")
+	})
+
+	JustBeforeEach(func() {
+		sut = NewECSResolver(sutConfig)
+		m = &resolverMock{}
+		m.On("Resolve", mock.Anything).Return(&Response{Res: mockAnswer}, nil)
+		sut.Next(m)
+	})
+
+	AfterEach(func() {
+		Expect(err).Should(Succeed())
+	})
+
+	requestWithECS := func(question, ip string) *Request {
+		request := newRequestWithClient(question, dns.TypeA, ip)
+		setECSOption(request.Req, &dns.EDNS0_SUBNET{
+			Code:          dns.EDNS0SUBNET,
+			Family:        ecsFamilyIPv4,
+			SourceNetmask: 24,
+			Address:       net.ParseIP("192.0.2.0").To4(),
+		})
+
+		return request
+	}
+
+	forwardedECS := func() *dns.EDNS0_SUBNET {
+		return ecsOption(m.Calls[0].Arguments.Get(0).(*Request).Req)
+	}
+
+	When("no policy is configured", func() {
+		It("should strip the ECS option of the query", func() {
+			resp, err = sut.Resolve(requestWithECS("example.com.", "203.0.113.7"))
+
+			Expect(m.Calls).Should(HaveLen(1))
+			Expect(forwardedECS()).Should(BeNil())
+			Expect(resp.Res.Answer).Should(BeDNSRecord("example.com.", dns.TypeA, 300, "123.122.121.120"))
+		})
+	})
+
+	When("the policy is 'forward'", func() {
+		BeforeEach(func() {
+			sutConfig.Policy = config.ECSPolicyForward
+		})
+
+		It("should forward the ECS option of the query unchanged", func() {
+			resp, err = sut.Resolve(requestWithECS("example.com.", "203.0.113.7"))
+
+			Expect(forwardedECS()).ShouldNot(BeNil())
+			Expect(forwardedECS().Address.String()).Should(Equal("192.0.2.0"))
+			Expect(forwardedECS().SourceNetmask).Should(Equal(uint8(24)))
+		})
+
+		It("should not add an ECS option", func() {
+			resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeA, "203.0.113.7"))
+
+			Expect(forwardedECS()).Should(BeNil())
+		})
+	})
+
+	When("the policy is 'synthesize'", func() {
+		BeforeEach(func() {
+			sutConfig.Policy = config.ECSPolicySynthesize
+		})
+
+		It("should add the /24 subnet of an IPv4 client", func() {
+			resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeA, "203.0.113.7"))
+
+			Expect(forwardedECS()).ShouldNot(BeNil())
+			Expect(forwardedECS().Family).Should(Equal(uint16(ecsFamilyIPv4)))
+			Expect(forwardedECS().Address.String()).Should(Equal("203.0.113.0"))
+			Expect(forwardedECS().SourceNetmask).Should(Equal(uint8(24)))
+		})
+
+		It("should add the /56 subnet of an IPv6 client", func() {
+			resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeA, "2001:db8:aaaa:bbcc:1::1"))
+
+			Expect(forwardedECS()).ShouldNot(BeNil())
+			Expect(forwardedECS().Family).Should(Equal(uint16(ecsFamilyIPv6)))
+			Expect(forwardedECS().Address.String()).Should(Equal("2001:db8:aaaa:bb00::"))
+			Expect(forwardedECS().SourceNetmask).Should(Equal(uint8(56)))
+		})
+
+		It("should replace the ECS option of the query", func() {
+			resp, err = sut.Resolve(requestWithECS("example.com.", "203.0.113.7"))
+
+			Expect(forwardedECS().Address.String()).Should(Equal("203.0.113.0"))
+		})
+
+		It("should not add an ECS option for private client addresses", func() {
+			for _, ip := range []string{"192.168.178.20", "10.1.2.3", "127.0.0.1", "fd00::1", "fe80::1"} {
+				resp, err = sut.Resolve(requestWithECS("example.com.", ip))
+				Expect(err).Should(Succeed())
+			}
+
+			for _, call := range m.Calls {
+				Expect(ecsOption(call.Arguments.Get(0).(*Request).Req)).Should(BeNil())
+			}
+		})
+
+		It("should remove the OPT record of the response, if the query had none", func() {
+			mockAnswer.SetEdns0(dns.DefaultMsgSize, false)
+			setECSOption(mockAnswer, &dns.EDNS0_SUBNET{
+				Code: dns.EDNS0SUBNET, Family: ecsFamilyIPv4, SourceNetmask: 24, SourceScope: 24,
+				Address: net.ParseIP("203.0.113.0").To4(),
+			})
+
+			resp, err = sut.Resolve(newRequestWithClient("example.com.", dns.TypeA, "203.0.113.7"))
+
+			Expect(resp.Res.IsEdns0()).Should(BeNil())
+			Expect(resp.Res.Answer).Should(HaveLen(1))
+		})
+	})
+
+	When("policies are configured per domain", func() {
+		BeforeEach(func() {
+			sutConfig.Policy = config.ECSPolicySynthesize
+			sutConfig.Domains = map[string]string{
+				"example.com":     config.ECSPolicyStrip,
+				"cdn.example.com": config.ECSPolicyForward,
+			}
+		})
+
+		It("should apply the policy of the longest matching domain suffix", func() {
+			By("default policy", func() {
+				resp, err = sut.Resolve(requestWithECS("other.com.", "203.0.113.7"))
+				Expect(ecsOption(m.Calls[0].Arguments.Get(0).(*Request).Req).Address.String()).Should(Equal("203.0.113.0"))
+			})
+
+			By("policy of the domain", func() {
+				resp, err = sut.Resolve(requestWithECS("www.example.com.", "203.0.113.7"))
+				Expect(ecsOption(m.Calls[1].Arguments.Get(0).(*Request).Req)).Should(BeNil())
+			})
+
+			By("policy of the subdomain", func() {
+				resp, err = sut.Resolve(requestWithECS("img.cdn.example.com.", "203.0.113.7"))
+				Expect(ecsOption(m.Calls[2].Arguments.Get(0).(*Request).Req).Address.String()).Should(Equal("192.0.2.0"))
+			})
+		})
+
+		It("should explain the policy of the domain", func() {
+			step := sut.(*ECSResolver).Explain(newRequestWithClient("other.com.", dns.TypeA, "203.0.113.7"))
+
+			Expect(step.Result).Should(Equal("ECS policy: synthesize"))
+			Expect(step.Details).Should(Equal([]string{"client subnet 203.0.113.0/24"}))
+		})
+	})
+
+	Describe("Configuration output", func() {
+		BeforeEach(func() {
+			sutConfig.Domains = map[string]string{"example.com": config.ECSPolicyForward}
+		})
+
+		It("should return configuration", func() {
+			c := sut.Configuration()
+			Expect(c).Should(Equal([]string{
+				"policy = \"strip\"",
+				"synthesized subnets = /24, /56",
+				"  example.com = \"forward\"",
+			}))
+		})
+	})
+})
@@ -0,0 +1,56 @@
+package resolver
+
+import (
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/util"
+)
+
+// Explainer is a resolver which can explain its decision for a request without resolving it
+type Explainer interface {
+	// Explain returns the result of the resolver for the request, the name of the resolver is set by the caller
+	Explain(request *Request) api.ExplainStep
+}
+
+// Explain walks through the resolver chain and collects the explanation of each resolver, which implements
+// Explainer. The walk stops at the first decisive resolver. Answers of upstream resolvers are not evaluated
+func Explain(chain Resolver, request *Request) api.ExplainResult {
+	result := api.ExplainResult{
+		Domain: util.ExtractDomain(request.Req.Question[0]),
+		Steps:  []api.ExplainStep{},
+	}
+
+	var last Resolver
+
+	for r := chain; r != nil; {
+		last = r
+
+		if e, ok := r.(Explainer); ok {
+			step := e.Explain(request)
+			step.Resolver = Name(r)
+
+			result.Steps = append(result.Steps, step)
+
+			if step.Decisive {
+				result.Outcome = step.Result
+				result.DecidedBy = step.Resolver
+
+				return result
+			}
+		}
+
+		cr, ok := r.(ChainedResolver)
+		if !ok {
+			break
+		}
+
+		r = cr.GetNext()
+	}
+
+	result.Outcome = RESOLVED.String()
+
+	if last != nil {
+		result.DecidedBy = Name(last)
+	}
+
+	return result
+}
@@ -0,0 +1,181 @@
+package resolver
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/lists"
+	"github.com/privacyherodev/ph-blocky/log"
+
+	"github.com/go-chi/chi"
+)
+
+// creates the overlay with entries added at runtime, which keeps the entries of the list caches up to date
+func createOverlay(file string, caches map[lists.ListCacheType]*lists.ListCache) *lists.Overlay {
+	overlay, err := lists.NewOverlay(file)
+	if err != nil {
+		log.Logger.Errorf("%v, list entries added via API will not be stored", err)
+
+		overlay, _ = lists.NewOverlay("")
+	}
+
+	for t, cache := range caches {
+		overlay.Attach(t, cache)
+	}
+
+	return overlay
+}
+
+// listEntriesParams returns the list type and the group from the request path.
+// The group must be defined in the black or white lists of the list type
+func (r *BlockingResolver) listEntriesParams(rw http.ResponseWriter, req *http.Request) (lists.ListCacheType, string,
+	bool) {
+	t, err := lists.ParseListCacheType(chi.URLParam(req, "type"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return t, "", false
+	}
+
+	group := chi.URLParam(req, "group")
+
+	groups, name := r.cfg.BlackLists, "blackLists"
+	if t == lists.WHITELIST {
+		groups, name = r.cfg.WhiteLists, "whiteLists"
+	}
+
+	if _, found := groups[group]; !found {
+		http.Error(rw, "group '"+group+"' is not defined in "+name, http.StatusBadRequest)
+
+		return t, "", false
+	}
+
+	return t, group, true
+}
+
+func (r *BlockingResolver) writeListEntries(rw http.ResponseWriter, t lists.ListCacheType, group string) {
+	response, _ := json.Marshal(api.ListEntries{
+		ListType: t.String(),
+		Group:    group,
+		Entries:  r.overlay.Entries(t, group),
+	})
+
+	rw.Header().Set("Content-Type", "application/json")
+
+	if _, err := rw.Write(response); err != nil {
+		log.Logger.Fatal("unable to write response ", err)
+	}
+}
+
+// apiListEntries is the http endpoint to get the entries of a group, which were added at runtime
+// @Summary List entries
+// @Description get the entries of the group, which were added at runtime
+// @Tags lists
+// @Produce  json
+// @Param type path string true "list type: blacklist or whitelist"
+// @Param group path string true "group"
+// @Success 200 {object} api.ListEntries "Returns the entries"
+// @Failure 400   "Unknown list type or group"
+// @Router /lists/{type}/{group}/entries [get]
+func (r *BlockingResolver) apiListEntries(rw http.ResponseWriter, req *http.Request) {
+	if t, group, ok := r.listEntriesParams(rw, req); ok {
+		r.writeListEntries(rw, t, group)
+	}
+}
+
+// apiListEntriesAdd is the http endpoint to add an entry to a group
+// @Summary Add list entry
+// @Description add a domain name, wildcard or IP address to the black or white list of the group.
+// @Description The entry is matched immediately and kept on list refreshes and restarts
+// @Tags lists
+// @Accept  json
+// @Produce  json
+// @Param type path string true "list type: blacklist or whitelist"
+// @Param group path string true "group"
+// @Param entry body api.ListEntryRequest true "entry to add"
+// @Success 200 {object} api.ListEntries "Entry was added, returns all entries of the group"
+// @Failure 400   "Unknown list type or group or invalid entry"
+// @Failure 500   "Entry can't be stored"
+// @Router /lists/{type}/{group}/entries [post]
+func (r *BlockingResolver) apiListEntriesAdd(rw http.ResponseWriter, req *http.Request) {
+	t, group, ok := r.listEntriesParams(rw, req)
+	if !ok {
+		return
+	}
+
+	var entryRequest api.ListEntryRequest
+	if err := json.NewDecoder(req.Body).Decode(&entryRequest); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	entry, err := lists.NormalizeEntry(entryRequest.Entry)
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	added, err := r.overlay.Add(t, group, entry)
+	if err != nil {
+		log.Logger.Error("can't add list entry: ", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if added {
+		log.Logger.Infof("added '%s' to %s of group '%s'", entry, t, group)
+	}
+
+	r.writeListEntries(rw, t, group)
+}
+
+// apiListEntriesRemove is the http endpoint to remove an entry, which was added at runtime, from a group
+// @Summary Remove list entry
+// @Description remove an entry, which was added at runtime, from the black or white list of the group
+// @Tags lists
+// @Produce  json
+// @Param type path string true "list type: blacklist or whitelist"
+// @Param group path string true "group"
+// @Param entry path string true "entry to remove"
+// @Success 200 {object} api.ListEntries "Entry was removed, returns all entries of the group"
+// @Failure 400   "Unknown list type or group or invalid escaping of the entry"
+// @Failure 404   "Group doesn't contain the entry"
+// @Failure 500   "Entry can't be removed from the store"
+// @Router /lists/{type}/{group}/entries/{entry} [delete]
+func (r *BlockingResolver) apiListEntriesRemove(rw http.ResponseWriter, req *http.Request) {
+	t, group, ok := r.listEntriesParams(rw, req)
+	if !ok {
+		return
+	}
+
+	// the router matches the escaped path, IP ranges contain an escaped slash ("10.0.0.0%2F8")
+	entry, err := url.PathUnescape(chi.URLParam(req, "entry"))
+	if err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	removed, err := r.overlay.Remove(t, group, entry)
+	if err != nil {
+		log.Logger.Error("can't remove list entry: ", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+
+	if !removed {
+		http.Error(rw, "group '"+group+"' doesn't contain the entry '"+entry+"'", http.StatusNotFound)
+
+		return
+	}
+
+	log.Logger.Infof("removed '%s' from %s of group '%s'", entry, t, group)
+
+	r.writeListEntries(rw, t, group)
+}
@@ -0,0 +1,201 @@
+package resolver
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/lists"
+	"github.com/privacyherodev/ph-blocky/log"
+)
+
+// listsRefresher executes on-demand refreshes of black and white lists in background and keeps the
+// status of the last refresh
+type listsRefresher struct {
+	caches map[lists.ListCacheType]*lists.ListCache
+	lock   sync.RWMutex
+	status api.ListsRefreshStatus
+}
+
+// refreshTask is a group of a list cache, which should be refreshed
+type refreshTask struct {
+	cache *lists.ListCache
+	group string
+}
+
+func newListsRefresher(blacklist, whitelist *lists.ListCache) *listsRefresher {
+	return &listsRefresher{
+		caches: map[lists.ListCacheType]*lists.ListCache{
+			lists.BLACKLIST: blacklist,
+			lists.WHITELIST: whitelist,
+		},
+	}
+}
+
+// tasks returns the groups to refresh for the list type ("blacklist", "whitelist" or empty for both)
+// and the group (empty for all groups)
+func (r *listsRefresher) tasks(listType, group string) ([]refreshTask, error) {
+	var types []lists.ListCacheType
+
+	if listType == "" {
+		types = []lists.ListCacheType{lists.BLACKLIST, lists.WHITELIST}
+	} else {
+		t, err := lists.ParseListCacheType(listType)
+		if err != nil {
+			return nil, err
+		}
+
+		types = []lists.ListCacheType{t}
+	}
+
+	var tasks []refreshTask
+
+	for _, t := range types {
+		for _, g := range r.caches[t].Groups() {
+			if group == "" || g == group {
+				tasks = append(tasks, refreshTask{cache: r.caches[t], group: g})
+			}
+		}
+	}
+
+	if len(tasks) == 0 && group != "" {
+		return nil, fmt.Errorf("group '%s' is not defined", group)
+	}
+
+	return tasks, nil
+}
+
+// start triggers the refresh in background. Returns false, if a refresh is already running
+func (r *listsRefresher) start(listType, group string, tasks []refreshTask) bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if r.status.Running {
+		return false
+	}
+
+	now := time.Now()
+	r.status = api.ListsRefreshStatus{
+		Running:     true,
+		ListType:    strings.ToLower(listType),
+		Group:       group,
+		StartedAt:   &now,
+		GroupsTotal: len(tasks),
+	}
+
+	go r.run(tasks)
+
+	return true
+}
+
+func (r *listsRefresher) run(tasks []refreshTask) {
+	for _, task := range tasks {
+		err := task.cache.RefreshGroup(task.group)
+
+		r.lock.Lock()
+		r.status.GroupsDone++
+
+		if err != nil {
+			r.status.Errors = append(r.status.Errors, err.Error())
+		}
+
+		r.status.Errors = append(r.status.Errors, sourceErrors(task.cache, task.group)...)
+		r.lock.Unlock()
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	now := time.Now()
+	r.status.Running = false
+	r.status.FinishedAt = &now
+
+	log.Logger.Infof("list refresh finished: %d group(s), %d error(s)",
+		r.status.GroupsDone, len(r.status.Errors))
+}
+
+// sourceErrors returns the errors of the last processing of the lists of the group
+func sourceErrors(cache *lists.ListCache, group string) (result []string) {
+	for _, g := range cache.Status() {
+		if g.Group != group {
+			continue
+		}
+
+		for _, s := range g.Sources {
+			if len(s.LastError) > 0 {
+				result = append(result, fmt.Sprintf("%s: %s: %s", group, s.Link, s.LastError))
+			}
+		}
+	}
+
+	return
+}
+
+func (r *listsRefresher) currentStatus() api.ListsRefreshStatus {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	status := r.status
+	status.Errors = append([]string(nil), r.status.Errors...)
+
+	return status
+}
+
+func (r *listsRefresher) writeStatus(rw http.ResponseWriter, statusCode int) {
+	response, _ := json.Marshal(r.currentStatus())
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(statusCode)
+
+	if _, err := rw.Write(response); err != nil {
+		log.Logger.Fatal("unable to write response ", err)
+	}
+}
+
+// apiListsRefresh is the http endpoint to trigger the refresh of black and white lists
+// @Summary Refresh lists
+// @Description download and parse black and white lists in background, the progress can be requested with GET
+// @Tags lists
+// @Produce  json
+// @Param type query string false "list type to refresh: blacklist or whitelist (default: both)"
+// @Param group query string false "group to refresh (default: all groups)"
+// @Success 202 {object} api.ListsRefreshStatus "Refresh was started"
+// @Failure 400   "Unknown list type or group"
+// @Failure 409 {object} api.ListsRefreshStatus "Another refresh is running"
+// @Router /lists/refresh [post]
+func (r *BlockingResolver) apiListsRefresh(rw http.ResponseWriter, req *http.Request) {
+	listType := req.URL.Query().Get("type")
+	group := req.URL.Query().Get("group")
+
+	tasks, err := r.listsRefresher.tasks(listType, group)
+	if err != nil {
+		log.Logger.Errorf("can't refresh lists: %v", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	if !r.listsRefresher.start(listType, group, tasks) {
+		r.listsRefresher.writeStatus(rw, http.StatusConflict)
+
+		return
+	}
+
+	log.Logger.Infof("refreshing %d list group(s)...", len(tasks))
+	r.listsRefresher.writeStatus(rw, http.StatusAccepted)
+}
+
+// apiListsRefreshStatus is the http endpoint to get the progress and result of the last list refresh
+// @Summary List refresh status
+// @Description get the progress and result of the last list refresh
+// @Tags lists
+// @Produce  json
+// @Success 200 {object} api.ListsRefreshStatus "Returns the status of the last refresh"
+// @Router /lists/refresh [get]
+func (r *BlockingResolver) apiListsRefreshStatus(rw http.ResponseWriter, _ *http.Request) {
+	r.listsRefresher.writeStatus(rw, http.StatusOK)
+}
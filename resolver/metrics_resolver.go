@@ -28,7 +28,7 @@ func (m *MetricsResolver) Resolve(request *Request) (*Response, error) {
 
 	if m.cfg.Enable {
 		m.totalQueries.With(prometheus.Labels{
-			"client": strings.Join(request.ClientNames, ","),
+			"client": strings.Join(request.Client.Names, ","),
 			"type":   dns.TypeToString[request.Req.Question[0].Qtype]}).Inc()
 
 		if err != nil {
@@ -76,6 +76,15 @@ func NewMetricsResolver(cfg config.PrometheusConfig) ChainedResolver {
 	}
 }
 
+// Reuse returns a new resolver sharing the registered metrics with this one, the next resolver of this one is not
+// changed
+func (m *MetricsResolver) Reuse() ChainedResolver {
+	res := *m
+	res.next = nil
+
+	return &res
+}
+
 func totalQueriesMetric() *prometheus.CounterVec {
 	return prometheus.NewCounterVec(
 		prometheus.CounterOpts{
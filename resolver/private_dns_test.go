@@ -189,6 +189,30 @@ badcnamedomain.com`)
 			req:  newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "1.2.1.2", []byte{72, 82, 74}, "unknown"),
 			want: []string{"adblock", "adult"},
 		},
+		{
+			name: "configured toggle gambling requested by client",
+			blockingCfg: config.BlockingConfig{
+				ClientGroupsBlock: map[string][]string{
+					"48:52:4a": {"gambling", "social"},
+				},
+				Toggles: []string{"gambling", "social"},
+				Global:  map[string]bool{"gambling": true},
+			},
+			req:  newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "1.2.1.2", []byte{72, 82, 74}, "unknown"),
+			want: []string{"gambling"},
+		},
+		{
+			name: "default toggle is ignored if toggles are configured",
+			blockingCfg: config.BlockingConfig{
+				ClientGroupsBlock: map[string][]string{
+					"48:52:4a": {"adblock", "gambling"},
+				},
+				Toggles: []string{"gambling"},
+				Global:  map[string]bool{"adblock": true, "gambling": true},
+			},
+			req:  newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "1.2.1.2", []byte{72, 82, 74}, "unknown"),
+			want: []string{"gambling"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -201,11 +225,10 @@ badcnamedomain.com`)
 }
 func newRequestWithClientAndEDNS0(question string, rType uint16, ip string, mac []byte, clientNames ...string) *Request {
 	return &Request{
-		ClientIP:    net.ParseIP(ip),
-		ClientNames: clientNames,
-		Req:         newMsgWithEDNS0(question, rType, mac),
-		Log:         logrus.NewEntry(logrus.New()),
-		RequestTS:   time.Time{},
+		Client:    Client{IP: net.ParseIP(ip), Names: clientNames, MAC: net.HardwareAddr(mac).String()},
+		Req:       newMsgWithEDNS0(question, rType, mac),
+		Log:       logrus.NewEntry(logrus.New()),
+		RequestTS: time.Time{},
 	}
 }
 
@@ -32,6 +32,8 @@ type QueryLoggingResolver struct {
 	perClient        bool
 	logRetentionDays uint64
 	logChan          chan *queryLogEntry
+	// closed to stop the log writer and the clean up
+	stop chan struct{}
 }
 
 type queryLogEntry struct {
@@ -44,7 +46,9 @@ type queryLogEntry struct {
 
 func NewQueryLoggingResolver(cfg config.QueryLogConfig) ChainedResolver {
 	if _, err := os.Stat(cfg.Dir); cfg.Dir != "" && err != nil && os.IsNotExist(err) {
-		logger(queryLoggingResolverPrefix).Fatalf("query log directory '%s' does not exist or is not writable", cfg.Dir)
+		logger(queryLoggingResolverPrefix).Fatalf("query log directory '%s' does not exist", cfg.Dir)
+	} else if cfg.Dir != "" && !writable(cfg.Dir) {
+		logger(queryLoggingResolverPrefix).Fatalf("query log directory '%s' is not writable", cfg.Dir)
 	}
 
 	logChan := make(chan *queryLogEntry, logChanCap)
@@ -54,6 +58,7 @@ func NewQueryLoggingResolver(cfg config.QueryLogConfig) ChainedResolver {
 		perClient:        cfg.PerClient,
 		logRetentionDays: cfg.LogRetentionDays,
 		logChan:          logChan,
+		stop:             make(chan struct{}),
 	}
 
 	go resolver.writeLog()
@@ -65,17 +70,48 @@ func NewQueryLoggingResolver(cfg config.QueryLogConfig) ChainedResolver {
 	return &resolver
 }
 
+// writable returns true if a file can be created in the directory
+func writable(dir string) bool {
+	f, err := ioutil.TempFile(dir, ".blocky-*")
+	if err != nil {
+		return false
+	}
+
+	_ = f.Close()
+	_ = os.Remove(f.Name())
+
+	return true
+}
+
+// Reuse returns a new resolver sharing the log writer with this one, the next resolver of this one is not changed
+func (r *QueryLoggingResolver) Reuse() ChainedResolver {
+	res := *r
+	res.next = nil
+
+	return &res
+}
+
 // triggers periodically cleanup of old log files
 func (r *QueryLoggingResolver) periodicCleanUp() {
 	ticker := time.NewTicker(cleanUpRunPeriod)
 	defer ticker.Stop()
 
 	for {
-		<-ticker.C
-		r.doCleanUp()
+		select {
+		case <-ticker.C:
+			r.doCleanUp()
+		case <-r.stop:
+			return
+		}
 	}
 }
 
+// Stop ends the log writer and the clean up of old log files. Queued entries are written, entries of requests
+// which are still resolved by the old chain are dropped
+func (r *QueryLoggingResolver) Stop() {
+	close(r.stop)
+}
+
 // deletes old log files
 func (r *QueryLoggingResolver) doCleanUp() {
 	logger := logger(queryLoggingResolverPrefix)
@@ -135,58 +171,75 @@ func (r *QueryLoggingResolver) Resolve(request *Request) (*Response, error) {
 	return resp, err
 }
 
-// write entry: if log directory is configured, write to log file
+// writes the entries until the resolver is stopped, entries which were queued before are written on stop
 func (r *QueryLoggingResolver) writeLog() {
-	for logEntry := range r.logChan {
-		if r.logDir != "" {
-			var clientPrefix string
+	for {
+		select {
+		case logEntry := <-r.logChan:
+			r.writeEntry(logEntry)
+		case <-r.stop:
+			for {
+				select {
+				case logEntry := <-r.logChan:
+					r.writeEntry(logEntry)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
 
-			start := time.Now()
+// write entry: if log directory is configured, write to log file
+func (r *QueryLoggingResolver) writeEntry(logEntry *queryLogEntry) {
+	if r.logDir != "" {
+		var clientPrefix string
 
-			dateString := logEntry.start.Format("2006-01-02")
+		start := time.Now()
 
-			if r.perClient {
-				clientPrefix = strings.Join(logEntry.request.ClientNames, "-")
-			} else {
-				clientPrefix = "ALL"
-			}
+		dateString := logEntry.start.Format("2006-01-02")
 
-			fileName := fmt.Sprintf("%s_%s.log", dateString, escape(clientPrefix))
-			writePath := filepath.Join(r.logDir, fileName)
+		if r.perClient {
+			clientPrefix = strings.Join(logEntry.request.Client.Names, "-")
+		} else {
+			clientPrefix = "ALL"
+		}
 
-			file, err := os.OpenFile(writePath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
+		fileName := fmt.Sprintf("%s_%s.log", dateString, escape(clientPrefix))
+		writePath := filepath.Join(r.logDir, fileName)
 
-			if err != nil {
-				logEntry.logger.WithField("file_name", writePath).Error("can't create/open file", err)
-			} else {
-				writer := createCsvWriter(file)
+		file, err := os.OpenFile(writePath, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0666)
 
-				err := writer.Write(createQueryLogRow(logEntry))
-				if err != nil {
-					logEntry.logger.WithField("file_name", writePath).Error("can't write to file", err)
-				}
-				writer.Flush()
+		if err != nil {
+			logEntry.logger.WithField("file_name", writePath).Error("can't create/open file", err)
+		} else {
+			writer := createCsvWriter(file)
 
-				_ = file.Close()
+			err := writer.Write(createQueryLogRow(logEntry))
+			if err != nil {
+				logEntry.logger.WithField("file_name", writePath).Error("can't write to file", err)
 			}
+			writer.Flush()
 
-			halfCap := cap(r.logChan) / 2
+			_ = file.Close()
+		}
 
-			// if log channel is > 50% full, this could be a problem with slow writer (external storage over network etc.)
-			if len(r.logChan) > halfCap {
-				logEntry.logger.WithField("channel_len",
-					len(r.logChan)).Warnf("query log writer is too slow, write duration: %d ms", time.Since(start).Milliseconds())
-			}
-		} else {
-			logEntry.logger.WithFields(
-				logrus.Fields{
-					"response_reason": logEntry.response.Reason,
-					"response_code":   dns.RcodeToString[logEntry.response.Res.Rcode],
-					"answer":          util.AnswerToString(logEntry.response.Res.Answer),
-					"duration_ms":     logEntry.durationMs,
-				},
-			).Infof("query resolved")
+		halfCap := cap(r.logChan) / 2
+
+		// if log channel is > 50% full, this could be a problem with slow writer (external storage over network etc.)
+		if len(r.logChan) > halfCap {
+			logEntry.logger.WithField("channel_len",
+				len(r.logChan)).Warnf("query log writer is too slow, write duration: %d ms", time.Since(start).Milliseconds())
 		}
+	} else {
+		logEntry.logger.WithFields(
+			logrus.Fields{
+				"response_reason": logEntry.response.Reason,
+				"response_code":   dns.RcodeToString[logEntry.response.Res.Rcode],
+				"answer":          util.AnswerToString(logEntry.response.Res.Answer),
+				"duration_ms":     logEntry.durationMs,
+			},
+		).Infof("query resolved")
 	}
 }
 
@@ -205,16 +258,11 @@ func createCsvWriter(file io.Writer) *csv.Writer {
 func createQueryLogRow(logEntry *queryLogEntry) []string {
 	request := logEntry.request
 	response := logEntry.response
-	clientID := request.ClientIP.String()
-
-	if request.ClientIP.String() == "127.0.0.1" {
-		clientID, _ = getMacFromEDNS0(logEntry.request.Req)
-	}
 
 	return []string{
 		logEntry.start.Format("2006-01-02 15:04:05"),
-		clientID,
-		strings.Join(request.ClientNames, "; "),
+		request.Client.Identifier(),
+		strings.Join(request.Client.Names, "; "),
 		fmt.Sprintf("%d", logEntry.durationMs),
 		response.Reason,
 		util.QuestionToString(request.Req.Question),
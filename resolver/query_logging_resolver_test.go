@@ -146,6 +146,29 @@ var _ = Describe("QueryLoggingResolver", func() {
 				})
 			})
 		})
+		When("Client is identified by MAC address", func() {
+			BeforeEach(func() {
+				sutConfig = config.QueryLogConfig{
+					Dir:       tmpDir,
+					PerClient: false,
+				}
+				mockAnswer, _ = util.NewMsgWithAnswer("example.com.", 300, dns.TypeA, "123.122.121.120")
+			})
+			It("should log the MAC address of each client instead of its IP address", func() {
+				request := newRequestWithClientAndEDNS0("example.com.", dns.TypeA, "192.168.178.25",
+					[]byte{0x48, 0x52, 0x4a, 0, 0, 1}, "client1")
+				resp, err = sut.Resolve(request)
+				Expect(err).Should(Succeed())
+
+				time.Sleep(100 * time.Millisecond)
+
+				csvLines := readCsv(filepath.Join(tmpDir, fmt.Sprintf("%s_ALL.log", time.Now().Format("2006-01-02"))))
+
+				Expect(csvLines).Should(HaveLen(1))
+				Expect(csvLines[0][1]).Should(Equal("48:52:4a:00:00:01"))
+				Expect(csvLines[0][2]).Should(Equal("client1"))
+			})
+		})
 	})
 
 	Describe("Configuration output", func() {
@@ -189,6 +212,25 @@ var _ = Describe("QueryLoggingResolver", func() {
 				Expect(fatal).Should(BeTrue())
 			})
 		})
+		When("Log directory is not writable", func() {
+			It("should exit with error", func() {
+				if os.Geteuid() == 0 {
+					Skip("root can write to read only directories")
+				}
+
+				defer func() { log.Logger.ExitFunc = nil }()
+
+				Expect(os.Chmod(tmpDir, 0500)).Should(Succeed())
+				defer func() { _ = os.Chmod(tmpDir, 0700) }()
+
+				var fatal bool
+
+				log.Logger.ExitFunc = func(int) { fatal = true }
+				_ = NewQueryLoggingResolver(config.QueryLogConfig{Dir: tmpDir})
+
+				Expect(fatal).Should(BeTrue())
+			})
+		})
 		When("not existing log directory is configured, log retention is enabled", func() {
 			It("should exit with error", func() {
 				defer func() { log.Logger.ExitFunc = nil }()
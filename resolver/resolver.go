@@ -14,11 +14,10 @@ import (
 )
 
 type Request struct {
-	ClientIP    net.IP
-	ClientNames []string
-	Req         *dns.Msg
-	Log         *logrus.Entry
-	RequestTS   time.Time
+	Client    Client
+	Req       *dns.Msg
+	Log       *logrus.Entry
+	RequestTS time.Time
 }
 
 func newRequest(question string, rType uint16) *Request {
@@ -30,11 +29,10 @@ func newRequest(question string, rType uint16) *Request {
 
 func newRequestWithClient(question string, rType uint16, ip string, clientNames ...string) *Request {
 	return &Request{
-		ClientIP:    net.ParseIP(ip),
-		ClientNames: clientNames,
-		Req:         util.NewMsgWithQuestion(question, rType),
-		Log:         logrus.NewEntry(logrus.New()),
-		RequestTS:   time.Time{},
+		Client:    Client{IP: net.ParseIP(ip), Names: clientNames},
+		Req:       util.NewMsgWithQuestion(question, rType),
+		Log:       logrus.NewEntry(logrus.New()),
+		RequestTS: time.Time{},
 	}
 }
 
@@ -75,6 +73,14 @@ type ChainedResolver interface {
 	GetNext() Resolver
 }
 
+// ReusableResolver is a resolver with warm state (caches, downloaded lists), which can be taken over by a new chain
+type ReusableResolver interface {
+	ChainedResolver
+	// Reuse returns a new resolver without next resolver, which shares the state with this one. The running chain of
+	// this resolver is not changed
+	Reuse() ChainedResolver
+}
+
 type NextResolver struct {
 	next Resolver
 }
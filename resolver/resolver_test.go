@@ -1,9 +1,12 @@
 package resolver
 
 import (
+	"net"
+
 	"github.com/privacyherodev/ph-blocky/config"
 
 	"github.com/go-chi/chi"
+	"github.com/miekg/dns"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -13,7 +16,7 @@ var _ = Describe("Resolver", func() {
 		When("A chain of resolvers will be created", func() {
 			It("should be iterable by calling 'GetNext'", func() {
 				ch := Chain(NewBlockingResolver(chi.NewRouter(),
-					config.BlockingConfig{}), NewClientNamesResolver(config.ClientLookupConfig{}))
+					config.BlockingConfig{}), NewClientIdentityResolver(config.ClientLookupConfig{}))
 				c, ok := ch.(ChainedResolver)
 				Expect(ok).Should(BeTrue())
 
@@ -28,4 +31,43 @@ var _ = Describe("Resolver", func() {
 			})
 		})
 	})
+	Describe("Explaining a request", func() {
+		var chain Resolver
+		BeforeEach(func() {
+			chain = Chain(
+				NewClientIdentityResolver(config.ClientLookupConfig{
+					ClientnameIPMapping: map[string][]net.IP{"laptop": {net.ParseIP("192.168.178.10")}},
+				}),
+				NewCustomDNSResolver(config.CustomDNSConfig{
+					Mapping: map[string]net.IP{"custom.domain": net.ParseIP("192.168.143.123")},
+				}),
+				NewBlockingResolver(chi.NewRouter(), config.BlockingConfig{}),
+				&resolverMock{})
+		})
+		When("a resolver decides the outcome", func() {
+			It("should stop at the decisive resolver", func() {
+				result := Explain(chain, newRequestWithClient("sub.custom.domain.", dns.TypeA, "192.168.178.10"))
+
+				Expect(result.Domain).Should(Equal("sub.custom.domain"))
+				Expect(result.Outcome).Should(Equal("CUSTOM DNS"))
+				Expect(result.DecidedBy).Should(Equal("CustomDNSResolver"))
+				Expect(result.Steps).Should(HaveLen(2))
+				Expect(result.Steps[0].Resolver).Should(Equal("ClientIdentityResolver"))
+				Expect(result.Steps[0].Result).Should(Equal("client names: laptop"))
+				Expect(result.Steps[1].Details).Should(ConsistOf(
+					"domain 'sub.custom.domain' matches mapping 'custom.domain' = 192.168.143.123"))
+			})
+		})
+		When("no resolver decides the outcome", func() {
+			It("should walk through the whole chain", func() {
+				result := Explain(chain, newRequestWithClient("example.com.", dns.TypeA, "192.168.178.10"))
+
+				Expect(result.Outcome).Should(Equal("RESOLVED"))
+				Expect(result.DecidedBy).Should(Equal("resolverMock"))
+				Expect(result.Steps).Should(HaveLen(3))
+				Expect(result.Steps[2].Resolver).Should(Equal("BlockingResolver"))
+				Expect(result.Steps[2].Decisive).Should(BeFalse())
+			})
+		})
+	})
 })
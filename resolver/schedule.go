@@ -0,0 +1,151 @@
+package resolver
+
+import (
+	"sort"
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/config"
+)
+
+// schedule is the parsed schedule configuration, groups with a schedule are only active inside of its windows
+type schedule struct {
+	location *time.Location
+	windows  []scheduleWindow
+}
+
+type scheduleWindow struct {
+	// week days on which the window starts, nil means every day
+	days map[time.Weekday]bool
+	// minutes since midnight
+	from int
+	to   int
+}
+
+// newSchedules parses the schedule configuration, invalid values were already reported by the config validation
+// and are logged here
+func newSchedules(cfg map[string]config.Schedule) map[string]*schedule {
+	result := make(map[string]*schedule, len(cfg))
+
+	for name, c := range cfg {
+		s := &schedule{location: time.Local}
+
+		if len(c.TimeZone) > 0 {
+			location, err := time.LoadLocation(c.TimeZone)
+			if err != nil {
+				logger("schedule").Errorf("schedule '%s': unknown time zone '%s', using local time zone", name, c.TimeZone)
+			} else {
+				s.location = location
+			}
+		}
+
+		for _, w := range c.Windows {
+			window, err := newScheduleWindow(w)
+			if err != nil {
+				logger("schedule").Errorf("schedule '%s': window will be ignored: %v", name, err)
+
+				continue
+			}
+
+			s.windows = append(s.windows, window)
+		}
+
+		result[name] = s
+	}
+
+	return result
+}
+
+func newScheduleWindow(cfg config.ScheduleWindow) (window scheduleWindow, err error) {
+	if window.from, err = config.ParseTimeOfDay(cfg.From); err != nil {
+		return
+	}
+
+	if window.to, err = config.ParseTimeOfDay(cfg.To); err != nil {
+		return
+	}
+
+	for _, d := range cfg.Days {
+		day, err := config.ParseWeekday(d)
+		if err != nil {
+			return window, err
+		}
+
+		if window.days == nil {
+			window.days = make(map[time.Weekday]bool)
+		}
+
+		window.days[day] = true
+	}
+
+	return window, nil
+}
+
+// active returns true if the time is inside of one of the windows
+func (s *schedule) active(now time.Time) bool {
+	now = now.In(s.location)
+	minutes := now.Hour()*60 + now.Minute()
+	today := now.Weekday()
+	yesterday := (today + 6) % 7
+
+	for _, w := range s.windows {
+		if w.from < w.to {
+			if w.startsOn(today) && minutes >= w.from && minutes < w.to {
+				return true
+			}
+
+			continue
+		}
+
+		// window ends on the next day
+		if (w.startsOn(today) && minutes >= w.from) || (w.startsOn(yesterday) && minutes < w.to) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (w scheduleWindow) startsOn(day time.Weekday) bool {
+	return w.days == nil || w.days[day]
+}
+
+// activeGroups removes the groups, whose schedule is currently not active, and the schedule suffix of the others
+func (r *BlockingResolver) activeGroups(entries []string) []string {
+	result := make([]string, 0, len(entries))
+	now := time.Now()
+
+	for _, entry := range entries {
+		group, name := config.SplitGroupSchedule(entry)
+		if len(name) == 0 || r.scheduleActive(name, now) {
+			result = append(result, group)
+		}
+	}
+
+	return result
+}
+
+// scheduleActive returns true if the schedule is active, unknown schedules are never active
+func (r *BlockingResolver) scheduleActive(name string, now time.Time) bool {
+	s, found := r.schedules[name]
+
+	return found && s.active(now)
+}
+
+// scheduleUsages returns for each schedule the client group entries which use it, for example "kids-tablet: social"
+func (r *BlockingResolver) scheduleUsages() map[string][]string {
+	result := make(map[string][]string)
+
+	for client, entries := range r.cfg.ClientGroupsBlock {
+		for _, entry := range entries {
+			if group, name := config.SplitGroupSchedule(entry); len(name) > 0 {
+				result[name] = append(result[name], client+": "+group)
+			}
+		}
+	}
+
+	for _, usages := range result {
+		sort.Strings(usages)
+	}
+
+	return result
+}
@@ -0,0 +1,70 @@
+package resolver
+
+import (
+	"time"
+
+	"github.com/privacyherodev/ph-blocky/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedule", func() {
+	var sut *schedule
+
+	BeforeEach(func() {
+		schedules := newSchedules(map[string]config.Schedule{
+			"school-nights": {
+				TimeZone: "Europe/Berlin",
+				Windows: []config.ScheduleWindow{
+					{Days: []string{"sun", "mon", "tue", "wed", "thu"}, From: "21:00", To: "07:00"},
+					{Days: []string{"sat"}, From: "12:00", To: "14:30"},
+				},
+			},
+		})
+		sut = schedules["school-nights"]
+	})
+
+	berlin := func(value string) time.Time {
+		location, err := time.LoadLocation("Europe/Berlin")
+		Expect(err).Should(Succeed())
+
+		t, err := time.ParseInLocation("Mon 2006-01-02 15:04", value, location)
+		Expect(err).Should(Succeed())
+
+		return t
+	}
+
+	DescribeTable("evaluation of windows",
+		func(at string, expected bool) {
+			Expect(sut.active(berlin(at))).Should(Equal(expected))
+		},
+		Entry("before the start on school night", "Mon 2021-03-01 20:59", false),
+		Entry("start on school night", "Mon 2021-03-01 21:00", true),
+		Entry("after midnight of school night", "Tue 2021-03-02 03:00", true),
+		Entry("end of the window on the next day", "Tue 2021-03-02 07:00", false),
+		Entry("friday evening", "Fri 2021-03-05 22:00", false),
+		Entry("early saturday morning after friday", "Sat 2021-03-06 03:00", false),
+		Entry("early monday morning after sunday", "Mon 2021-03-08 03:00", true),
+		Entry("saturday window", "Sat 2021-03-06 14:29", true),
+		Entry("end of saturday window", "Sat 2021-03-06 14:30", false),
+	)
+
+	It("should evaluate the windows in the time zone of the schedule", func() {
+		// 21:30 in Berlin
+		Expect(sut.active(time.Date(2021, 3, 1, 20, 30, 0, 0, time.UTC))).Should(BeTrue())
+		// 21:30 in UTC is 22:30 in Berlin on friday
+		Expect(sut.active(time.Date(2021, 3, 5, 21, 30, 0, 0, time.UTC))).Should(BeFalse())
+	})
+
+	It("should ignore invalid windows", func() {
+		schedules := newSchedules(map[string]config.Schedule{
+			"invalid": {Windows: []config.ScheduleWindow{{From: "25:00", To: "07:00"}, {From: "00:00", To: "24:00"}}},
+		})
+
+		Expect(schedules["invalid"].windows).Should(HaveLen(1))
+		Expect(schedules["invalid"].location).Should(Equal(time.Local))
+		Expect(schedules["invalid"].active(time.Now())).Should(BeTrue())
+	})
+})
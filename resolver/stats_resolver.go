@@ -88,6 +88,15 @@ func NewStatsResolver() ChainedResolver {
 	return resolver
 }
 
+// Reuse returns a new resolver sharing the collected statistics with this one, the next resolver of this one is not
+// changed
+func (r *StatsResolver) Reuse() ChainedResolver {
+	res := *r
+	res.next = nil
+
+	return &res
+}
+
 func (r *StatsResolver) printStats() {
 	logger := logger("stats_resolver")
 
@@ -123,7 +132,7 @@ func createRecorders() []*resolverStatRecorder {
 			return ""
 		}),
 		newRecorder("Query count per client", func(e *statsEntry) string {
-			return strings.Join(e.request.ClientNames, ",")
+			return strings.Join(e.request.Client.Names, ",")
 		}),
 		newRecorder("Reason", func(e *statsEntry) string {
 			return e.response.Reason
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"html/template"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/privacyherodev/ph-blocky/api"
+	"github.com/privacyherodev/ph-blocky/config"
+	"github.com/privacyherodev/ph-blocky/resolver"
+	"github.com/privacyherodev/ph-blocky/web"
+
+	"github.com/go-chi/chi"
+)
+
+var blockPageTemplate = template.Must(template.New("blockPage").Parse(web.BlockPageTmpl))
+
+// createBlockPageListeners opens the HTTP and HTTPS listeners of the block page, nil if the port is not configured
+func createBlockPageListeners(cfg *config.Config) (httpListener, httpsListener net.Listener, err error) {
+	if cfg.BlockPage.HTTPPort > 0 {
+		if httpListener, err = net.Listen("tcp", fmt.Sprintf(":%d", cfg.BlockPage.HTTPPort)); err != nil {
+			return nil, nil, fmt.Errorf("start block page http listener on port %d failed: %v", cfg.BlockPage.HTTPPort, err)
+		}
+	}
+
+	if cfg.BlockPage.HTTPSPort > 0 {
+		certificates, err := loadBlockPageCertificates(cfg)
+		if err == nil {
+			httpsListener, err = tls.Listen("tcp", fmt.Sprintf(":%d", cfg.BlockPage.HTTPSPort),
+				&tls.Config{GetCertificate: certificates.get})
+		}
+
+		if err != nil {
+			if httpListener != nil {
+				_ = httpListener.Close()
+			}
+
+			return nil, nil, fmt.Errorf("start block page https listener on port %d failed: %v",
+				cfg.BlockPage.HTTPSPort, err)
+		}
+	}
+
+	return httpListener, httpsListener, nil
+}
+
+// blockPageCertificates selects the certificate by the requested host name (SNI). If no certificate matches,
+// the default certificate is used
+type blockPageCertificates struct {
+	// certificates per DNS name, wildcard names like "*.example.com" are stored as they are
+	byName   map[string]*tls.Certificate
+	fallback *tls.Certificate
+}
+
+func loadBlockPageCertificates(cfg *config.Config) (*blockPageCertificates, error) {
+	defaultCertificate := cfg.BlockPageDefaultCertificate()
+
+	fallback, err := tls.LoadX509KeyPair(defaultCertificate.CertFile, defaultCertificate.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("can't load block page certificate '%s': %v", defaultCertificate.CertFile, err)
+	}
+
+	result := &blockPageCertificates{byName: make(map[string]*tls.Certificate), fallback: &fallback}
+
+	for _, c := range cfg.BlockPage.Certificates {
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("can't load block page certificate '%s': %v", c.CertFile, err)
+		}
+
+		leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+		if err != nil {
+			return nil, fmt.Errorf("can't parse block page certificate '%s': %v", c.CertFile, err)
+		}
+
+		for _, name := range leaf.DNSNames {
+			name = strings.ToLower(name)
+			if _, found := result.byName[name]; !found {
+				result.byName[name] = &certificate
+			}
+		}
+	}
+
+	return result, nil
+}
+
+// get returns the certificate for the exact host name, then for the wildcard name of its parent domain and
+// finally the default certificate
+func (c *blockPageCertificates) get(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+
+	if certificate, found := c.byName[name]; found {
+		return certificate, nil
+	}
+
+	if idx := strings.Index(name, "."); idx > 0 {
+		if certificate, found := c.byName["*"+name[idx:]]; found {
+			return certificate, nil
+		}
+	}
+
+	return c.fallback, nil
+}
+
+// blockPageHandler serves the block page for each path. Unblock requests of the page are passed to the API
+func (s *Server) blockPageHandler() http.Handler {
+	router := chi.NewRouter()
+
+	router.Post(api.UnblockRequestsPath, s.httpMux.ServeHTTP)
+	router.Get("/*", s.blockPage)
+
+	return router
+}
+
+func (s *Server) blockPage(rw http.ResponseWriter, req *http.Request) {
+	host := req.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if len(host) == 0 && req.TLS != nil {
+		host = req.TLS.ServerName
+	}
+
+	data := struct {
+		Host        string
+		Group       string
+		Reason      string
+		Found       bool
+		UnblockPath string
+	}{Host: host, UnblockPath: api.UnblockRequestsPath}
+
+	// the listener faces the clients directly, so X-Forwarded-For could be sent by any client to read foreign decisions
+	clientIP, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		clientIP = req.RemoteAddr
+	}
+
+	if b, ok := findResolver(s.getQueryResolver(), "BlockingResolver").(*resolver.BlockingResolver); ok {
+		if decision, found := b.BlockDecision(net.ParseIP(clientIP), host); found {
+			data.Group = decision.Group
+			data.Reason = decision.Reason
+			data.Found = true
+		}
+	}
+
+	rw.Header().Set("Cache-Control", "no-store")
+	rw.WriteHeader(http.StatusForbidden)
+
+	if err := blockPageTemplate.Execute(rw, data); err != nil {
+		logger().Error("can't write block page template: ", err)
+	}
+}
@@ -0,0 +1,74 @@
+package server
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/miekg/dns"
+)
+
+// client IDs consist of letters, digits and hyphens like a DNS label
+var clientIDPattern = regexp.MustCompile(`^[a-zA-Z0-9-]{1,63}$`) // nolint:gochecknoglobals
+
+// normalizeClientID returns the client ID in lower case, so it matches the keys of clientGroupsBlock, toggles and
+// pauses independent of the spelling. Returns false, if the client ID is invalid
+func normalizeClientID(clientID string) (string, bool) {
+	if !clientIDPattern.MatchString(clientID) {
+		return "", false
+	}
+
+	return strings.ToLower(clientID), true
+}
+
+// clientIDFromServerName returns the leftmost label of the TLS server name (SNI) in lower case, if the server name
+// is a direct subdomain of the client ID domain, for example "abc123" of "abc123.dns.example.com" for the domain
+// "dns.example.com". Otherwise the result is empty
+func clientIDFromServerName(serverName, domain string) string {
+	serverName = strings.TrimSuffix(strings.ToLower(serverName), ".")
+	domain = strings.Trim(strings.ToLower(domain), ".")
+
+	if len(domain) == 0 || !strings.HasSuffix(serverName, "."+domain) {
+		return ""
+	}
+
+	clientID, _ := normalizeClientID(strings.TrimSuffix(serverName, "."+domain))
+
+	return clientID
+}
+
+// clientIDFromTLS returns the client ID of the TLS server name, empty if the connection does not use TLS
+func (s *Server) clientIDFromTLS(state *tls.ConnectionState) string {
+	if state == nil {
+		return ""
+	}
+
+	return clientIDFromServerName(state.ServerName, s.getConfig().ClientLookup.ClientIDDomain)
+}
+
+// clientIDFromDNSConnection returns the client ID of a DNS over TLS connection
+func (s *Server) clientIDFromDNSConnection(w dns.ResponseWriter) string {
+	if stater, ok := w.(dns.ConnectionStater); ok {
+		return s.clientIDFromTLS(stater.ConnectionState())
+	}
+
+	return ""
+}
+
+// dohClientID returns the client ID in lower case of the DoH request path ("/dns-query/{clientID}"), otherwise of the
+// TLS server name
+func (s *Server) dohClientID(req *http.Request) (string, error) {
+	if pathID := chi.URLParam(req, "clientID"); len(pathID) > 0 {
+		clientID, ok := normalizeClientID(pathID)
+		if !ok {
+			return "", fmt.Errorf("invalid client ID '%s'", pathID)
+		}
+
+		return clientID, nil
+	}
+
+	return s.clientIDFromTLS(req.TLS), nil
+}
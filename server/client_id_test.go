@@ -0,0 +1,44 @@
+package server
+
+import (
+	"crypto/tls"
+
+	"github.com/privacyherodev/ph-blocky/config"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Client ID", func() {
+	DescribeTable("client ID of the TLS server name",
+		func(serverName, domain, expected string) {
+			Expect(clientIDFromServerName(serverName, domain)).Should(Equal(expected))
+		},
+		Entry("leftmost label below the domain", "abc123.dns.example.com", "dns.example.com", "abc123"),
+		Entry("upper case and trailing dot", "ABC123.DNS.example.com.", "dns.example.com", "abc123"),
+		Entry("domain with dots", "abc123.dns.example.com", ".dns.example.com.", "abc123"),
+		Entry("domain itself", "dns.example.com", "dns.example.com", ""),
+		Entry("more than one label below the domain", "a.b.dns.example.com", "dns.example.com", ""),
+		Entry("other domain", "abc123.dns.example.org", "dns.example.com", ""),
+		Entry("no domain configured", "abc123.dns.example.com", "", ""),
+	)
+
+	DescribeTable("normalized client ID",
+		func(clientID, expected string, valid bool) {
+			normalized, ok := normalizeClientID(clientID)
+			Expect(ok).Should(Equal(valid))
+			Expect(normalized).Should(Equal(expected))
+		},
+		Entry("lower case", "kids-tablet", "kids-tablet", true),
+		Entry("upper case", "Kids-Tablet", "kids-tablet", true),
+		Entry("invalid character", "kids_tablet", "", false),
+	)
+
+	It("should take the client ID of TLS connections only", func() {
+		sut := &Server{cfg: &config.Config{ClientLookup: config.ClientLookupConfig{ClientIDDomain: "dns.example.com"}}}
+
+		Expect(sut.clientIDFromTLS(&tls.ConnectionState{ServerName: "abc123.dns.example.com"})).Should(Equal("abc123"))
+		Expect(sut.clientIDFromTLS(nil)).Should(BeEmpty())
+	})
+})
@@ -1,10 +1,14 @@
 package server
 
 import (
+	"context"
+	"errors"
 	"github.com/privacyherodev/ph-blocky/log"
 	"net/http"
+	"reflect"
 	"runtime"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/privacyherodev/ph-blocky/config"
@@ -27,8 +31,16 @@ type Server struct {
 	httpListener  net.Listener
 	httpsListener net.Listener
 	queryResolver resolver.Resolver
-	cfg           *config.Config
-	httpMux       *chi.Mux
+	// router with the API endpoints registered by the resolvers of the current chain
+	resolverRouter *chi.Mux
+	resolverLock   sync.RWMutex
+	reloadLock     sync.Mutex
+	cfg            *config.Config
+	httpMux        *chi.Mux
+
+	// listeners of the block page, nil if not configured
+	blockPageListener    net.Listener
+	blockPageTLSListener net.Listener
 }
 
 func logger() *logrus.Entry {
@@ -78,16 +90,26 @@ func NewServer(cfg *config.Config) (server *Server, err error) {
 		metrics.Start(router, cfg.Prometheus)
 	}
 
-	queryResolver := createQueryResolver(cfg, router)
+	blockPageListener, blockPageTLSListener, err := createBlockPageListeners(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	resolverRouter := chi.NewRouter()
+	queryResolver := createQueryResolver(cfg, resolverRouter, nil, nil)
 
 	server = &Server{
-		udpServer:     udpServer,
-		tcpServer:     tcpServer,
-		queryResolver: queryResolver,
-		cfg:           cfg,
-		httpListener:  httpListener,
-		httpsListener: httpsListener,
-		httpMux:       router,
+		udpServer:      udpServer,
+		tcpServer:      tcpServer,
+		queryResolver:  queryResolver,
+		resolverRouter: resolverRouter,
+		cfg:            cfg,
+		httpListener:   httpListener,
+		httpsListener:  httpsListener,
+		httpMux:        router,
+
+		blockPageListener:    blockPageListener,
+		blockPageTLSListener: blockPageTLSListener,
 	}
 
 	server.printConfiguration()
@@ -99,21 +121,173 @@ func NewServer(cfg *config.Config) (server *Server, err error) {
 	return server, nil
 }
 
-func createQueryResolver(cfg *config.Config, router *chi.Mux) resolver.Resolver {
+// createQueryResolver creates the resolver chain. If the resolvers of the previous chain and its configuration are
+// passed, resolvers with warm state (caches, downloaded lists) are taken over if their config section is unchanged.
+// Taken over resolvers are removed from oldResolvers, the previous chain itself is not changed
+func createQueryResolver(cfg *config.Config, router *chi.Mux,
+	oldCfg *config.Config, oldResolvers map[string]resolver.Resolver) resolver.Resolver {
+	reuse := func(name string, section func(c *config.Config) interface{},
+		create func() resolver.Resolver) resolver.Resolver {
+		if oldCfg != nil && reflect.DeepEqual(section(oldCfg), section(cfg)) {
+			if res, ok := oldResolvers[name].(resolver.ReusableResolver); ok {
+				logger().Debugf("reusing resolver '%s'", name)
+				delete(oldResolvers, name)
+
+				return res.Reuse()
+			}
+		}
+
+		return create()
+	}
+
 	return resolver.Chain(
-		resolver.NewClientNamesResolver(cfg.ClientLookup),
-		resolver.NewQueryLoggingResolver(cfg.QueryLog),
-		resolver.NewStatsResolver(),
-		resolver.NewMetricsResolver(cfg.Prometheus),
+		reuse("ClientIdentityResolver",
+			func(c *config.Config) interface{} { return c.ClientLookup },
+			func() resolver.Resolver { return resolver.NewClientIdentityResolver(cfg.ClientLookup) }),
+		reuse("QueryLoggingResolver",
+			func(c *config.Config) interface{} { return c.QueryLog },
+			func() resolver.Resolver { return resolver.NewQueryLoggingResolver(cfg.QueryLog) }),
+		reuse("StatsResolver",
+			func(c *config.Config) interface{} { return nil },
+			func() resolver.Resolver { return resolver.NewStatsResolver() }),
+		reuse("MetricsResolver",
+			func(c *config.Config) interface{} { return c.Prometheus },
+			func() resolver.Resolver { return resolver.NewMetricsResolver(cfg.Prometheus) }),
+		// the ECS policy applies to all forwarded queries, also to the conditional upstream servers
+		resolver.NewECSResolver(cfg.Upstream.ECS),
 		resolver.NewConditionalUpstreamResolver(cfg.Conditional),
 		resolver.NewCustomDNSResolver(cfg.CustomDNS),
 		resolver.NewCnameResolver(cfg.Cname),
-		resolver.NewBlockingResolver(router, cfg.Blocking),
-		resolver.NewCachingResolver(cfg.Caching),
+		reuse("BlockingResolver",
+			func(c *config.Config) interface{} { return c.Blocking },
+			func() resolver.Resolver {
+				res := resolver.NewBlockingResolver(router, cfg.Blocking)
+				if replaced, ok := oldResolvers["BlockingResolver"].(*resolver.BlockingResolver); ok {
+					res.(*resolver.BlockingResolver).TakeOverState(replaced)
+				}
+
+				return res
+			}),
+		// cached answers depend on the client subnets, which are passed by the ECS policy
+		reuse("CachingResolver",
+			func(c *config.Config) interface{} { return []interface{}{c.Caching, c.Upstream.ECS} },
+			func() resolver.Resolver { return resolver.NewCachingResolver(cfg.Caching) }),
 		resolver.NewParallelBestResolver(cfg.Upstream),
 	)
 }
 
+// findResolver returns the resolver with passed name from the chain or nil
+func findResolver(chain resolver.Resolver, name string) resolver.Resolver {
+	return chainResolvers(chain)[name]
+}
+
+// chainResolvers returns the resolvers of the chain by name
+func chainResolvers(chain resolver.Resolver) map[string]resolver.Resolver {
+	result := make(map[string]resolver.Resolver)
+
+	res := chain
+	for res != nil {
+		result[resolver.Name(res)] = res
+
+		if c, ok := res.(resolver.ChainedResolver); ok {
+			res = c.GetNext()
+		} else {
+			break
+		}
+	}
+
+	return result
+}
+
+// Reload reads the configuration file again, creates a new resolver chain and replaces the current one.
+// DNS and HTTP listeners are not affected, changes of their configuration require a restart
+func (s *Server) Reload() error {
+	s.reloadLock.Lock()
+	defer s.reloadLock.Unlock()
+
+	if s.cfg.Path() == "" {
+		return errors.New("configuration was not loaded from a file, reload is not possible")
+	}
+
+	cfg, err := config.LoadConfig(s.cfg.Path())
+	if err != nil {
+		return fmt.Errorf("can't reload configuration: %v", err)
+	}
+
+	s.applyConfig(&cfg)
+
+	return nil
+}
+
+// applyConfig builds the resolver chain for the passed configuration and swaps it with the current one
+func (s *Server) applyConfig(cfg *config.Config) {
+	if cfg.Port != s.cfg.Port || cfg.HTTPPort != s.cfg.HTTPPort || cfg.HTTPSPort != s.cfg.HTTPSPort ||
+		cfg.CertFile != s.cfg.CertFile || cfg.KeyFile != s.cfg.KeyFile ||
+		cfg.BootstrapDNS != s.cfg.BootstrapDNS || cfg.Prometheus != s.cfg.Prometheus ||
+		!reflect.DeepEqual(cfg.BlockPage, s.cfg.BlockPage) {
+		logger().Warn("changes of listener ports, certificates, bootstrap DNS, prometheus or block page require a restart")
+	}
+
+	log.NewLogger(cfg.LogLevel, cfg.LogFormat)
+
+	oldResolvers := chainResolvers(s.getQueryResolver())
+	router := chi.NewRouter()
+	queryResolver := createQueryResolver(cfg, router, s.cfg, oldResolvers)
+
+	s.resolverLock.Lock()
+	if _, replaced := oldResolvers["BlockingResolver"]; !replaced {
+		// the reused BlockingResolver keeps its API endpoints on the current router
+		router = s.resolverRouter
+	}
+
+	s.queryResolver = queryResolver
+	s.resolverRouter = router
+	s.cfg = cfg
+	s.resolverLock.Unlock()
+
+	stopReplacedResolvers(oldResolvers)
+
+	logger().Info("configuration reloaded")
+	s.printConfiguration()
+}
+
+// stopReplacedResolvers stops background work (list refresh, timers) of the resolvers from the old chain, which
+// were not taken over by the new one
+func stopReplacedResolvers(replaced map[string]resolver.Resolver) {
+	for _, res := range replaced {
+		if s, ok := res.(interface{ Stop() }); ok {
+			s.Stop()
+		}
+	}
+}
+
+// serveResolverAPI serves the API endpoints registered by the resolvers of the current chain. Each chain has its own
+// router, replacing the chain never changes a router which serves requests
+func (s *Server) serveResolverAPI(rw http.ResponseWriter, req *http.Request) {
+	s.resolverLock.RLock()
+	router := s.resolverRouter
+	s.resolverLock.RUnlock()
+
+	// without the route context of this router, the resolver router matches the full path
+	router.ServeHTTP(rw, req.WithContext(context.WithValue(req.Context(), chi.RouteCtxKey, nil)))
+}
+
+// getQueryResolver returns the current resolver chain
+func (s *Server) getQueryResolver() resolver.Resolver {
+	s.resolverLock.RLock()
+	defer s.resolverLock.RUnlock()
+
+	return s.queryResolver
+}
+
+// getConfig returns the current configuration
+func (s *Server) getConfig() *config.Config {
+	s.resolverLock.RLock()
+	defer s.resolverLock.RUnlock()
+
+	return s.cfg
+}
+
 func (s *Server) registerDNSHandlers(server *dns.Server) {
 	handler := server.Handler.(*dns.ServeMux)
 	handler.HandleFunc(".", s.OnRequest)
@@ -123,7 +297,7 @@ func (s *Server) registerDNSHandlers(server *dns.Server) {
 func (s *Server) printConfiguration() {
 	logger().Info("current configuration:")
 
-	res := s.queryResolver
+	res := s.getQueryResolver()
 	for res != nil {
 		logger().Infof("-> resolver: '%s'", resolver.Name(res))
 
@@ -138,8 +312,15 @@ func (s *Server) printConfiguration() {
 		}
 	}
 
-	logger().Infof("- DNS listening port: %d", s.cfg.Port)
-	logger().Infof("- HTTP listening port: %d", s.cfg.HTTPPort)
+	cfg := s.getConfig()
+
+	logger().Infof("- DNS listening port: %d", cfg.Port)
+	logger().Infof("- HTTP listening port: %d", cfg.HTTPPort)
+
+	if s.blockPageListener != nil || s.blockPageTLSListener != nil {
+		logger().Infof("- block page listening ports: HTTP %d, HTTPS %d", cfg.BlockPage.HTTPPort,
+			cfg.BlockPage.HTTPSPort)
+	}
 
 	logger().Info("runtime information:")
 
@@ -199,7 +380,28 @@ func (s *Server) Start() {
 		}
 	}()
 
+	go func() {
+		if s.blockPageListener != nil {
+			logger().Infof("block page http server is up and running on port %d", s.cfg.BlockPage.HTTPPort)
+
+			if err := http.Serve(s.blockPageListener, s.blockPageHandler()); err != nil {
+				logger().Fatalf("start block page http listener failed: %v", err)
+			}
+		}
+	}()
+
+	go func() {
+		if s.blockPageTLSListener != nil {
+			logger().Infof("block page https server is up and running on port %d", s.cfg.BlockPage.HTTPSPort)
+
+			if err := http.Serve(s.blockPageTLSListener, s.blockPageHandler()); err != nil {
+				logger().Fatalf("start block page https listener failed: %v", err)
+			}
+		}
+	}()
+
 	registerPrintConfigurationTrigger(s)
+	registerReloadTrigger(s)
 }
 
 func (s *Server) Stop() {
@@ -222,7 +424,7 @@ func createResolverRequest(remoteAddress net.Addr, request *dns.Msg) *resolver.R
 
 func newRequest(clientIP net.IP, request *dns.Msg) *resolver.Request {
 	return &resolver.Request{
-		ClientIP:  clientIP,
+		Client:    resolver.Client{IP: clientIP},
 		Req:       request,
 		RequestTS: time.Now(),
 		Log: log.Logger.WithFields(logrus.Fields{
@@ -236,8 +438,9 @@ func (s *Server) OnRequest(w dns.ResponseWriter, request *dns.Msg) {
 	logger().Debug("new request")
 
 	r := createResolverRequest(w.RemoteAddr(), request)
+	r.Client.ID = s.clientIDFromDNSConnection(w)
 
-	response, err := s.queryResolver.Resolve(r)
+	response, err := s.getQueryResolver().Resolve(r)
 
 	if err != nil {
 		logger().Errorf("error on processing request: %v", err)
@@ -9,7 +9,7 @@ import (
 )
 
 func registerPrintConfigurationTrigger(s *Server) {
-	signals := make(chan os.Signal)
+	signals := make(chan os.Signal, 1)
 	signal.Notify(signals, syscall.SIGUSR1)
 
 	go func() {
@@ -19,3 +19,19 @@ func registerPrintConfigurationTrigger(s *Server) {
 		}
 	}()
 }
+
+func registerReloadTrigger(s *Server) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+
+	go func() {
+		for {
+			<-signals
+			logger().Info("SIGHUP received, reloading configuration")
+
+			if err := s.Reload(); err != nil {
+				logger().Error("reload failed, keeping current configuration: ", err)
+			}
+		}
+	}()
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/privacyherodev/ph-blocky/api"
 	"github.com/privacyherodev/ph-blocky/config"
 	"github.com/privacyherodev/ph-blocky/docs"
+	"github.com/privacyherodev/ph-blocky/resolver"
 	"github.com/privacyherodev/ph-blocky/util"
 	"github.com/privacyherodev/ph-blocky/web"
 	"html/template"
@@ -30,9 +31,19 @@ const (
 
 func (s *Server) registerAPIEndpoints(router *chi.Mux) {
 	router.Post(api.BlockingQueryPath, s.apiQuery)
+	router.Post(api.ConfigReloadPath, s.apiConfigReload)
+	router.Post(api.ExplainPath, s.apiExplain)
+
+	// endpoints of blocking and lists are registered by the BlockingResolver of the current chain
+	router.Handle("/api/blocking/*", http.HandlerFunc(s.serveResolverAPI))
+	router.Handle(api.ListsPath, http.HandlerFunc(s.serveResolverAPI))
+	router.Handle(api.ListsPath+"/*", http.HandlerFunc(s.serveResolverAPI))
 
 	router.Get("/dns-query", s.dohGetRequestHandler)
 	router.Post("/dns-query", s.dohPostRequestHandler)
+	// client ID in the path identifies the device, for example to assign groups in clientGroupsBlock
+	router.Get("/dns-query/{clientID}", s.dohGetRequestHandler)
+	router.Post("/dns-query/{clientID}", s.dohPostRequestHandler)
 }
 
 func (s *Server) dohGetRequestHandler(rw http.ResponseWriter, req *http.Request) {
@@ -96,7 +107,13 @@ func (s *Server) processDohMessage(rawMsg []byte, rw http.ResponseWriter, req *h
 
 	r := newRequest(net.ParseIP(extractIP(req)), msg)
 
-	resResponse, err := s.queryResolver.Resolve(r)
+	if r.Client.ID, err = s.dohClientID(req); err != nil {
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	resResponse, err := s.getQueryResolver().Resolve(r)
 
 	if err != nil {
 		logger().Error("unable to process query: ", err)
@@ -182,7 +199,7 @@ func (s *Server) apiQuery(rw http.ResponseWriter, req *http.Request) {
 	dnsRequest := util.NewMsgWithQuestion(query, qType)
 	r := createResolverRequest(nil, dnsRequest)
 
-	response, err := s.queryResolver.Resolve(r)
+	response, err := s.getQueryResolver().Resolve(r)
 
 	if err != nil {
 		logger().Error("unable to process query: ", err)
@@ -207,6 +224,105 @@ func (s *Server) apiQuery(rw http.ResponseWriter, req *http.Request) {
 	}
 }
 
+// apiExplain is the http endpoint to explain the decision for a domain and a simulated client
+// @Summary Explain blocking decision
+// @Description walks through the resolver chain without resolving the domain and returns the checked groups,
+// @Description the matching list entries with source and line number and the resolver which decided the outcome
+// @Tags query
+// @Accept  json
+// @Produce  json
+// @Param query body api.ExplainRequest true "domain and client"
+// @Success 200 {object} api.ExplainResult "decision was explained"
+// @Failure 400   "Wrong request format"
+// @Router /explain [post]
+func (s *Server) apiExplain(rw http.ResponseWriter, req *http.Request) {
+	var explainRequest api.ExplainRequest
+	if err := json.NewDecoder(req.Body).Decode(&explainRequest); err != nil {
+		logger().Error("can't read request: ", err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	r, err := createExplainRequest(&explainRequest)
+	if err != nil {
+		logger().Error(err)
+		http.Error(rw, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	jsonResponse, _ := json.Marshal(resolver.Explain(s.getQueryResolver(), r))
+
+	if _, err = rw.Write(jsonResponse); err != nil {
+		logger().Error("unable to write response ", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// creates the resolver request for the simulated client, the MAC address is passed as EDNS0 option
+func createExplainRequest(explainRequest *api.ExplainRequest) (*resolver.Request, error) {
+	domain := strings.TrimSpace(explainRequest.Domain)
+	if len(domain) == 0 {
+		return nil, fmt.Errorf("domain is missing")
+	}
+
+	qType := dns.TypeA
+	if len(explainRequest.Type) > 0 {
+		qType = dns.StringToType[strings.ToUpper(explainRequest.Type)]
+		if qType == dns.TypeNone {
+			return nil, fmt.Errorf("unknown query type '%s'", explainRequest.Type)
+		}
+	}
+
+	var clientIP net.IP
+
+	if len(explainRequest.ClientIP) > 0 {
+		if clientIP = net.ParseIP(explainRequest.ClientIP); clientIP == nil {
+			return nil, fmt.Errorf("invalid client IP '%s'", explainRequest.ClientIP)
+		}
+	}
+
+	r := newRequest(clientIP, util.NewMsgWithQuestion(dns.Fqdn(domain), qType))
+	r.Client.Names = explainRequest.ClientNames
+
+	if len(explainRequest.ClientMAC) > 0 {
+		mac, err := net.ParseMAC(explainRequest.ClientMAC)
+		if err != nil {
+			return nil, fmt.Errorf("invalid client MAC '%s': %v", explainRequest.ClientMAC, err)
+		}
+
+		r.Client.MAC = mac.String()
+	}
+
+	if len(explainRequest.ClientID) > 0 {
+		clientID, ok := normalizeClientID(explainRequest.ClientID)
+		if !ok {
+			return nil, fmt.Errorf("invalid client ID '%s'", explainRequest.ClientID)
+		}
+
+		r.Client.ID = clientID
+	}
+
+	return r, nil
+}
+
+// apiConfigReload is the http endpoint to reload the configuration file
+// @Summary Reload configuration
+// @Description reads the configuration file again and replaces the resolver chain without restarting listeners
+// @Tags configuration
+// @Success 200   "Configuration was reloaded"
+// @Failure 500   "Configuration is invalid or can't be read, current configuration is still active"
+// @Router /config/reload [post]
+func (s *Server) apiConfigReload(rw http.ResponseWriter, _ *http.Request) {
+	if err := s.Reload(); err != nil {
+		logger().Error("reload failed: ", err)
+		http.Error(rw, err.Error(), http.StatusInternalServerError)
+
+		return
+	}
+}
+
 func createRouter(cfg *config.Config) *chi.Mux {
 	router := chi.NewRouter()
 
@@ -282,7 +398,7 @@ func configureDebugHandler(router *chi.Mux) {
 func configureCorsHandler(router *chi.Mux) {
 	crs := cors.New(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST"},
+		AllowedMethods:   []string{"GET", "POST", "DELETE"},
 		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
 		ExposedHeaders:   []string{"Link"},
 		AllowCredentials: true,
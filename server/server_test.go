@@ -2,8 +2,14 @@ package server
 
 import (
 	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"github.com/privacyherodev/ph-blocky/api"
 	"github.com/privacyherodev/ph-blocky/config"
 	. "github.com/privacyherodev/ph-blocky/helpertest"
@@ -11,8 +17,12 @@ import (
 	"github.com/privacyherodev/ph-blocky/util"
 	"io/ioutil"
 	"log"
+	"math/big"
 	"net"
 	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
@@ -83,13 +93,18 @@ var _ = Describe("Running DNS server", func() {
 					"clWhitelistOnly": {"whitelist"},
 					"clAdsAndYoutube": {"ads", "youtube"},
 					"clYoutubeOnly":   {"youtube"},
+					"kids-tablet":     {"youtube"},
+					"Phone-A":         {"youtube"},
 				},
+				Toggles: []string{"youtube"},
+				Global:  map[string]bool{"youtube": true},
 			},
 			Upstream: config.UpstreamConfig{
 				ExternalResolvers: []config.Upstream{upstreamGoogle},
 			},
 			ClientLookup: config.ClientLookupConfig{
 				Upstream: upstreamClient,
+				EDNS0:    config.EDNS0Config{ClientID: config.EDNS0OptionClientID},
 			},
 
 			Port:     55555,
@@ -120,7 +135,7 @@ var _ = Describe("Running DNS server", func() {
 			// reset client cache
 			res := sut.queryResolver
 			for res != nil {
-				if t, ok := res.(*resolver.ClientNamesResolver); ok {
+				if t, ok := res.(*resolver.ClientIdentityResolver); ok {
 					t.FlushCache()
 					break
 				}
@@ -346,6 +361,51 @@ var _ = Describe("Running DNS server", func() {
 		})
 	})
 
+	Describe("Explain Rest API", func() {
+		When("Explain API is called for a blocked domain", func() {
+			It("Should return the decisive resolver with group, source and line", func() {
+				req := api.ExplainRequest{
+					Domain:      "youtube.com",
+					ClientIP:    "192.168.178.100",
+					ClientNames: []string{"clYoutubeOnly"},
+				}
+				jsonValue, _ := json.Marshal(req)
+
+				resp, err := http.Post("http://localhost:4000/api/explain", "application/json", bytes.NewBuffer(jsonValue))
+
+				Expect(err).Should(Succeed())
+				defer resp.Body.Close()
+
+				Expect(resp.StatusCode).Should(Equal(http.StatusOK))
+
+				var result api.ExplainResult
+				err = json.NewDecoder(resp.Body).Decode(&result)
+				Expect(err).Should(Succeed())
+				Expect(result.Outcome).Should(Equal("BLOCKED (youtube)"))
+				Expect(result.DecidedBy).Should(Equal("BlockingResolver"))
+
+				step := result.Steps[len(result.Steps)-1]
+				Expect(step.Groups).Should(Equal([]string{"youtube"}))
+				Expect(step.Matches).Should(ContainElement(api.ExplainMatch{
+					List: "blacklist", Group: "youtube", Matched: true,
+					Entry: "youtube.com", Source: "../testdata/youtube.com.txt", Line: 1,
+				}))
+			})
+		})
+		When("Explain API is called with an invalid client", func() {
+			It("Should return bad request", func() {
+				jsonValue, _ := json.Marshal(api.ExplainRequest{Domain: "youtube.com", ClientMAC: "no-mac"})
+
+				resp, err := http.Post("http://localhost:4000/api/explain", "application/json", bytes.NewBuffer(jsonValue))
+
+				Expect(err).Should(Succeed())
+				defer resp.Body.Close()
+
+				Expect(resp.StatusCode).Should(Equal(http.StatusBadRequest))
+			})
+		})
+	})
+
 	Describe("DOH endpoint", func() {
 		Context("DOH over GET (RFC 8484)", func() {
 			When("DOH get request with 'example.com' is performed", func() {
@@ -438,6 +498,69 @@ var _ = Describe("Running DNS server", func() {
 					Expect(resp).Should(HaveHTTPStatus(http.StatusRequestEntityTooLarge))
 				})
 			})
+			When("DOH post request contains a client ID in the path", func() {
+				It("should use the groups of the client ID", func() {
+					msg := util.NewMsgWithQuestion("youtube.com.", dns.TypeA)
+					rawDNSMessage, err := msg.Pack()
+					Expect(err).Should(Succeed())
+
+					resp, err := http.Post("http://localhost:4000/dns-query/Kids-Tablet",
+						"application/dns-message", bytes.NewReader(rawDNSMessage))
+					Expect(err).Should(Succeed())
+					defer resp.Body.Close()
+					Expect(resp).Should(HaveHTTPStatus(http.StatusOK))
+					rawMsg, err := ioutil.ReadAll(resp.Body)
+					Expect(err).Should(Succeed())
+
+					msg = new(dns.Msg)
+					err = msg.Unpack(rawMsg)
+					Expect(err).Should(Succeed())
+
+					Expect(msg.Answer).Should(BeDNSRecord("youtube.com.", dns.TypeA, 0, "0.0.0.0"))
+				})
+			})
+			When("client ID differs in case from the clientGroupsBlock key", func() {
+				It("should use the groups of the key for DoH and EDNS0 client IDs", func() {
+					By("sending the client ID in the DoH path", func() {
+						rawDNSMessage, err := util.NewMsgWithQuestion("youtube.com.", dns.TypeA).Pack()
+						Expect(err).Should(Succeed())
+
+						resp, err := http.Post("http://localhost:4000/dns-query/phone-a",
+							"application/dns-message", bytes.NewReader(rawDNSMessage))
+						Expect(err).Should(Succeed())
+						defer resp.Body.Close()
+						Expect(resp).Should(HaveHTTPStatus(http.StatusOK))
+						rawMsg, err := ioutil.ReadAll(resp.Body)
+						Expect(err).Should(Succeed())
+
+						msg := new(dns.Msg)
+						Expect(msg.Unpack(rawMsg)).Should(Succeed())
+						Expect(msg.Answer).Should(BeDNSRecord("youtube.com.", dns.TypeA, 0, "0.0.0.0"))
+					})
+
+					By("sending the client ID as EDNS0 option", func() {
+						msg := util.NewMsgWithQuestion("youtube.com.", dns.TypeA)
+						msg.SetEdns0(4096, false)
+						msg.IsEdns0().Option = append(msg.IsEdns0().Option,
+							&dns.EDNS0_LOCAL{Code: config.EDNS0OptionClientID, Data: []byte("PHONE-A")})
+
+						Expect(requestServer(msg).Answer).Should(BeDNSRecord("youtube.com.", dns.TypeA, 0, "0.0.0.0"))
+					})
+				})
+			})
+			When("Client ID in the path is invalid", func() {
+				It("should return 'Bad Request'", func() {
+					msg := util.NewMsgWithQuestion("youtube.com.", dns.TypeA)
+					rawDNSMessage, err := msg.Pack()
+					Expect(err).Should(Succeed())
+
+					resp, err := http.Post("http://localhost:4000/dns-query/kids_tablet",
+						"application/dns-message", bytes.NewReader(rawDNSMessage))
+					Expect(err).Should(Succeed())
+					defer resp.Body.Close()
+					Expect(resp).Should(HaveHTTPStatus(http.StatusBadRequest))
+				})
+			})
 			When("Request has wrong type", func() {
 				It("should return 'Unsupported Media Type'", func() {
 					resp, err := http.Post("http://localhost:4000/dns-query", "application/text", bytes.NewReader([]byte("a")))
@@ -551,6 +674,293 @@ var _ = Describe("Running DNS server", func() {
 		})
 	})
 
+	Describe("Reload configuration", func() {
+		var (
+			server  *Server
+			cfgFile *os.File
+		)
+		BeforeEach(func() {
+			cfgFile = TempFile(`port: 55558
+upstream:
+  externalResolvers:
+    - udp:8.8.8.8
+customDNS:
+  mapping:
+    custom.lan: 192.168.178.55`)
+
+			cfg, err := config.LoadConfig(cfgFile.Name())
+			Expect(err).Should(Succeed())
+
+			server, err = NewServer(&cfg)
+			Expect(err).Should(Succeed())
+		})
+		AfterEach(func() {
+			_ = os.Remove(cfgFile.Name())
+		})
+		When("configuration file was changed", func() {
+			It("should replace the resolver chain and keep resolvers with unchanged configuration", func() {
+				oldChain := chainResolvers(server.getQueryResolver())
+				oldNext := make(map[string]resolver.Resolver)
+				for name, res := range oldChain {
+					if c, ok := res.(resolver.ChainedResolver); ok {
+						oldNext[name] = c.GetNext()
+					}
+				}
+
+				err := ioutil.WriteFile(cfgFile.Name(), []byte(`port: 55558
+upstream:
+  externalResolvers:
+    - udp:8.8.8.8
+customDNS:
+  mapping:
+    custom.lan: 192.168.178.66`), 0600)
+				Expect(err).Should(Succeed())
+
+				Expect(server.Reload()).Should(Succeed())
+
+				By("building new chain links, the old chain is not changed", func() {
+					newChain := chainResolvers(server.getQueryResolver())
+					Expect(newChain).Should(HaveLen(len(oldChain)))
+
+					for name, res := range oldChain {
+						Expect(newChain[name]).ShouldNot(BeIdenticalTo(res))
+
+						if c, ok := res.(resolver.ChainedResolver); ok {
+							Expect(c.GetNext()).Should(BeIdenticalTo(oldNext[name]))
+						}
+					}
+				})
+
+				resp, err := server.getQueryResolver().Resolve(newRequest(net.ParseIP("192.168.178.1"),
+					util.NewMsgWithQuestion("custom.lan.", dns.TypeA)))
+				Expect(err).Should(Succeed())
+				Expect(resp.Res.Answer).Should(BeDNSRecord("custom.lan.", dns.TypeA, 3600, "192.168.178.66"))
+			})
+		})
+		When("blocking configuration was changed", func() {
+			It("should serve the API of the new blocking resolver with the runtime state of the replaced one", func() {
+				rr := httptest.NewRecorder()
+				server.httpMux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, api.BlockingDisablePath, nil))
+				Expect(rr.Code).Should(Equal(http.StatusOK))
+
+				oldBlocking := findResolver(server.getQueryResolver(), "BlockingResolver")
+
+				err := ioutil.WriteFile(cfgFile.Name(), []byte(`port: 55558
+upstream:
+  externalResolvers:
+    - udp:8.8.8.8
+blocking:
+  blockType: NxDomain`), 0600)
+				Expect(err).Should(Succeed())
+
+				Expect(server.Reload()).Should(Succeed())
+				Expect(findResolver(server.getQueryResolver(), "BlockingResolver")).ShouldNot(BeIdenticalTo(oldBlocking))
+
+				rr = httptest.NewRecorder()
+				server.httpMux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, api.BlockingStatusPath, nil))
+				Expect(rr.Code).Should(Equal(http.StatusOK))
+
+				var result api.BlockingStatus
+				Expect(json.NewDecoder(rr.Body).Decode(&result)).Should(Succeed())
+				Expect(result.Enabled).Should(BeFalse())
+			})
+		})
+		When("query log configuration was changed", func() {
+			It("should stop the log writer of the replaced resolver", func() {
+				oldDir, err := ioutil.TempDir("", "querylog")
+				Expect(err).Should(Succeed())
+				defer os.RemoveAll(oldDir)
+
+				newDir, err := ioutil.TempDir("", "querylog")
+				Expect(err).Should(Succeed())
+				defer os.RemoveAll(newDir)
+
+				writeConfig := func(dir string) {
+					Expect(ioutil.WriteFile(cfgFile.Name(), []byte(`port: 55558
+upstream:
+  externalResolvers:
+    - udp:8.8.8.8
+customDNS:
+  mapping:
+    custom.lan: 192.168.178.55
+queryLog:
+  dir: `+dir), 0600)).Should(Succeed())
+				}
+
+				logFiles := func(dir string) func() []os.FileInfo {
+					return func() []os.FileInfo {
+						files, _ := ioutil.ReadDir(dir)
+						return files
+					}
+				}
+
+				writeConfig(oldDir)
+				Expect(server.Reload()).Should(Succeed())
+
+				oldLogging := findResolver(server.getQueryResolver(), "QueryLoggingResolver")
+				request := func() *resolver.Request {
+					return newRequest(net.ParseIP("192.168.178.1"), util.NewMsgWithQuestion("custom.lan.", dns.TypeA))
+				}
+
+				_, err = oldLogging.Resolve(request())
+				Expect(err).Should(Succeed())
+				Eventually(logFiles(oldDir)).Should(HaveLen(1))
+				Expect(os.Remove(filepath.Join(oldDir, logFiles(oldDir)()[0].Name()))).Should(Succeed())
+
+				writeConfig(newDir)
+				Expect(server.Reload()).Should(Succeed())
+
+				_, err = oldLogging.Resolve(request())
+				Expect(err).Should(Succeed())
+				Consistently(logFiles(oldDir), "200ms").Should(BeEmpty())
+
+				_, err = server.getQueryResolver().Resolve(request())
+				Expect(err).Should(Succeed())
+				Eventually(logFiles(newDir)).Should(HaveLen(1))
+			})
+		})
+		When("changed configuration file is invalid", func() {
+			It("should keep the current resolver chain", func() {
+				current := server.getQueryResolver()
+
+				err := ioutil.WriteFile(cfgFile.Name(), []byte("port: wrong"), 0600)
+				Expect(err).Should(Succeed())
+
+				Expect(server.Reload()).ShouldNot(Succeed())
+				Expect(server.getQueryResolver()).Should(BeIdenticalTo(current))
+			})
+		})
+		When("configuration was not loaded from a file", func() {
+			It("should return an error", func() {
+				server, err := NewServer(&config.Config{Port: 55559})
+				Expect(err).Should(Succeed())
+
+				Expect(server.Reload()).ShouldNot(Succeed())
+			})
+		})
+	})
+
+	Describe("Block page", func() {
+		requestBlockPage := func(host string) (int, string) {
+			req := httptest.NewRequest(http.MethodGet, "/some/path?x=1", nil)
+			req.Host = host
+			// httptest requests are sent from 192.0.2.1
+			rr := httptest.NewRecorder()
+			sut.blockPageHandler().ServeHTTP(rr, req)
+
+			return rr.Code, rr.Body.String()
+		}
+
+		When("the host was blocked recently for the client", func() {
+			It("should show the host, group and reason", func() {
+				response, err := sut.getQueryResolver().Resolve(newRequest(net.ParseIP("192.0.2.1"),
+					util.NewMsgWithQuestion("doubleclick.net.", dns.TypeA)))
+				Expect(err).Should(Succeed())
+				Expect(response.Reason).Should(Equal("BLOCKED (ads)"))
+
+				code, body := requestBlockPage("doubleclick.net:80")
+				Expect(code).Should(Equal(http.StatusForbidden))
+				Expect(body).Should(ContainSubstring("<h1>doubleclick.net is blocked</h1>"))
+				Expect(body).Should(ContainSubstring("Blocked by group <b>ads</b>: BLOCKED (ads)"))
+				Expect(body).Should(ContainSubstring(api.UnblockRequestsPath))
+			})
+			It("should not show the decision to another client with forwarded header", func() {
+				response, err := sut.getQueryResolver().Resolve(newRequest(net.ParseIP("192.0.2.1"),
+					util.NewMsgWithQuestion("doubleclick.net.", dns.TypeA)))
+				Expect(err).Should(Succeed())
+				Expect(response.Reason).Should(Equal("BLOCKED (ads)"))
+
+				req := httptest.NewRequest(http.MethodGet, "/", nil)
+				req.Host = "doubleclick.net"
+				req.RemoteAddr = "192.0.2.2:1234"
+				req.Header.Set("X-Forwarded-For", "192.0.2.1")
+				rr := httptest.NewRecorder()
+				sut.blockPageHandler().ServeHTTP(rr, req)
+
+				Expect(rr.Body.String()).Should(ContainSubstring("No recent block decision"))
+				Expect(rr.Body.String()).ShouldNot(ContainSubstring("ads"))
+			})
+		})
+
+		When("no block decision is known", func() {
+			It("should show the host without group", func() {
+				code, body := requestBlockPage("unknown.com")
+				Expect(code).Should(Equal(http.StatusForbidden))
+				Expect(body).Should(ContainSubstring("<h1>unknown.com is blocked</h1>"))
+				Expect(body).Should(ContainSubstring("No recent block decision"))
+			})
+		})
+
+		When("unblock is requested", func() {
+			It("should pass the request to the API", func() {
+				rr := httptest.NewRecorder()
+				sut.blockPageHandler().ServeHTTP(rr, httptest.NewRequest(http.MethodPost, api.UnblockRequestsPath,
+					strings.NewReader(`{"host": "unblock.example.com"}`)))
+				Expect(rr.Code).Should(Equal(http.StatusOK))
+
+				rr = httptest.NewRecorder()
+				sut.httpMux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, api.UnblockRequestsPath, nil))
+
+				var result []api.UnblockRequestEntry
+				Expect(json.Unmarshal(rr.Body.Bytes(), &result)).Should(Succeed())
+				Expect(result).Should(ContainElement(WithTransform(func(e api.UnblockRequestEntry) string {
+					return e.Host
+				}, Equal("unblock.example.com"))))
+			})
+		})
+
+		When("HTTPS certificates are configured", func() {
+			var (
+				dir          string
+				certificates *blockPageCertificates
+			)
+			BeforeEach(func() {
+				dir, err = ioutil.TempDir("", "certs")
+				Expect(err).Should(Succeed())
+
+				defaultCert, defaultKey := createCertificate(dir, "default", "blocky.lan")
+				exampleCert, exampleKey := createCertificate(dir, "example", "*.example.com", "ads.other.com")
+
+				certificates, err = loadBlockPageCertificates(&config.Config{
+					CertFile: defaultCert,
+					KeyFile:  defaultKey,
+					BlockPage: config.BlockPageConfig{
+						Certificates: []config.CertificateConfig{{CertFile: exampleCert, KeyFile: exampleKey}},
+					},
+				})
+				Expect(err).Should(Succeed())
+			})
+			AfterEach(func() {
+				_ = os.RemoveAll(dir)
+			})
+
+			It("should select the certificate by SNI with fallback to the default certificate", func() {
+				names := func(serverName string) []string {
+					c, err := certificates.get(&tls.ClientHelloInfo{ServerName: serverName})
+					Expect(err).Should(Succeed())
+
+					leaf, err := x509.ParseCertificate(c.Certificate[0])
+					Expect(err).Should(Succeed())
+
+					return leaf.DNSNames
+				}
+
+				Expect(names("ads.example.com")).Should(ContainElement("*.example.com"))
+				Expect(names("ADS.other.com")).Should(ContainElement("ads.other.com"))
+				Expect(names("example.com")).Should(Equal([]string{"blocky.lan"}))
+				Expect(names("tracker.net")).Should(Equal([]string{"blocky.lan"}))
+				Expect(names("")).Should(Equal([]string{"blocky.lan"}))
+			})
+
+			It("should fail if a certificate can't be loaded", func() {
+				_, err = loadBlockPageCertificates(&config.Config{
+					BlockPage: config.BlockPageConfig{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: "missing.key"},
+				})
+				Expect(err).Should(HaveOccurred())
+			})
+		})
+	})
+
 	Describe("resolve client IP", func() {
 		Context("UDP address", func() {
 			It("should correct resolve client IP", func() {
@@ -568,6 +978,35 @@ var _ = Describe("Running DNS server", func() {
 
 })
 
+// createCertificate creates a self signed certificate for the DNS names and returns the certificate and key file
+func createCertificate(dir, name string, dnsNames ...string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	Expect(err).Should(Succeed())
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		DNSNames:     dnsNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	Expect(err).Should(Succeed())
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	Expect(err).Should(Succeed())
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+
+	Expect(ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)).
+		Should(Succeed())
+	Expect(ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600)).
+		Should(Succeed())
+
+	return certFile, keyFile
+}
+
 func requestServer(request *dns.Msg) *dns.Msg {
 	conn, err := net.Dial("udp", ":55555")
 	if err != nil {
@@ -2,7 +2,11 @@ package util
 
 import (
 	"fmt"
+	"io/ioutil"
 	"net"
+	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
 	"strings"
 
@@ -106,6 +110,21 @@ type kv struct {
 }
 
 // IterateValueSorted iterates over maps value in a sorted order and applies the passed function
+// SortedKeys returns the sorted keys of a map with string keys, for example to print or validate entries in a stable
+// order. Panics if m is not a map
+func SortedKeys(m interface{}) []string {
+	keys := reflect.ValueOf(m).MapKeys()
+
+	result := make([]string, 0, len(keys))
+	for _, k := range keys {
+		result = append(result, k.String())
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
 func IterateValueSorted(in map[string]int, fn func(string, int)) {
 	ss := make([]kv, 0)
 
@@ -170,3 +189,28 @@ func Chunks(s string, chunkSize int) []string {
 
 	return chunks
 }
+
+// WriteFileAtomic writes the data to a temporary file in the same directory and renames it to the file name,
+// so readers never see a partially written file
+func WriteFileAtomic(file string, data []byte) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(file), "."+filepath.Base(file)+"-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(tmp.Name(), file)
+	}
+
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+
+	return err
+}
@@ -172,6 +172,14 @@ var _ = Describe("Common function tests", func() {
 		})
 	})
 
+	Describe("Sorted keys of map", func() {
+		It("should return the keys of maps with any value type in sorted order", func() {
+			Expect(SortedKeys(map[string]string{"x": "1", "a": "2"})).Should(Equal([]string{"a", "x"}))
+			Expect(SortedKeys(map[string][]string{"m": nil, "b": {"c"}})).Should(Equal([]string{"b", "m"}))
+			Expect(SortedKeys(map[string]int{})).Should(BeEmpty())
+		})
+	})
+
 	Describe("Logging functions", func() {
 		When("LogOnError is called with error", func() {
 			err := errors.New("test")
@@ -0,0 +1,33 @@
+package web
+
+const BlockPageTmpl = `<!DOCTYPE html>
+<html>
+	<head>
+		<meta charset="utf-8">
+		<title>blocked: {{.Host}}</title>
+	</head>
+	<body>
+		<h1>{{.Host}} is blocked</h1>
+		{{if .Found}}
+		<p>{{if .Group}}Blocked by group <b>{{.Group}}</b>: {{end}}{{.Reason}}</p>
+		{{else}}
+		<p>No recent block decision was found for this host and your device.</p>
+		{{end}}
+		<button id="unblock" type="button">Request unblock</button>
+		<p id="result"></p>
+		<script>
+			document.getElementById("unblock").addEventListener("click", function () {
+				var result = document.getElementById("result");
+				fetch({{.UnblockPath}}, {
+					method: "POST",
+					headers: {"Content-Type": "application/json"},
+					body: JSON.stringify({host: {{.Host}}})
+				}).then(function (response) {
+					result.textContent = response.ok ? "Unblock was requested." : "Request failed: " + response.status;
+				}).catch(function (err) {
+					result.textContent = "Request failed: " + err;
+				});
+			});
+		</script>
+	</body>
+</html>`